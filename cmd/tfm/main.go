@@ -16,11 +16,14 @@ func main() {
 	var logLevel string
 	var workingDir string
 	var showVersion bool
+	var restore, noRestore bool
 
 	flag.StringVar(&configPath, "config", "", "Path to config file (TOML)")
 	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug|info|warn|error")
 	flag.StringVar(&workingDir, "working-dir", "", "Working directory to start in")
 	flag.BoolVar(&showVersion, "version", false, "Print version and exit")
+	flag.BoolVar(&restore, "restore", false, "Restore the tabs of the last session")
+	flag.BoolVar(&noRestore, "no-restore", false, "Start fresh even if the config restores sessions")
 	flag.Parse()
 
 	if showVersion {
@@ -34,6 +37,12 @@ func main() {
 		WorkingDir: workingDir,
 		Version:    version,
 	}
+	switch {
+	case noRestore:
+		opts.Restore = app.RestoreNo
+	case restore:
+		opts.Restore = app.RestoreYes
+	}
 
 	ctx := context.Background()
 	if err := app.Run(ctx, opts); err != nil {
@@ -7,21 +7,34 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/MrTeeett/TerminalFileMeneger/internal/bookmarks"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/frecency"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ops"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/keymap"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/logging"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/session"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/theme"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/commands"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/tui"
 )
 
+// Restore selects whether the last session is reopened on start.
+type Restore int
+
+const (
+	RestoreDefault Restore = iota // follow the config ([session] restore)
+	RestoreYes
+	RestoreNo
+)
+
 // Options holds startup options from CLI.
 type Options struct {
 	ConfigPath string
 	WorkingDir string
 	LogLevel   string
 	Version    string
+	Restore    Restore
 }
 
 // Run wires dependencies and starts the TUI.
@@ -70,6 +83,33 @@ func Run(ctx context.Context, opts Options) error {
 	th := theme.Default()
 	reg := commands.NewRegistry()
 	fsman := ops.NewManager()
+	marks, err := bookmarks.Load(bookmarks.DefaultPath())
+	if err != nil {
+		logger.Warnf("bookmarks: %v", err)
+	}
+	var freq *frecency.DB
+	if cfg.Frecency {
+		path := frecency.DefaultPath()
+		firstRun := !frecency.Exists(path)
+		freq, err = frecency.Load(path)
+		if err != nil {
+			// The database is used but not saved for this session.
+			logger.Warnf("frecency: %v (not saving this session)", err)
+		}
+		if firstRun && err == nil && cfg.FrecencyImport {
+			n, err := freq.ImportDefaults()
+			if err != nil {
+				logger.Warnf("frecency import: %v", err)
+			}
+			if n > 0 {
+				logger.Infof("frecency: imported %d directories", n)
+			}
+			// Saved even when empty: the import is only tried once.
+			if err := freq.Save(); err != nil {
+				logger.Warnf("frecency: %v", err)
+			}
+		}
+	}
 
 	// Apply key overrides from config ([keys] section)
 	if cfg != nil && len(cfg.Keys) > 0 {
@@ -81,16 +121,43 @@ func Run(ctx context.Context, opts Options) error {
 		}
 	}
 
+	// An explicit --working-dir opens there unless --restore is given too.
+	restore := cfg.RestoreSession && opts.WorkingDir == ""
+	switch opts.Restore {
+	case RestoreYes:
+		restore = true
+	case RestoreNo:
+		restore = false
+	}
+	sessPath := session.DefaultPath()
+	var sess *session.Session
+	if restore {
+		sess, err = session.Load(sessPath)
+		if err != nil {
+			logger.Warnf("session: %v", err)
+		}
+	}
+
 	deps := tui.Dependencies{
-		Logger:   logger,
-		Config:   cfg,
-		Keymap:   km,
-		Theme:    th,
-		Registry: reg,
-		FS:       fsman,
+		Logger:      logger,
+		Config:      cfg,
+		Keymap:      km,
+		Theme:       th,
+		Registry:    reg,
+		FS:          fsman,
+		Bookmarks:   marks,
+		Frecency:    freq,
+		Session:     sess,
+		SessionPath: sessPath,
 	}
 
-	if err := tui.Start(ctx, deps); err != nil {
+	err = tui.Start(ctx, deps)
+	if freq != nil {
+		if serr := freq.Save(); serr != nil {
+			logger.Warnf("frecency: %v", serr)
+		}
+	}
+	if err != nil {
 		return fmt.Errorf("tui: %w", err)
 	}
 	logger.Infof("tfm exited")
@@ -0,0 +1,175 @@
+package bookmarks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bookmark is a named location bound to a single-character mark.
+type Bookmark struct {
+	Key  string // mark character, e.g. "a"
+	Name string // display name
+	Path string // absolute path (directory or file)
+}
+
+// Store keeps marks in memory and persists them to a plain-text file.
+// Each line of the file is "key<TAB>name<TAB>path"; a name or path holding
+// a tab or newline, or starting with a quote, is written quoted as by
+// strconv.Quote.
+type Store struct {
+	path  string
+	items []Bookmark
+}
+
+// DefaultPath returns the XDG-compliant bookmarks file path.
+func DefaultPath() string {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tfm", "bookmarks")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "tfm", "bookmarks")
+}
+
+// ValidKey reports whether k can be used as a mark (a single ASCII letter or digit).
+func ValidKey(k string) bool {
+	if len(k) != 1 {
+		return false
+	}
+	c := k[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// New returns an empty store persisted at path.
+func New(path string) *Store { return &Store{path: path} }
+
+// Load reads marks from path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := New(path)
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 || !ValidKey(parts[0]) {
+			continue
+		}
+		s.put(Bookmark{Key: parts[0], Name: decodeField(parts[1]), Path: decodeField(parts[2])})
+	}
+	return s, sc.Err()
+}
+
+// Save writes all marks to the store file, creating parent directories.
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, bm := range s.items {
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", bm.Key, encodeField(bm.Name), encodeField(bm.Path))
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// encodeField quotes a field that would otherwise break its line.
+func encodeField(f string) string {
+	if strings.ContainsAny(f, "\t\n\r") || strings.HasPrefix(f, `"`) {
+		return strconv.Quote(f)
+	}
+	return f
+}
+
+// decodeField reverses encodeField. A field that only looks quoted, as
+// written before quoting was introduced, is kept as it is.
+func decodeField(f string) string {
+	if strings.HasPrefix(f, `"`) {
+		if u, err := strconv.Unquote(f); err == nil {
+			return u
+		}
+	}
+	return f
+}
+
+// All returns a copy of all marks sorted by key.
+func (s *Store) All() []Bookmark {
+	return append([]Bookmark(nil), s.items...)
+}
+
+// Get returns the mark bound to key.
+func (s *Store) Get(key string) (Bookmark, bool) {
+	for _, bm := range s.items {
+		if bm.Key == key {
+			return bm, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// Set binds key to path, naming it after the path's base name. An existing
+// mark with the same key is replaced.
+func (s *Store) Set(key, path string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("invalid mark: %q", key)
+	}
+	s.put(Bookmark{Key: key, Name: filepath.Base(path), Path: path})
+	return nil
+}
+
+// Rename changes the display name of the mark bound to key.
+func (s *Store) Rename(key, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, "\t\n") {
+		return fmt.Errorf("invalid bookmark name: %q", name)
+	}
+	for i := range s.items {
+		if s.items[i].Key == key {
+			s.items[i].Name = name
+			return nil
+		}
+	}
+	return fmt.Errorf("no such mark: %s", key)
+}
+
+// Delete removes the mark bound to key.
+func (s *Store) Delete(key string) error {
+	for i, bm := range s.items {
+		if bm.Key == key {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such mark: %s", key)
+}
+
+func (s *Store) put(bm Bookmark) {
+	for i := range s.items {
+		if s.items[i].Key == bm.Key {
+			s.items[i] = bm
+			return
+		}
+	}
+	s.items = append(s.items, bm)
+	sort.Slice(s.items, func(i, j int) bool { return s.items[i].Key < s.items[j].Key })
+}
@@ -0,0 +1,106 @@
+package bookmarks
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultPathXDG(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/tmp/xdgdata")
+	if got := DefaultPath(); got != "/tmp/xdgdata/tfm/bookmarks" {
+		t.Fatalf("DefaultPath = %q", got)
+	}
+}
+
+func TestSetRenameDeleteAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "bookmarks")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load missing file: %v", err)
+	}
+	if err := s.Set("b", "/srv/data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("a", "/home/user/projects"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("!", "/x"); err == nil {
+		t.Fatalf("expected error for invalid key")
+	}
+	if err := s.Rename("a", "projects home"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s2, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []Bookmark{
+		{Key: "a", Name: "projects home", Path: "/home/user/projects"},
+		{Key: "b", Name: "data", Path: "/srv/data"},
+	}
+	if got := s2.All(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("All = %#v; want %#v", got, want)
+	}
+	// Replacing a key keeps a single entry
+	_ = s2.Set("a", "/tmp")
+	if bm, _ := s2.Get("a"); bm.Path != "/tmp" || len(s2.All()) != 2 {
+		t.Fatalf("replace failed: %#v", s2.All())
+	}
+	if err := s2.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s2.Get("b"); ok {
+		t.Fatalf("b should be deleted")
+	}
+	if err := s2.Delete("b"); err == nil {
+		t.Fatalf("expected error deleting missing mark")
+	}
+}
+
+func TestLoadSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks")
+	data := "# comment\nbad line\nab\tname\t/path\nc\tok\t/ok\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.All(); len(got) != 1 || got[0].Key != "c" {
+		t.Fatalf("All = %#v", got)
+	}
+}
+
+func TestOddPathsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks")
+	s := New(path)
+	for key, p := range map[string]string{"a": "/tmp/tab\there", "b": "/tmp/line\nbreak", "c": `"quoted`, "d": "/plain"} {
+		if err := s.Set(key, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s2.All(), s.All(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %#v; want %#v", got, want)
+	}
+	// Files written before quoting keep a field that merely starts with a quote.
+	if err := os.WriteFile(path, []byte("e\t\"odd\t/srv/\"x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s3, _ := Load(path)
+	if bm, _ := s3.Get("e"); bm.Name != `"odd` || bm.Path != `/srv/"x` {
+		t.Fatalf("legacy line = %#v", bm)
+	}
+}
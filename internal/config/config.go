@@ -26,6 +26,9 @@ type ThemeConfig struct {
 	Dir      ColorStyle
 	Selected ColorStyle
 	Normal   ColorStyle
+	// Syntax styles highlighted previews by token class ("keyword",
+	// "string", "comment", …). Unset classes use built-in colors.
+	Syntax map[string]ColorStyle
 }
 
 // Config holds user preferences loaded from TOML-like config.
@@ -39,18 +42,36 @@ type Config struct {
 	// UI options
 	ShowPreview       bool    // show preview pane on the right
 	OpenDirsRight     bool    // when entering a dir, open it in right pane instead of replacing left
+	Layout            string  // "miller" (columns + preview) or "dual" (two commander panels)
 	RightPaneWidth    int     // right pane width percent (10..80)
 	InlineImages      bool    // enable inline image previews (iTerm2/WezTerm/Kitty etc.)
+	ImageProtocol     string  // inline image protocol: auto|iterm2|kitty|sixel|none
+	Thumbnails        string  // text drawing of images without a protocol: auto|blocks|braille|ascii
+	HexWidth          int     // bytes per hex dump row, fewer when the pane is too narrow (1..64)
+	PreviewWrap       bool    // wrap long lines in the preview ([preview] wrap)
 	ColorProfile      string  // color profile: auto|none|ansi|256|truecolor
 	BackgroundOpacity float64 // 0..1 hint: if <1, avoid BG fills to let terminal transparency show
 	Blur              bool    // hint flag (actual blur depends on terminal/compositor)
+	Watch             bool    // refresh visible directories when they change on disk
+	Mouse             bool    // clicks select and open entries, the wheel scrolls columns
+	DirLoadTimeout    int     // seconds without progress before a directory listing is abandoned (0 = never)
+	Frecency          bool    // record directory visits for :z / :zi
+	FrecencyImport    bool    // import zoxide/autojump databases on first run
+	PreviewCacheBytes int64   // memory budget for cached previews ([cache] preview_bytes)
+	PreviewDiskCache  bool    // keep expensive previews in $XDG_CACHE_HOME/tfm/preview ([cache] disk)
+	PreviewDiskBytes  int64   // size limit of the on-disk preview cache ([cache] disk_bytes)
+	RestoreSession    bool    // reopen the tabs of the last session on start ([session] restore)
+	SessionSaveEvery  int     // seconds between automatic session saves (0 = only on exit)
 	// CustomCommands maps command names to shell snippets.
 	// Example:
 	//   [commands]
 	//   open = "xdg-open {path}"
 	// Placeholders: {path}, {cwd}, {file}
 	CustomCommands map[string]string
-	Theme          ThemeConfig
+	// Bookmarks maps names to paths (section [bookmarks]). Single-character
+	// names also work as marks for the ' key.
+	Bookmarks map[string]string
+	Theme     ThemeConfig
 }
 
 func Default() *Config {
@@ -61,18 +82,32 @@ func Default() *Config {
 		Keys:              map[string]string{},
 		ShowPreview:       true,
 		OpenDirsRight:     false,
+		Layout:            "miller",
 		RightPaneWidth:    40,
 		InlineImages:      true,
+		ImageProtocol:     "auto",
+		Thumbnails:        "auto",
+		HexWidth:          16,
 		ColorProfile:      "auto",
 		BackgroundOpacity: 1.0,
 		Blur:              false,
+		Watch:             true,
+		Mouse:             true,
+		DirLoadTimeout:    10,
+		Frecency:          true,
+		FrecencyImport:    true,
+		PreviewCacheBytes: 32 << 20,
+		PreviewDiskBytes:  256 << 20,
+		SessionSaveEvery:  60,
 		CustomCommands:    map[string]string{},
+		Bookmarks:         map[string]string{},
 		Theme: ThemeConfig{
 			Header:   ColorStyle{Bold: true},
 			Status:   ColorStyle{Faint: true},
 			Dir:      ColorStyle{FG: "12"}, // ANSI blue
 			Selected: ColorStyle{Bold: true, Reverse: true},
 			Normal:   ColorStyle{},
+			Syntax:   map[string]ColorStyle{},
 		},
 	}
 }
@@ -106,8 +141,13 @@ func DefaultPath() string {
 // Supported constructs:
 //   - Comments starting with '#'
 //   - Sections: [theme], [theme.header], [theme.status], [theme.dir], [theme.selected], [theme.normal]
+//   - [theme.syntax]: class = "color" (foreground); [theme.syntax.<class>]: fg, bg, bold, faint
 //   - Keys with values: key = "value" | true | false
-//   - Root keys: show_hidden, theme_name, keymap
+//   - Root keys: show_hidden, theme_name, keymap, image_protocol
+//   - [bookmarks]: name = "path" (names keep their case)
+//   - [preview]: hex_width = 16 (bytes per hex dump row), wrap = false, thumbnails = "auto"
+//   - [cache]: preview_bytes = "32MB", disk = true, disk_bytes = "256MB"
+//   - [session]: restore = true, save_interval = 60
 func Parse(s string) (*Config, error) {
 	cfg := Default()
 	sec := ""
@@ -125,6 +165,7 @@ func Parse(s string) (*Config, error) {
 		if !ok {
 			continue
 		}
+		rawKey := k
 		k = strings.ToLower(k)
 		switch sec {
 		case "": // root
@@ -145,6 +186,10 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.OpenDirsRight = b
 				}
+			case "layout":
+				if l, ok := parseLayout(v); ok {
+					cfg.Layout = l
+				}
 			case "right_pane_width", "right_width":
 				if n, err := strconv.Atoi(trimQuotes(v)); err == nil {
 					if n < 10 {
@@ -158,6 +203,8 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.InlineImages = b
 				}
+			case "image_protocol":
+				cfg.ImageProtocol = strings.ToLower(trimQuotes(v))
 			case "color_profile", "colors", "color":
 				cfg.ColorProfile = strings.ToLower(trimQuotes(v))
 			case "background_opacity", "opacity":
@@ -177,12 +224,70 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.Blur = b
 				}
+			case "watch":
+				if b, err := parseBool(v); err == nil {
+					cfg.Watch = b
+				}
+			case "mouse":
+				if b, err := parseBool(v); err == nil {
+					cfg.Mouse = b
+				}
+			case "dir_load_timeout", "load_timeout":
+				if n, err := strconv.Atoi(trimQuotes(v)); err == nil && n >= 0 {
+					cfg.DirLoadTimeout = n
+				}
+			case "restore_session":
+				if b, err := parseBool(v); err == nil {
+					cfg.RestoreSession = b
+				}
 			}
 		case "commands", "cmd", "ex":
 			if cfg.CustomCommands == nil {
 				cfg.CustomCommands = make(map[string]string)
 			}
 			cfg.CustomCommands[strings.TrimSpace(k)] = trimQuotes(v)
+		case "frecency":
+			switch k {
+			case "enabled":
+				if b, err := parseBool(v); err == nil {
+					cfg.Frecency = b
+				}
+			case "import":
+				if b, err := parseBool(v); err == nil {
+					cfg.FrecencyImport = b
+				}
+			}
+		case "session":
+			switch k {
+			case "restore":
+				if b, err := parseBool(v); err == nil {
+					cfg.RestoreSession = b
+				}
+			case "save_interval", "autosave":
+				if n, err := strconv.Atoi(trimQuotes(v)); err == nil && n >= 0 {
+					cfg.SessionSaveEvery = n
+				}
+			}
+		case "cache":
+			switch k {
+			case "preview_bytes", "preview_memory":
+				if n, err := parseSize(v); err == nil && n > 0 {
+					cfg.PreviewCacheBytes = n
+				}
+			case "disk":
+				if b, err := parseBool(v); err == nil {
+					cfg.PreviewDiskCache = b
+				}
+			case "disk_bytes", "disk_size":
+				if n, err := parseSize(v); err == nil && n > 0 {
+					cfg.PreviewDiskBytes = n
+				}
+			}
+		case "bookmarks":
+			if cfg.Bookmarks == nil {
+				cfg.Bookmarks = make(map[string]string)
+			}
+			cfg.Bookmarks[trimQuotes(rawKey)] = trimQuotes(v)
 		case "keys", "keymap.keys", "keys.normal":
 			if cfg.Keys == nil {
 				cfg.Keys = make(map[string]string)
@@ -207,6 +312,18 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.InlineImages = b
 				}
+			case "image_protocol":
+				cfg.ImageProtocol = strings.ToLower(trimQuotes(v))
+			case "thumbnails":
+				cfg.Thumbnails = strings.ToLower(trimQuotes(v))
+			case "wrap":
+				if b, err := parseBool(v); err == nil {
+					cfg.PreviewWrap = b
+				}
+			case "hex_width":
+				if n, err := strconv.Atoi(trimQuotes(v)); err == nil && n >= 1 && n <= 64 {
+					cfg.HexWidth = n
+				}
 			case "color_profile", "colors", "color":
 				cfg.ColorProfile = strings.ToLower(trimQuotes(v))
 			case "background_opacity", "opacity":
@@ -233,6 +350,10 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.OpenDirsRight = b
 				}
+			case "layout":
+				if l, ok := parseLayout(v); ok {
+					cfg.Layout = l
+				}
 			case "right_pane_width":
 				if n, err := strconv.Atoi(trimQuotes(v)); err == nil {
 					if n < 10 {
@@ -259,11 +380,32 @@ func Parse(s string) (*Config, error) {
 				if b, err := parseBool(v); err == nil {
 					cfg.Blur = b
 				}
+			case "watch":
+				if b, err := parseBool(v); err == nil {
+					cfg.Watch = b
+				}
+			case "mouse":
+				if b, err := parseBool(v); err == nil {
+					cfg.Mouse = b
+				}
+			case "dir_load_timeout", "load_timeout":
+				if n, err := strconv.Atoi(trimQuotes(v)); err == nil && n >= 0 {
+					cfg.DirLoadTimeout = n
+				}
 			}
 		case "theme", "theme.header", "theme.status", "theme.dir", "theme.selected", "theme.normal":
 			applyStyleKey(&cfg.Theme, sec, k, v)
+		case "theme.syntax":
+			st := cfg.Theme.Syntax[k]
+			st.FG = normalizeColorString(trimQuotes(v))
+			cfg.Theme.Syntax[k] = st
 		default:
-			// ignore unknown sections
+			if class, ok := strings.CutPrefix(sec, "theme.syntax."); ok {
+				st := cfg.Theme.Syntax[class]
+				applyColorKey(&st, k, v)
+				cfg.Theme.Syntax[class] = st
+			}
+			// other sections are ignored
 		}
 	}
 	if err := sc.Err(); err != nil {
@@ -292,6 +434,18 @@ func trimQuotes(v string) string {
 	return v
 }
 
+// parseLayout normalizes a layout name; "commander" and "mc" mean "dual",
+// "columns" means "miller".
+func parseLayout(v string) (string, bool) {
+	switch strings.ToLower(trimQuotes(v)) {
+	case "miller", "columns":
+		return "miller", true
+	case "dual", "commander", "mc":
+		return "dual", true
+	}
+	return "", false
+}
+
 func parseBool(v string) (bool, error) {
 	v = strings.ToLower(strings.TrimSpace(v))
 	switch v {
@@ -309,6 +463,30 @@ func parseBool(v string) (bool, error) {
 	return false, fmt.Errorf("invalid bool: %q", v)
 }
 
+// parseSize parses a byte count with an optional K/M/G suffix (powers of
+// 1024), e.g. 65536, "512K", "32MB" or "1GiB".
+func parseSize(v string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(trimQuotes(v)))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %q", v)
+	}
+	return n * mult, nil
+}
+
 func applyStyleKey(th *ThemeConfig, section, key, val string) {
 	var target *ColorStyle
 	switch section {
@@ -326,6 +504,11 @@ func applyStyleKey(th *ThemeConfig, section, key, val string) {
 	if target == nil {
 		return
 	}
+	applyColorKey(target, key, val)
+}
+
+// applyColorKey sets one property (fg, bg, bold, faint, reverse) of target.
+func applyColorKey(target *ColorStyle, key, val string) {
 	switch key {
 	case "fg", "foreground":
 		target.FG = normalizeColorString(trimQuotes(val))
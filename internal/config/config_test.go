@@ -129,6 +129,163 @@ bold = true
 	}
 }
 
+func TestParseWatch(t *testing.T) {
+	cfg, err := Parse("watch = false\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Watch {
+		t.Fatalf("Watch = true; want false")
+	}
+	cfg, _ = Parse("[view]\nwatch = off\n")
+	if cfg.Watch {
+		t.Fatalf("[view] watch = true; want false")
+	}
+}
+
+func TestParseSyntaxTheme(t *testing.T) {
+	cfg, err := Parse(`[theme.syntax]
+keyword = "#c678ddff"
+comment = "8"
+
+[theme.syntax.string]
+fg = "2"
+bold = true
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := map[string]ColorStyle{
+		"keyword": {FG: "#c678dd"},
+		"comment": {FG: "8"},
+		"string":  {FG: "2", Bold: true},
+	}
+	if !reflect.DeepEqual(cfg.Theme.Syntax, want) {
+		t.Fatalf("Syntax = %#v; want %#v", cfg.Theme.Syntax, want)
+	}
+}
+
+func TestParseImageProtocol(t *testing.T) {
+	if cfg, _ := Parse(""); cfg.ImageProtocol != "auto" {
+		t.Fatalf("default image_protocol = %q", cfg.ImageProtocol)
+	}
+	if cfg, _ := Parse("[preview]\nimage_protocol = \"Kitty\"\n"); cfg.ImageProtocol != "kitty" {
+		t.Fatalf("image_protocol = %q; want kitty", cfg.ImageProtocol)
+	}
+	if cfg, _ := Parse("[preview]\nthumbnails = \"braille\"\n"); cfg.Thumbnails != "braille" {
+		t.Fatalf("thumbnails = %q; want braille", cfg.Thumbnails)
+	}
+}
+
+func TestParseHexWidth(t *testing.T) {
+	cfg, _ := Parse("[preview]\nhex_width = 8\n")
+	if cfg.HexWidth != 8 {
+		t.Fatalf("HexWidth = %d; want 8", cfg.HexWidth)
+	}
+	if cfg.PreviewWrap {
+		t.Fatalf("wrap enabled by default")
+	}
+	cfg, _ = Parse("[preview]\nhex_width = 1000\nwrap = true\n")
+	if !cfg.PreviewWrap {
+		t.Fatalf("wrap = true not parsed")
+	}
+	if cfg.HexWidth != 16 {
+		t.Fatalf("out of range hex_width accepted: %d", cfg.HexWidth)
+	}
+}
+
+func TestParseMouse(t *testing.T) {
+	if cfg, _ := Parse(""); !cfg.Mouse {
+		t.Fatalf("Mouse disabled by default")
+	}
+	cfg, err := Parse("mouse = false\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Mouse {
+		t.Fatalf("Mouse = true; want false")
+	}
+	cfg, _ = Parse("[view]\nmouse = off\n")
+	if cfg.Mouse {
+		t.Fatalf("[view] mouse = true; want false")
+	}
+}
+
+func TestParseDirLoadTimeout(t *testing.T) {
+	if got := Default().DirLoadTimeout; got != 10 {
+		t.Fatalf("default DirLoadTimeout = %d; want 10", got)
+	}
+	cfg, _ := Parse("[view]\ndir_load_timeout = 0\n")
+	if cfg.DirLoadTimeout != 0 {
+		t.Fatalf("DirLoadTimeout = %d; want 0", cfg.DirLoadTimeout)
+	}
+	cfg, _ = Parse("dir_load_timeout = -3\n")
+	if cfg.DirLoadTimeout != 10 {
+		t.Fatalf("negative timeout accepted: %d", cfg.DirLoadTimeout)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	if got := Default().Layout; got != "miller" {
+		t.Fatalf("default Layout = %q; want miller", got)
+	}
+	cfg, _ := Parse("layout = \"commander\"\n")
+	if cfg.Layout != "dual" {
+		t.Fatalf("Layout = %q; want dual", cfg.Layout)
+	}
+	cfg, _ = Parse("[view]\nlayout = \"columns\"\n")
+	if cfg.Layout != "miller" {
+		t.Fatalf("[view] Layout = %q; want miller", cfg.Layout)
+	}
+	cfg, _ = Parse("layout = \"grid\"\n")
+	if cfg.Layout != "miller" {
+		t.Fatalf("unknown layout accepted: %q", cfg.Layout)
+	}
+}
+
+func TestParseSessionSection(t *testing.T) {
+	def := Default()
+	if def.RestoreSession || def.SessionSaveEvery != 60 {
+		t.Fatalf("defaults: restore=%v every=%d", def.RestoreSession, def.SessionSaveEvery)
+	}
+	cfg, _ := Parse("[session]\nrestore = true\nsave_interval = 0\n")
+	if !cfg.RestoreSession || cfg.SessionSaveEvery != 0 {
+		t.Fatalf("[session]: restore=%v every=%d", cfg.RestoreSession, cfg.SessionSaveEvery)
+	}
+	cfg, _ = Parse("restore_session = yes\n")
+	if !cfg.RestoreSession {
+		t.Fatalf("root restore_session ignored")
+	}
+}
+
+func TestParseFrecencySection(t *testing.T) {
+	cfg, err := Parse("[frecency]\nenabled = false\nimport = no\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Frecency || cfg.FrecencyImport {
+		t.Fatalf("frecency = %v import = %v; want false/false", cfg.Frecency, cfg.FrecencyImport)
+	}
+}
+
+func TestParseBookmarksKeepCase(t *testing.T) {
+	src := `
+[bookmarks]
+W = "~/work"
+"logs" = "/var/log"
+`
+	cfg, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := cfg.Bookmarks["W"]; got != "~/work" {
+		t.Fatalf("bookmarks[W] = %q; want ~/work", got)
+	}
+	if got := cfg.Bookmarks["logs"]; got != "/var/log" {
+		t.Fatalf("bookmarks[logs] = %q; want /var/log", got)
+	}
+}
+
 func TestSplitKVAndTrimQuotesAndParseBool(t *testing.T) {
 	if k, v, ok := splitKV("a = b"); !ok || k != "a" || v != "b" {
 		t.Fatalf("splitKV failed: %v %q %q", ok, k, v)
@@ -190,3 +347,31 @@ func TestDefaultPath(t *testing.T) {
 		t.Fatalf("DefaultPath should use XDG_CONFIG_HOME, got %q", got)
 	}
 }
+
+func TestParseCacheSection(t *testing.T) {
+	cfg, err := Parse("[cache]\npreview_bytes = \"8MB\"\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.PreviewCacheBytes != 8<<20 {
+		t.Fatalf("PreviewCacheBytes = %d; want %d", cfg.PreviewCacheBytes, 8<<20)
+	}
+	for in, want := range map[string]int64{"65536": 65536, "512K": 512 << 10, "1GiB": 1 << 30, "2 mb": 2 << 20} {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseSize("lots"); err == nil {
+		t.Errorf("parseSize(lots) should fail")
+	}
+}
+
+func TestParseDiskCache(t *testing.T) {
+	if Default().PreviewDiskCache {
+		t.Fatalf("disk cache should be opt-in")
+	}
+	cfg, _ := Parse("[cache]\ndisk = yes\ndisk_bytes = 1G\n")
+	if !cfg.PreviewDiskCache || cfg.PreviewDiskBytes != 1<<30 {
+		t.Fatalf("disk=%v bytes=%d", cfg.PreviewDiskCache, cfg.PreviewDiskBytes)
+	}
+}
@@ -0,0 +1,247 @@
+package frecency
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRank is the total rank after which all ranks are aged (scaled down).
+const maxRank = 10000
+
+// Entry is a visited directory with its accumulated rank.
+type Entry struct {
+	Path       string
+	Rank       float64
+	LastAccess int64 // unix seconds
+}
+
+// Score returns the frecency of e at now: rank weighted by recency.
+func Score(e Entry, now time.Time) float64 {
+	age := now.Unix() - e.LastAccess
+	switch {
+	case age < 3600:
+		return e.Rank * 4
+	case age < 86400:
+		return e.Rank * 2
+	case age < 7*86400:
+		return e.Rank / 2
+	default:
+		return e.Rank / 4
+	}
+}
+
+// DB is a small frecency database persisted as a text file.
+// Each line of the file is "rank<TAB>last_access<TAB>path".
+type DB struct {
+	path    string
+	entries map[string]*Entry
+	dirty   bool
+	// readOnly is set when the file could not be read in full; saving
+	// would then overwrite the entries that were not loaded.
+	readOnly bool
+}
+
+// DefaultPath returns the XDG-compliant database path.
+func DefaultPath() string {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tfm", "frecency")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "tfm", "frecency")
+}
+
+// New returns an empty database persisted at path.
+func New(path string) *DB {
+	return &DB{path: path, entries: make(map[string]*Entry)}
+}
+
+// Load reads the database at path. A missing file yields an empty database.
+// On error the entries read so far are returned in a database that is never
+// saved, so that the file is left as it is.
+func Load(path string) (*DB, error) {
+	db := New(path)
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return db, nil
+		}
+		db.readOnly = true
+		return db, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		parts := strings.SplitN(sc.Text(), "\t", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			continue
+		}
+		rank, err1 := strconv.ParseFloat(parts[0], 64)
+		last, err2 := strconv.ParseInt(parts[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		db.entries[parts[2]] = &Entry{Path: parts[2], Rank: rank, LastAccess: last}
+	}
+	if err := sc.Err(); err != nil {
+		db.readOnly = true
+		return db, err
+	}
+	return db, nil
+}
+
+// Exists reports whether a database file is present at path.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Dirty reports whether there are unsaved changes.
+func (db *DB) Dirty() bool { return db.dirty }
+
+// Len returns the number of directories in the database.
+func (db *DB) Len() int { return len(db.entries) }
+
+// Save writes the database if it has unsaved changes or its file does not
+// exist yet, so that an empty database still records that it was created.
+// A database that failed to load is not saved.
+func (db *DB) Save() error {
+	if db.path == "" || db.readOnly || !db.dirty && Exists(db.path) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(db.path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, e := range db.sorted() {
+		fmt.Fprintf(&b, "%s\t%d\t%s\n", strconv.FormatFloat(e.Rank, 'f', -1, 64), e.LastAccess, e.Path)
+	}
+	tmp := db.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, db.path); err != nil {
+		return err
+	}
+	db.dirty = false
+	return nil
+}
+
+// Add records a visit of path at now.
+func (db *DB) Add(path string, now time.Time) {
+	if path == "" {
+		return
+	}
+	if e, ok := db.entries[path]; ok {
+		e.Rank++
+		e.LastAccess = now.Unix()
+	} else {
+		db.entries[path] = &Entry{Path: path, Rank: 1, LastAccess: now.Unix()}
+	}
+	db.dirty = true
+	db.age()
+}
+
+// Remove forgets path.
+func (db *DB) Remove(path string) {
+	if _, ok := db.entries[path]; ok {
+		delete(db.entries, path)
+		db.dirty = true
+	}
+}
+
+// Merge adds imported entries: ranks are summed and the latest access wins.
+func (db *DB) Merge(entries []Entry) {
+	for _, in := range entries {
+		if in.Path == "" || in.Rank <= 0 {
+			continue
+		}
+		if e, ok := db.entries[in.Path]; ok {
+			e.Rank += in.Rank
+			if in.LastAccess > e.LastAccess {
+				e.LastAccess = in.LastAccess
+			}
+		} else {
+			cp := in
+			db.entries[in.Path] = &cp
+		}
+		db.dirty = true
+	}
+	db.age()
+}
+
+// Query returns entries matching all keywords, best score first.
+// Keywords are matched case-insensitively and in order within the path; the
+// last keyword must match the final path component.
+func (db *DB) Query(keywords []string, now time.Time) []Entry {
+	var out []Entry
+	for _, e := range db.entries {
+		if Match(e.Path, keywords) {
+			out = append(out, *e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		si, sj := Score(out[i], now), Score(out[j], now)
+		if si != sj {
+			return si > sj
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// Match reports whether path matches keywords (see Query).
+func Match(path string, keywords []string) bool {
+	p := strings.ToLower(path)
+	for _, kw := range keywords {
+		kw = strings.ToLower(kw)
+		i := strings.Index(p, kw)
+		if i < 0 {
+			return false
+		}
+		p = p[i+len(kw):]
+	}
+	if n := len(keywords); n > 0 {
+		last := strings.ToLower(keywords[n-1])
+		if strings.Contains(last, "/") {
+			return true
+		}
+		return strings.Contains(strings.ToLower(filepath.Base(path)), last)
+	}
+	return true
+}
+
+// age scales all ranks down once their total exceeds maxRank and drops
+// entries whose rank falls below 1.
+func (db *DB) age() {
+	total := 0.0
+	for _, e := range db.entries {
+		total += e.Rank
+	}
+	if total <= maxRank {
+		return
+	}
+	factor := 0.9 * maxRank / total
+	for k, e := range db.entries {
+		e.Rank *= factor
+		if e.Rank < 1 {
+			delete(db.entries, k)
+		}
+	}
+	db.dirty = true
+}
+
+func (db *DB) sorted() []Entry {
+	out := make([]Entry, 0, len(db.entries))
+	for _, e := range db.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
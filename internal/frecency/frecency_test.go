@@ -0,0 +1,184 @@
+package frecency
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddQueryAndScore(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	db := New("")
+	db.Add("/home/u/projects/tfm", now)
+	db.Add("/home/u/projects/tfm", now)
+	db.Add("/home/u/tmp", now)
+	db.Merge([]Entry{{Path: "/srv/projects/old", Rank: 10, LastAccess: now.Add(-30 * 24 * time.Hour).Unix()}})
+
+	got := db.Query([]string{"proj"}, now)
+	if len(got) != 0 {
+		t.Fatalf("last keyword must match the base name: %v", got)
+	}
+	got = db.Query([]string{"proj", "tf"}, now)
+	if len(got) != 1 || got[0].Path != "/home/u/projects/tfm" {
+		t.Fatalf("Query(proj tf) = %v", got)
+	}
+	// rank 2 visited now (score 8) beats rank 10 visited a month ago (score 2.5)
+	got = db.Query(nil, now)
+	if len(got) != 3 || got[0].Path != "/home/u/projects/tfm" || got[2].Path != "/srv/projects/old" {
+		t.Fatalf("Query() order = %v", got)
+	}
+	if s := Score(Entry{Rank: 4, LastAccess: now.Unix() - 2*86400}, now); s != 2 {
+		t.Fatalf("Score = %v; want 2", s)
+	}
+}
+
+func TestMatchIsOrderedAndCaseInsensitive(t *testing.T) {
+	if !Match("/Home/User/Docs", []string{"home", "docs"}) {
+		t.Fatalf("expected match")
+	}
+	if Match("/home/user/docs", []string{"docs", "home"}) {
+		t.Fatalf("keywords must match in order")
+	}
+	if !Match("/a/b/c", []string{"a/b/"}) {
+		t.Fatalf("keyword with slash need not match base name")
+	}
+}
+
+func TestAgingDropsLowRanks(t *testing.T) {
+	db := New("")
+	now := time.Now()
+	db.Merge([]Entry{{Path: "/big", Rank: maxRank, LastAccess: now.Unix()}, {Path: "/small", Rank: 1, LastAccess: now.Unix()}})
+	db.Add("/small", now)
+	if db.Len() != 2 {
+		t.Fatalf("len = %d", db.Len())
+	}
+	if e := db.entries["/big"]; e.Rank >= maxRank {
+		t.Fatalf("rank not aged: %v", e.Rank)
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tfm", "frecency")
+	db := New(path)
+	now := time.Unix(1_700_000_000, 0)
+	db.Add("/a b/c", now)
+	if err := db.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if db.Dirty() {
+		t.Fatalf("db should be clean after Save")
+	}
+	db2, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got := db2.Query(nil, now)
+	if len(got) != 1 || got[0].Path != "/a b/c" || got[0].Rank != 1 || got[0].LastAccess != now.Unix() {
+		t.Fatalf("round trip = %#v", got)
+	}
+}
+
+func TestSaveCreatesEmptyDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tfm", "frecency")
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := db.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if !Exists(path) {
+		t.Fatalf("empty database was not written")
+	}
+}
+
+func TestUnreadableDBIsLeftAlone(t *testing.T) {
+	dir := t.TempDir()
+	// A line longer than the scanner's buffer stops the read half-way.
+	long := filepath.Join(dir, "long")
+	data := "1\t1700000000\t/kept\n" + strings.Repeat("x", 1<<17) + "\n2\t1700000000\t/lost\n"
+	unreadable := filepath.Join(dir, "unreadable")
+	for _, p := range []string{long, unreadable} {
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(unreadable, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{long, unreadable} {
+		db, err := Load(p)
+		if err == nil {
+			if p == unreadable {
+				continue // running as root
+			}
+			t.Fatalf("%s: no error", p)
+		}
+		db.Add("/new", time.Now())
+		if err := db.Save(); err != nil {
+			t.Fatalf("%s: Save: %v", p, err)
+		}
+		os.Chmod(p, 0o644)
+		if got, err := os.ReadFile(p); err != nil || string(got) != data {
+			t.Fatalf("%s: file changed (%v)", p, err)
+		}
+	}
+}
+
+func zoxideDB(entries []Entry) []byte {
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.LittleEndian, uint32(zoxideVersion))
+	_ = binary.Write(&b, binary.LittleEndian, uint64(len(entries)))
+	for _, e := range entries {
+		_ = binary.Write(&b, binary.LittleEndian, uint64(len(e.Path)))
+		b.WriteString(e.Path)
+		_ = binary.Write(&b, binary.LittleEndian, math.Float64bits(e.Rank))
+		_ = binary.Write(&b, binary.LittleEndian, uint64(e.LastAccess))
+	}
+	return b.Bytes()
+}
+
+func TestReadZoxideAndAutojump(t *testing.T) {
+	want := []Entry{{Path: "/home/u/src", Rank: 12.5, LastAccess: 1_700_000_000}, {Path: "/tmp", Rank: 1, LastAccess: 1_600_000_000}}
+	got, err := ReadZoxide(bytes.NewReader(zoxideDB(want)))
+	if err != nil {
+		t.Fatalf("ReadZoxide: %v", err)
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("ReadZoxide = %#v", got)
+	}
+	bad := zoxideDB(want)
+	bad[0] = 9
+	if _, err := ReadZoxide(bytes.NewReader(bad)); err == nil {
+		t.Fatalf("expected version error")
+	}
+	aj, err := ReadAutojump(strings.NewReader("22.4\t/home/u/src\nbad\n10\t/tmp\n"), 42)
+	if err != nil {
+		t.Fatalf("ReadAutojump: %v", err)
+	}
+	if len(aj) != 2 || aj[0].Rank != 22.4 || aj[1].Path != "/tmp" || aj[1].LastAccess != 42 {
+		t.Fatalf("ReadAutojump = %#v", aj)
+	}
+}
+
+func TestImportDefaults(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("_ZO_DATA_DIR", "")
+	if err := os.MkdirAll(filepath.Join(data, "zoxide"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "zoxide", "db.zo"), zoxideDB([]Entry{{Path: "/z", Rank: 3, LastAccess: 5}}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := New("")
+	n, err := db.ImportDefaults()
+	if err != nil || n != 1 || db.Len() != 1 {
+		t.Fatalf("ImportDefaults = %d, %v (len %d)", n, err, db.Len())
+	}
+}
@@ -0,0 +1,129 @@
+package frecency
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// zoxideVersion is the database format version written by zoxide >= 0.8.
+const zoxideVersion = 3
+
+// ZoxidePath returns the default location of zoxide's database.
+func ZoxidePath() string {
+	if d := os.Getenv("_ZO_DATA_DIR"); d != "" {
+		return filepath.Join(d, "db.zo")
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "zoxide", "db.zo")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "zoxide", "db.zo")
+}
+
+// AutojumpPath returns the default location of autojump's database.
+func AutojumpPath() string {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "autojump", "autojump.txt")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "autojump", "autojump.txt")
+}
+
+// ReadZoxide parses a zoxide database (bincode: u32 version followed by a
+// Vec of {path string, rank f64, last_accessed u64}, all little-endian).
+func ReadZoxide(r io.Reader) ([]Entry, error) {
+	br := bufio.NewReader(r)
+	var version uint32
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != zoxideVersion {
+		return nil, fmt.Errorf("unsupported zoxide database version %d", version)
+	}
+	var n uint64
+	if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for i := uint64(0); i < n; i++ {
+		var plen uint64
+		if err := binary.Read(br, binary.LittleEndian, &plen); err != nil {
+			return nil, err
+		}
+		if plen > 1<<16 {
+			return nil, errors.New("corrupt zoxide database")
+		}
+		pb := make([]byte, plen)
+		if _, err := io.ReadFull(br, pb); err != nil {
+			return nil, err
+		}
+		var rank uint64
+		var last uint64
+		if err := binary.Read(br, binary.LittleEndian, &rank); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(br, binary.LittleEndian, &last); err != nil {
+			return nil, err
+		}
+		out = append(out, Entry{Path: string(pb), Rank: math.Float64frombits(rank), LastAccess: int64(last)})
+	}
+	return out, nil
+}
+
+// ReadAutojump parses an autojump database ("weight<TAB>path" per line).
+// Autojump does not track access times, so lastAccess is used for all entries.
+func ReadAutojump(r io.Reader, lastAccess int64) ([]Entry, error) {
+	var out []Entry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		parts := strings.SplitN(sc.Text(), "\t", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		w, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, Entry{Path: parts[1], Rank: w, LastAccess: lastAccess})
+	}
+	return out, sc.Err()
+}
+
+// ImportDefaults merges the zoxide and autojump databases found at their
+// default locations. It returns the number of imported entries; missing
+// databases are not an error.
+func (db *DB) ImportDefaults() (int, error) {
+	total := 0
+	var errs []error
+	if f, err := os.Open(ZoxidePath()); err == nil {
+		entries, err := ReadZoxide(f)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("zoxide: %w", err))
+		}
+		db.Merge(entries)
+		total += len(entries)
+	}
+	if f, err := os.Open(AutojumpPath()); err == nil {
+		var last int64
+		if fi, err := f.Stat(); err == nil {
+			last = fi.ModTime().Unix()
+		}
+		entries, err := ReadAutojump(f, last)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("autojump: %w", err))
+		}
+		db.Merge(entries)
+		total += len(entries)
+	}
+	return total, errors.Join(errs...)
+}
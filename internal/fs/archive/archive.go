@@ -0,0 +1,241 @@
+// Package archive reads zip and tar archives, plain or compressed with
+// gzip, xz, zstd or bzip2.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format is the kind of an archive file.
+type Format string
+
+const (
+	Zip    Format = "zip"
+	Tar    Format = "tar"
+	TarGz  Format = "tar.gz"
+	TarXz  Format = "tar.xz"
+	TarZst Format = "tar.zst"
+	TarBz2 Format = "tar.bz2"
+)
+
+// suffixes map file name endings to formats, longest first.
+var suffixes = []struct {
+	suffix string
+	format Format
+}{
+	{".tar.gz", TarGz}, {".tgz", TarGz},
+	{".tar.xz", TarXz}, {".txz", TarXz},
+	{".tar.zst", TarZst}, {".tzst", TarZst},
+	{".tar.bz2", TarBz2}, {".tbz2", TarBz2}, {".tbz", TarBz2},
+	{".tar", Tar},
+	{".zip", Zip}, {".jar", Zip},
+}
+
+// Detect returns the archive format of a file by its name, or "" if the
+// name is not one of a supported archive.
+func Detect(name string) Format {
+	name = strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// Stem returns name without its archive suffix: src.tar.gz gives src.
+func Stem(name string) string {
+	lower := strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(lower, s.suffix) {
+			return name[:len(name)-len(s.suffix)]
+		}
+	}
+	return name
+}
+
+// Entry is a member of an archive.
+type Entry struct {
+	Name    string // slash-separated path in the archive, without a trailing slash
+	Size    int64
+	ModTime time.Time
+	Mode    fs.FileMode
+	IsDir   bool
+	Link    string // target of a symlink or tar hard link (set by Walk for zip)
+	// Unsafe is set when the name recorded in the archive is absolute or
+	// has ".." elements, which would leave the directory the archive is
+	// extracted to were it not cleaned (see CleanName).
+	Unsafe bool
+}
+
+// ErrTooManyEntries is returned by List when the listing was cut at the
+// maximum number of entries.
+var ErrTooManyEntries = errors.New("too many entries")
+
+// List reads the members of the archive at path from its headers only:
+// the central directory of a zip file, the headers of a tar stream (whose
+// contents still have to be decompressed to be skipped). It stops after
+// limit entries (0 = unlimited) with ErrTooManyEntries or with the error of
+// ctx once it is done; the entries read so far are returned as well.
+func List(ctx context.Context, path string, limit int) ([]Entry, error) {
+	format := Detect(path)
+	var out []Entry
+	add := func(e Entry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.Name == "" {
+			return nil // the archive root, "./"
+		}
+		if limit > 0 && len(out) >= limit {
+			return ErrTooManyEntries
+		}
+		out = append(out, e)
+		return nil
+	}
+	switch format {
+	case "":
+		return nil, fmt.Errorf("%s: not a supported archive", path)
+	case Zip:
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if err := add(zipEntry(f)); err != nil {
+				return out, err
+			}
+		}
+		return out, nil
+	}
+	tr, err := OpenTar(ctx, path, format)
+	if err != nil {
+		return nil, err
+	}
+	defer tr.Close()
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		if h.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err := add(tarEntry(h)); err != nil {
+			return out, err
+		}
+	}
+}
+
+// CleanName turns a member name into a slash-separated path relative to the
+// archive root. Leading slashes and ".." elements cannot leave the root.
+func CleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+}
+
+// unsafeName reports whether a recorded member name is absolute, starts
+// with a drive letter or has ".." elements.
+func unsafeName(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || len(name) >= 2 && name[1] == ':' {
+		return true
+	}
+	return slices.Contains(strings.Split(name, "/"), "..")
+}
+
+func zipEntry(f *zip.File) Entry {
+	fi := f.FileInfo()
+	return Entry{
+		Name:    CleanName(f.Name),
+		Size:    int64(f.UncompressedSize64),
+		ModTime: f.Modified,
+		Mode:    fi.Mode(),
+		IsDir:   fi.IsDir(),
+		Unsafe:  unsafeName(f.Name),
+	}
+}
+
+func tarEntry(h *tar.Header) Entry {
+	fi := h.FileInfo()
+	e := Entry{
+		Name:    CleanName(h.Name),
+		Size:    h.Size,
+		ModTime: h.ModTime,
+		Mode:    fi.Mode(),
+		IsDir:   h.Typeflag == tar.TypeDir,
+		Unsafe:  unsafeName(h.Name),
+	}
+	if h.Typeflag == tar.TypeSymlink || h.Typeflag == tar.TypeLink {
+		e.Link = h.Linkname
+	}
+	return e
+}
+
+// TarReader reads a tar archive file, decompressing it.
+type TarReader struct {
+	*tar.Reader
+	f    *os.File
+	zstd *zstd.Decoder
+	read int64 // bytes read from f
+}
+
+// OpenTar opens the tar archive at path in format, one of the tar formats.
+// Reads fail with the error of ctx once it is done, even while Next skips
+// the contents of a large member.
+func OpenTar(ctx context.Context, path string, format Format) (*TarReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &TarReader{f: f}
+	var r io.Reader = bufio.NewReaderSize(readCounter{f, func(n int) { t.read += int64(n) }}, 64<<10)
+	switch format {
+	case TarGz:
+		r, err = gzip.NewReader(r)
+	case TarXz:
+		r, err = xz.NewReader(r)
+	case TarZst:
+		t.zstd, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		r = t.zstd
+	case TarBz2:
+		r = bzip2.NewReader(r)
+	case Tar:
+	default:
+		err = fmt.Errorf("%s: not a tar archive", path)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.Reader = tar.NewReader(ctxReader{ctx, r})
+	return t, nil
+}
+
+// Close closes the archive file.
+func (t *TarReader) Close() error {
+	if t.zstd != nil {
+		t.zstd.Close()
+	}
+	return t.f.Close()
+}
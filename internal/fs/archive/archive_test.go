@@ -0,0 +1,243 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// members are written to the test archives; the directory "docs" is only
+// implied by its file.
+var members = []struct {
+	name, body string
+}{
+	{"src/", ""},
+	{"src/main.go", "package main\n"},
+	{"docs/readme.txt", "hello archive\n"},
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, m.body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func writeTar(t *testing.T, path string, format Format) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w io.WriteCloser = f
+	switch format {
+	case TarGz:
+		w = gzip.NewWriter(f)
+	case TarXz:
+		if w, err = xz.NewWriter(f); err != nil {
+			t.Fatal(err)
+		}
+	case TarZst:
+		if w, err = zstd.NewWriter(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw := tar.NewWriter(w)
+	for _, m := range members {
+		h := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.body)), ModTime: time.Unix(1700000000, 0), Typeflag: tar.TypeReg}
+		if m.name[len(m.name)-1] == '/' {
+			h.Typeflag, h.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, m.body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w != f {
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	for name, format := range map[string]Format{
+		"a.zip": Zip, "a.tar": Tar, "a.tgz": TarGz, "a.tar.xz": TarXz, "a.tar.zst": TarZst,
+	} {
+		path := filepath.Join(dir, name)
+		if format == Zip {
+			writeZip(t, path)
+		} else {
+			writeTar(t, path, format)
+		}
+		entries, err := List(context.Background(), path, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("%s: %d entries: %+v", name, len(entries), entries)
+		}
+		if e := entries[0]; e.Name != "src" || !e.IsDir {
+			t.Errorf("%s: first entry %+v; want directory src", name, e)
+		}
+		if e := entries[1]; e.Name != "src/main.go" || e.IsDir || e.Size != 13 {
+			t.Errorf("%s: second entry %+v", name, e)
+		}
+		entries, err = List(context.Background(), path, 2)
+		if !errors.Is(err, ErrTooManyEntries) || len(entries) != 2 {
+			t.Errorf("%s: limit: %d entries, %v", name, len(entries), err)
+		}
+	}
+}
+
+func TestListCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.tar")
+	writeTar(t, path, Tar)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := List(ctx, path, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v; want context.Canceled", err)
+	}
+}
+
+func TestTarStopsInsideMember(t *testing.T) {
+	// Skipping a large compressed member decompresses it: cancelling must
+	// stop that too, not only the loop over headers.
+	path := filepath.Join(t.TempDir(), "big.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	const size = 8 << 20
+	tw.WriteHeader(&tar.Header{Name: "big", Mode: 0o644, Size: size, Typeflag: tar.TypeReg})
+	tw.Write(make([]byte, size))
+	tw.WriteHeader(&tar.Header{Name: "after", Mode: 0o644, Typeflag: tar.TypeReg})
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr, err := OpenTar(ctx, path, TarGz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	if _, err := tr.Next(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := tr.Next(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Next after cancel: %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for name, want := range map[string]Format{
+		"a.zip": Zip, "b.TAR.GZ": TarGz, "c.tgz": TarGz, "d.tar.xz": TarXz, "e.tar.zst": TarZst,
+		"f.tar.bz2": TarBz2, "g.tar": Tar, "h.gz": "", "notes.txt": "",
+	} {
+		if got := Detect(name); got != want {
+			t.Errorf("Detect(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestStemAndUnsafeNames(t *testing.T) {
+	for name, want := range map[string]string{"src.tar.gz": "src", "A.ZIP": "A", "v1.2.tgz": "v1.2", "notes.txt": "notes.txt"} {
+		if got := Stem(name); got != want {
+			t.Errorf("Stem(%q) = %q; want %q", name, got, want)
+		}
+	}
+	for name, want := range map[string]bool{"a/b": false, "../a": true, "/etc/passwd": true, `..\x`: true, "C:/x": true, "a/../../b": true, "a..b": false} {
+		if got := unsafeName(name); got != want {
+			t.Errorf("unsafeName(%q) = %v", name, got)
+		}
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for name, want := range map[string]string{
+		"./a/b/": "a/b", "/etc/passwd": "etc/passwd", "../../x": "x", `dir\file`: "dir/file", "./": "",
+	} {
+		if got := CleanName(name); got != want {
+			t.Errorf("CleanName(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestLoadReadDirAndSplit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.tar.gz")
+	writeTar(t, path, TarGz)
+	a, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := a.ReadDir("")
+	if err != nil || len(root) != 2 || root[0].Name != "docs" || !root[0].IsDir || root[1].Name != "src" {
+		t.Fatalf("root: %+v, %v", root, err)
+	}
+	if kids, _ := a.ReadDir("docs"); len(kids) != 1 || kids[0].Name != "docs/readme.txt" {
+		t.Fatalf("implied directory: %+v", kids)
+	}
+	if _, err := a.ReadDir("nope"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing directory: %v", err)
+	}
+	if again, _ := Load(context.Background(), path); again != a {
+		t.Error("index not cached")
+	}
+
+	for p, want := range map[string][2]string{
+		path:                                    {path, ""},
+		filepath.Join(path, "src", "main.go"):   {path, "src/main.go"},
+		filepath.Join(dir, "plain", "file.txt"): {"", ""},
+	} {
+		file, name, _ := Split(p)
+		if file != want[0] || name != want[1] {
+			t.Errorf("Split(%q) = %q, %q; want %q, %q", p, file, name, want[0], want[1])
+		}
+	}
+	if !IsVirtual(filepath.Join(path, "src")) || IsVirtual(path) {
+		t.Error("IsVirtual")
+	}
+
+	var b bytes.Buffer
+	if err := ReadMember(context.Background(), path, "docs/readme.txt", &b, 5); err != nil || b.String() != "hello" {
+		t.Fatalf("ReadMember: %q, %v", b.String(), err)
+	}
+	if err := ReadMember(context.Background(), path, "missing", &b, 5); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing member: %v", err)
+	}
+}
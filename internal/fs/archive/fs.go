@@ -0,0 +1,349 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxEntries bounds the members indexed by Load; larger archives are
+// browsed in part.
+const MaxEntries = 1 << 20
+
+// FS is the directory tree of an archive file, read once from its headers.
+// Archives are read-only: members are read with Walk and ReadMember.
+type FS struct {
+	Path      string
+	Format    Format
+	Truncated bool // the archive has more than MaxEntries members
+
+	size    int64
+	modTime time.Time
+	entries map[string]Entry   // by name, including implied directories
+	dirs    map[string][]Entry // children by directory name, "" for the root
+}
+
+// newFS indexes the entries of the archive file. Directories that the
+// archive only implies by the names of their members are added.
+func newFS(file string, fi os.FileInfo, entries []Entry) *FS {
+	a := &FS{
+		Path:    file,
+		Format:  Detect(file),
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+		entries: make(map[string]Entry, len(entries)),
+		dirs:    map[string][]Entry{"": nil},
+	}
+	var add func(e Entry)
+	add = func(e Entry) {
+		if old, ok := a.entries[e.Name]; ok {
+			if old.IsDir && e.IsDir {
+				// An implied directory listed later keeps its place.
+				a.replace(e)
+			}
+			return
+		}
+		parent := path.Dir(e.Name)
+		if parent == "." {
+			parent = ""
+		}
+		if _, ok := a.entries[parent]; !ok && parent != "" {
+			add(Entry{Name: parent, IsDir: true, Mode: fs.ModeDir | 0o755, ModTime: e.ModTime})
+		}
+		a.entries[e.Name] = e
+		a.dirs[parent] = append(a.dirs[parent], e)
+		if e.IsDir {
+			if _, ok := a.dirs[e.Name]; !ok {
+				a.dirs[e.Name] = nil
+			}
+		}
+	}
+	for _, e := range entries {
+		add(e)
+	}
+	for _, kids := range a.dirs {
+		sort.Slice(kids, func(i, j int) bool { return kids[i].Name < kids[j].Name })
+	}
+	return a
+}
+
+// replace updates the indexed entry with the name of e.
+func (a *FS) replace(e Entry) {
+	a.entries[e.Name] = e
+	parent := path.Dir(e.Name)
+	if parent == "." {
+		parent = ""
+	}
+	for i, c := range a.dirs[parent] {
+		if c.Name == e.Name {
+			a.dirs[parent][i] = e
+		}
+	}
+}
+
+// ReadDir returns the members of the directory dir ("" for the root of the
+// archive), sorted by name. Entry names are full member names.
+func (a *FS) ReadDir(dir string) ([]Entry, error) {
+	kids, ok := a.dirs[CleanName(dir)]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: filepath.Join(a.Path, dir), Err: fs.ErrNotExist}
+	}
+	return kids, nil
+}
+
+// Stat returns the member called name.
+func (a *FS) Stat(name string) (Entry, bool) {
+	e, ok := a.entries[CleanName(name)]
+	return e, ok
+}
+
+// cache keeps the most recently used archive indexes.
+var cache struct {
+	sync.Mutex
+	fss []*FS // most recently used last
+}
+
+// cacheSize is the number of archive indexes kept by Load.
+const cacheSize = 8
+
+// Load returns the index of the archive at path. Indexes are cached until
+// the size or modification time of the file changes.
+func Load(ctx context.Context, path string) (*FS, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	cache.Lock()
+	for i, a := range cache.fss {
+		if a.Path == path {
+			cache.fss = append(cache.fss[:i], cache.fss[i+1:]...)
+			if a.size == fi.Size() && a.modTime.Equal(fi.ModTime()) {
+				cache.fss = append(cache.fss, a)
+				cache.Unlock()
+				return a, nil
+			}
+			break
+		}
+	}
+	cache.Unlock()
+	entries, err := List(ctx, path, MaxEntries)
+	truncated := errors.Is(err, ErrTooManyEntries)
+	if err != nil && !truncated {
+		return nil, err
+	}
+	a := newFS(path, fi, entries)
+	a.Truncated = truncated
+	cache.Lock()
+	defer cache.Unlock()
+	if len(cache.fss) >= cacheSize {
+		cache.fss = cache.fss[1:]
+	}
+	cache.fss = append(cache.fss, a)
+	return a, nil
+}
+
+// Split splits a path inside an archive into the archive file and the
+// member name: /data/src.tar.gz/src/main.go gives /data/src.tar.gz and
+// src/main.go. The archive itself gives an empty name. It reports false
+// for paths that do not lead into an archive file.
+func Split(p string) (string, string, bool) {
+	p = filepath.Clean(p)
+	for dir := p; ; {
+		if Detect(dir) != "" {
+			if fi, err := os.Stat(dir); err == nil && fi.Mode().IsRegular() {
+				rel, _ := filepath.Rel(dir, p)
+				if rel == "." {
+					rel = ""
+				}
+				return dir, filepath.ToSlash(rel), true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// IsVirtual reports whether p is a path inside an archive file.
+func IsVirtual(p string) bool {
+	_, name, ok := Split(p)
+	return ok && name != ""
+}
+
+// Walk calls fn for every member of the archive at path in archive order,
+// with the contents of regular files; r is nil for other members. Member
+// names are cleaned (see CleanName). Walk stops at the first error of fn
+// or once ctx is done.
+func Walk(ctx context.Context, path string, fn func(e Entry, r io.Reader) error) error {
+	return WalkProgress(ctx, path, fn, nil)
+}
+
+// WalkProgress is Walk that also reports, as the contents are read, how
+// many of the total bytes of the archive are done: the packed bytes of tar
+// archives, the unpacked bytes of zip members.
+func WalkProgress(ctx context.Context, path string, fn func(e Entry, r io.Reader) error, progress func(done, total int64)) error {
+	if progress == nil {
+		progress = func(int64, int64) {}
+	}
+	format := Detect(path)
+	if format == Zip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		var done, total int64
+		for _, f := range zr.File {
+			total += int64(f.UncompressedSize64)
+		}
+		for _, f := range zr.File {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			e := zipEntry(f)
+			if e.Name == "" {
+				continue
+			}
+			if e.Mode&fs.ModeSymlink != 0 {
+				if e.Link, err = zipLink(f); err != nil {
+					return err
+				}
+			}
+			if !e.Mode.IsRegular() {
+				if err := fn(e, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(e, readCounter{ctxReader{ctx, rc}, func(n int) {
+				done += int64(n)
+				progress(done, total)
+			}})
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tr, err := OpenTar(ctx, path, format)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+	report := func(int) { progress(tr.read, fi.Size()) }
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := tarEntry(h)
+		if e.Name == "" || h.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		var r io.Reader
+		if h.Typeflag == tar.TypeReg {
+			r = readCounter{tr, report}
+		}
+		if err := fn(e, r); err != nil {
+			return err
+		}
+		report(0)
+	}
+}
+
+// zipLink reads the target of a zip symlink, stored as its contents.
+func zipLink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, 4096))
+	return string(b), err
+}
+
+// readCounter calls count with the number of bytes of every read.
+type readCounter struct {
+	r     io.Reader
+	count func(n int)
+}
+
+func (c readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count(n)
+	return n, err
+}
+
+// ctxReader fails reads once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// errFound stops Walk once ReadMember found its member.
+var errFound = errors.New("found")
+
+// ReadMember copies at most limit bytes of the regular file name in the
+// archive at path to w. Tar archives are read up to the member.
+func ReadMember(ctx context.Context, path, name string, w io.Writer, limit int64) error {
+	name = CleanName(name)
+	err := Walk(ctx, path, func(e Entry, r io.Reader) error {
+		if e.Name != name {
+			return nil
+		}
+		if r == nil {
+			return &fs.PathError{Op: "open", Path: filepath.Join(path, name), Err: errors.New("not a regular file")}
+		}
+		if _, err := io.Copy(w, io.LimitReader(r, limit)); err != nil {
+			return err
+		}
+		return errFound
+	})
+	switch {
+	case err == errFound:
+		return nil
+	case err == nil:
+		return &fs.PathError{Op: "open", Path: filepath.Join(path, name), Err: fs.ErrNotExist}
+	}
+	return err
+}
+
+// Under reports whether the member name is dir or lies below it; every
+// member lies below the root "".
+func Under(name, dir string) bool {
+	return dir == "" || name == dir || strings.HasPrefix(name, dir+"/")
+}
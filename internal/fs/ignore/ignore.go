@@ -0,0 +1,174 @@
+// Package ignore evaluates .gitignore rules for paths below a directory.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// rule is one pattern of a .gitignore file.
+type rule struct {
+	base     string   // directory of the .gitignore, relative to the repository top ("" for the top)
+	segs     []string // pattern split on "/"
+	anchored bool     // pattern contains a slash: match relative to base
+	dirOnly  bool     // trailing slash: match directories only
+	negate   bool     // leading "!": re-include
+}
+
+// Matcher answers whether paths below root are ignored. It reads the
+// .gitignore of each directory on first use, including those between root
+// and the top of the enclosing git repository.
+type Matcher struct {
+	root   string // directory paths are relative to
+	top    string // repository top (root if not in a repository)
+	prefix string // root relative to top, "" if equal
+	rules  map[string][]rule
+}
+
+// New returns a Matcher for paths relative to root.
+func New(root string) *Matcher {
+	root = filepath.Clean(root)
+	top := root
+	for dir := root; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			top = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	m := &Matcher{root: root, top: top, rules: make(map[string][]rule)}
+	if rel, err := filepath.Rel(top, root); err == nil && rel != "." {
+		m.prefix = filepath.ToSlash(rel)
+	}
+	return m
+}
+
+// Ignored reports whether rel (relative to root, using the OS separator)
+// is excluded by the .gitignore files that apply to it. The caller is
+// expected not to descend into ignored directories.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	p := filepath.ToSlash(rel)
+	if m.prefix != "" {
+		p = m.prefix + "/" + p
+	}
+	ignored := false
+	// Rules of outer directories come first; the last match wins.
+	dirs := append([]string{""}, ancestors(p)...)
+	for _, dir := range dirs {
+		for _, r := range m.load(dir) {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.match(p) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// ancestors returns the directories containing p, outermost first,
+// excluding the top itself.
+func ancestors(p string) []string {
+	var out []string
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			out = append(out, p[:i])
+		}
+	}
+	return out
+}
+
+func (m *Matcher) load(dir string) []rule {
+	if rs, ok := m.rules[dir]; ok {
+		return rs
+	}
+	data, err := os.ReadFile(filepath.Join(m.top, filepath.FromSlash(dir), ".gitignore"))
+	var rs []rule
+	if err == nil {
+		rs = parse(dir, data)
+	}
+	m.rules[dir] = rs
+	return rs
+}
+
+// parse reads the patterns of a .gitignore located in base (relative to
+// the repository top, "" for the top itself).
+func parse(base string, data []byte) []rule {
+	var out []rule
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := rule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // escaped leading "#" or "!"
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.segs = strings.Split(line, "/")
+		out = append(out, r)
+	}
+	return out
+}
+
+// match reports whether p (relative to the repository top) matches r.
+func (r rule) match(p string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		p = p[len(r.base)+1:]
+	}
+	if !r.anchored {
+		// A pattern without a slash matches the name at any depth.
+		ok, _ := path.Match(r.segs[0], path.Base(p))
+		return ok
+	}
+	return matchSegs(r.segs, strings.Split(p, "/"))
+}
+
+// matchSegs matches path segments against pattern segments, where "**"
+// stands for any number of segments.
+func matchSegs(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegs(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,58 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	top := t.TempDir()
+	if err := os.Mkdir(filepath.Join(top, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(top, ".gitignore"), "# build output\n*.log\n!keep.log\n/bin/\nbuild/\ndocs/**/*.tmp\n")
+	write(t, filepath.Join(top, "src", ".gitignore"), "gen\n!important.log\n")
+
+	m := New(top)
+	cases := []struct {
+		rel  string
+		dir  bool
+		want bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"src/deep/x.log", false, true},
+		{"src/important.log", false, false},
+		{"bin", true, true},
+		{"src/bin", true, false}, // anchored to the top
+		{"src/build", true, true},
+		{"build", false, false}, // dir-only pattern
+		{"docs/a/b/c.tmp", false, true},
+		{"docs/c.tmp", false, true},
+		{"src/gen", false, true},
+		{"gen", false, false}, // src/.gitignore does not apply outside src
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := m.Ignored(filepath.FromSlash(c.rel), c.dir); got != c.want {
+			t.Errorf("Ignored(%q, %v) = %v; want %v", c.rel, c.dir, got, c.want)
+		}
+	}
+
+	// A matcher rooted in a subdirectory still sees the outer rules.
+	sub := New(filepath.Join(top, "src"))
+	if !sub.Ignored("x.log", false) || !sub.Ignored("gen", true) || sub.Ignored("important.log", false) {
+		t.Fatalf("subdirectory matcher ignores outer .gitignore")
+	}
+}
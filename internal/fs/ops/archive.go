@@ -0,0 +1,254 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+)
+
+// ErrReadOnly is returned when an operation would change the contents of
+// an archive browsed as a directory.
+var ErrReadOnly = errors.New("archives are read-only")
+
+// copyFromArchive writes the member name of the archive file, with
+// everything below it, to dst ("" copies the whole archive into dst).
+func copyFromArchive(ctx context.Context, file, name, dst string) error {
+	a, err := archive.Load(ctx, file)
+	if err != nil {
+		return err
+	}
+	e, ok := a.Stat(name)
+	if !ok && name != "" {
+		return &os.PathError{Op: "copy", Path: filepath.Join(file, name), Err: os.ErrNotExist}
+	}
+	if name != "" && !e.IsDir {
+		return copyMember(ctx, file, e, dst)
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	return extractMembers(ctx, file, name, dst, nil)
+}
+
+// copyMember writes the regular file member e of the archive file to dst.
+func copyMember(ctx context.Context, file string, e archive.Entry, dst string) error {
+	if !e.Mode.IsRegular() {
+		return &os.PathError{Op: "copy", Path: filepath.Join(file, e.Name), Err: errors.New("not a regular file")}
+	}
+	out, err := createMember(dst, e.Mode)
+	if err != nil {
+		return err
+	}
+	if err := archive.ReadMember(ctx, file, e.Name, out, math.MaxInt64); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ErrUnsafeMember is returned, once everything else is extracted, when
+// members were skipped because they would have been written outside the
+// destination: absolute or ".." names ("zip slip") and symlinks pointing out
+// of it or lying on the way to a member.
+var ErrUnsafeMember = errors.New("unsafe archive members skipped")
+
+// Progress reports how far a long operation got: Done of Total bytes
+// (Total is 0 when unknown) and the file being worked on.
+type Progress struct {
+	Done, Total int64
+	Name        string
+}
+
+// Extract writes every member of the archive file src into the directory
+// dst, creating it. progress, if not nil, is called as the archive is read.
+func (m Manager) Extract(ctx context.Context, src, dst string, progress func(Progress)) error {
+	if archive.IsVirtual(dst) {
+		return ErrReadOnly
+	}
+	if archive.IsVirtual(src) {
+		return fmt.Errorf("%s: copy the archive out before extracting it", src)
+	}
+	if archive.Detect(src) == "" {
+		return fmt.Errorf("%s: not a supported archive", src)
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	return extractMembers(ctx, src, "", dst, progress)
+}
+
+// extractMembers writes the members of the archive file below prefix to
+// dst, named relative to prefix. Members that would end up outside dst are
+// skipped and reported with ErrUnsafeMember: names are confined to dst,
+// symlinks are only created when they point inside it and nothing is
+// written through a link leading out of it. Hard links and special files
+// are skipped.
+func extractMembers(ctx context.Context, file, prefix, dst string, progress func(Progress)) error {
+	root, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	var skipped []string
+	var cur string
+	var report func(done, total int64)
+	if progress != nil {
+		report = func(done, total int64) { progress(Progress{Done: done, Total: total, Name: cur}) }
+	}
+	err = archive.WalkProgress(ctx, file, func(e archive.Entry, r io.Reader) error {
+		if !archive.Under(e.Name, prefix) {
+			return nil
+		}
+		cur = e.Name
+		rel := strings.TrimPrefix(strings.TrimPrefix(e.Name, prefix), "/")
+		if rel == "" && e.IsDir {
+			return os.MkdirAll(root, dirPerm(e.Mode))
+		}
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		inside, err := checkInside(root, filepath.Dir(target))
+		if err != nil {
+			return err
+		}
+		if e.Unsafe || !inside {
+			skipped = append(skipped, e.Name)
+			return nil
+		}
+		switch {
+		case e.IsDir:
+			return os.MkdirAll(target, dirPerm(e.Mode))
+		case e.Mode&os.ModeSymlink != 0 && e.Link != "":
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			ok, err := linkInside(root, filepath.Dir(target), e.Link)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				skipped = append(skipped, e.Name)
+				return nil
+			}
+			link := filepath.FromSlash(e.Link)
+			_ = os.Remove(target)
+			return os.Symlink(link, target)
+		case r == nil:
+			return nil
+		}
+		return writeMember(target, e.Mode, r)
+	}, report)
+	if err == nil && len(skipped) > 0 {
+		err = fmt.Errorf("%w: %s", ErrUnsafeMember, strings.Join(skipped, ", "))
+	}
+	return err
+}
+
+// writeMember writes the contents of a regular file member to target.
+func writeMember(target string, mode os.FileMode, r io.Reader) error {
+	out, err := createMember(target, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// createMember creates target, and its parent directories, for a regular
+// file member with mode.
+func createMember(target string, mode os.FileMode) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+	// An existing symlink at target is replaced rather than written through.
+	if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+}
+
+// checkInside reports whether dir, once its symlinks are resolved, is
+// inside root. Directories that do not exist yet are checked from their
+// nearest existing parent.
+func checkInside(root, dir string) (bool, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false, err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		real, err := filepath.EvalSymlinks(d)
+		if err == nil {
+			return within(realRoot, real), nil
+		}
+		if !os.IsNotExist(err) || filepath.Dir(d) == d {
+			return false, err
+		}
+	}
+}
+
+// linkInside reports whether a symlink in the directory dir pointing to
+// link stays inside root. The link is followed from the real directory, with
+// the links already extracted resolved, so a chain such as l -> . followed
+// by l/up -> .. is caught. Only leading ".." elements are allowed: below a
+// name that is, or may later become, a symlink, ".." cannot be resolved in
+// advance.
+func linkInside(root, dir, link string) (bool, error) {
+	if path.IsAbs(link) || filepath.IsAbs(filepath.FromSlash(link)) {
+		return false, nil
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false, err
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, err
+	}
+	down := false
+	for _, elem := range strings.Split(link, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if down {
+				return false, nil
+			}
+			real = filepath.Dir(real)
+		default:
+			down = true
+		}
+	}
+	return within(realRoot, real), nil
+}
+
+// within reports whether p is root or lies below it; both are clean.
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, filepath.Clean(p))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dirPerm is the permission of an extracted directory, which must stay
+// writable and searchable by its owner.
+func dirPerm(mode os.FileMode) os.FileMode {
+	return mode.Perm() | 0o700
+}
+
+// virtual returns the archive file and member name of p when it lies
+// inside an archive (see archive.Split).
+func virtual(p string) (string, string, bool) {
+	file, name, ok := archive.Split(p)
+	return file, name, ok && name != ""
+}
@@ -0,0 +1,254 @@
+package ops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTar writes a tar archive of regular files (name=contents),
+// directories (name/) and symlinks (name->target).
+func writeTar(t *testing.T, path string, members ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, m := range members {
+		var h tar.Header
+		body := ""
+		switch {
+		case strings.HasSuffix(m, "/"):
+			h = tar.Header{Name: m, Typeflag: tar.TypeDir, Mode: 0o755}
+		case strings.Contains(m, "->"):
+			name, target, _ := strings.Cut(m, "->")
+			h = tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: target, Mode: 0o777}
+		default:
+			name, contents, _ := strings.Cut(m, "=")
+			h = tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o640, Size: int64(len(contents))}
+			body = contents
+		}
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(body))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyFromArchive(t *testing.T) {
+	m := NewManager()
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "a.tar")
+	writeTar(t, arc, "src/", "src/main.go=package main", "src/lib/util.go=package lib", "src/link->main.go", "top=x")
+	ctx := context.Background()
+
+	if err := m.Copy(ctx, filepath.Join(arc, "src", "main.go"), filepath.Join(dir, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "main.go")); got != "package main" {
+		t.Fatalf("member file = %q", got)
+	}
+	if fi, _ := os.Stat(filepath.Join(dir, "main.go")); fi.Mode().Perm() != 0o640 {
+		t.Errorf("member mode = %v", fi.Mode())
+	}
+
+	out := filepath.Join(dir, "out")
+	if err := m.Copy(ctx, filepath.Join(arc, "src"), out); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(out, "lib", "util.go")); got != "package lib" {
+		t.Fatalf("member directory: util.go = %q", got)
+	}
+	if target, err := os.Readlink(filepath.Join(out, "link")); err != nil || target != "main.go" {
+		t.Fatalf("symlink = %q, %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "top")); !os.IsNotExist(err) {
+		t.Error("copied a member outside the copied directory")
+	}
+
+	if err := m.Copy(ctx, filepath.Join(dir, "main.go"), filepath.Join(arc, "x")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("copy into archive: %v", err)
+	}
+	if err := m.Move(ctx, filepath.Join(arc, "top"), filepath.Join(dir, "top")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("move out of archive: %v", err)
+	}
+	if err := m.Delete(ctx, filepath.Join(arc, "top")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("delete in archive: %v", err)
+	}
+}
+
+func TestCopyFromArchiveRefusesEscapingSymlinks(t *testing.T) {
+	m := NewManager()
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "evil.tar")
+	writeTar(t, arc, "d/", "d/up->../../outside", "d/up/file=pwned")
+	out := filepath.Join(dir, "out")
+	if err := m.Copy(context.Background(), filepath.Join(arc, "d"), out); err == nil {
+		t.Fatal("escaping symlink extracted")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Fatal("wrote outside the destination")
+	}
+}
+
+func TestCompressExtractRoundTrip(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	writeFile(t, filepath.Join(src, "main.go"), "package main", 0o644)
+	writeFile(t, filepath.Join(src, "bin", "run.sh"), "#!/bin/sh", 0o755)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "notes", 0o600)
+	if err := os.Symlink("main.go", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz", "out.tar.xz", "out.tar.zst"} {
+		arc := filepath.Join(dir, name)
+		var last Progress
+		err := m.Compress(ctx, arc, []string{src, filepath.Join(dir, "notes.txt")}, func(p Progress) { last = p })
+		if err != nil {
+			t.Fatalf("%s: Compress: %v", name, err)
+		}
+		if last.Total != 26 || last.Done != last.Total {
+			t.Errorf("%s: progress %+v", name, last)
+		}
+		if fi, err := os.Stat(arc); err != nil || fi.Mode().Perm() != 0o644 {
+			t.Fatalf("%s: archive: %v", name, err)
+		}
+		out := filepath.Join(dir, "x-"+name)
+		last = Progress{}
+		if err := m.Extract(ctx, arc, out, func(p Progress) { last = p }); err != nil {
+			t.Fatalf("%s: Extract: %v", name, err)
+		}
+		if last.Done <= 0 || last.Done > last.Total || last.Name == "" {
+			t.Errorf("%s: extract progress %+v", name, last)
+		}
+		if got := readFile(t, filepath.Join(out, "proj", "bin", "run.sh")); got != "#!/bin/sh" {
+			t.Errorf("%s: run.sh = %q", name, got)
+		}
+		if fi, _ := os.Stat(filepath.Join(out, "proj", "bin", "run.sh")); fi.Mode().Perm() != 0o755 {
+			t.Errorf("%s: run.sh mode %v", name, fi.Mode())
+		}
+		if got := readFile(t, filepath.Join(out, "notes.txt")); got != "notes" {
+			t.Errorf("%s: notes.txt = %q", name, got)
+		}
+		if target, err := os.Readlink(filepath.Join(out, "proj", "link")); err != nil || target != "main.go" {
+			t.Errorf("%s: link = %q, %v", name, target, err)
+		}
+	}
+	if err := m.Compress(ctx, filepath.Join(dir, "out.zip"), []string{src}, nil); !errors.Is(err, os.ErrExist) {
+		t.Errorf("overwrote an archive: %v", err)
+	}
+	if err := m.Compress(ctx, filepath.Join(dir, "out.tar.bz2"), []string{src}, nil); err == nil {
+		t.Error("wrote tar.bz2")
+	}
+}
+
+func TestCompressCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "src", "f"), "data", 0o644)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewManager().Compress(ctx, filepath.Join(dir, "a.tar.gz"), []string{filepath.Join(dir, "src")}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	if ents, _ := os.ReadDir(dir); len(ents) != 1 {
+		t.Fatalf("left behind: %v", ents)
+	}
+}
+
+func TestExtractSkipsZipSlip(t *testing.T) {
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "evil.zip")
+	f, err := os.Create(arc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"../../slip.txt", "/abs.txt", "ok/../../dots.txt", "fine.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	out := filepath.Join(dir, "a", "b")
+	err = NewManager().Extract(context.Background(), arc, out, nil)
+	if !errors.Is(err, ErrUnsafeMember) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := readFile(t, filepath.Join(out, "fine.txt")); got != "fine.txt" {
+		t.Fatalf("fine.txt = %q", got)
+	}
+	for _, p := range []string{filepath.Join(dir, "slip.txt"), filepath.Join(dir, "a", "slip.txt"), filepath.Join(out, "slip.txt"), filepath.Join(out, "abs.txt")} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%s written", p)
+		}
+	}
+}
+
+func TestExtractSkipsSymlinkEscapes(t *testing.T) {
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "evil.tar")
+	writeTar(t, arc, "abs->/etc", "up->..", "up/pwned=x", "inner->sub", "sub/", "inner/ok=fine")
+	out := filepath.Join(dir, "out")
+	// A link already in the destination is not written through either.
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dir, filepath.Join(out, "pre")); err != nil {
+		t.Fatal(err)
+	}
+	writeTar(t, filepath.Join(dir, "pre.tar"), "pre/pwned=x")
+
+	m := NewManager()
+	if err := m.Extract(context.Background(), arc, out, nil); !errors.Is(err, ErrUnsafeMember) {
+		t.Fatalf("err = %v", err)
+	}
+	if err := m.Extract(context.Background(), filepath.Join(dir, "pre.tar"), out, nil); !errors.Is(err, ErrUnsafeMember) {
+		t.Fatalf("pre-existing link: err = %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Fatal("wrote through a symlink out of the destination")
+	}
+	for _, name := range []string{"abs", "up"} {
+		if fi, err := os.Lstat(filepath.Join(out, name)); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("escaping symlink %s created", name)
+		}
+	}
+	if got := readFile(t, filepath.Join(out, "sub", "ok")); got != "fine" {
+		t.Errorf("member through an inner link: %q", got)
+	}
+}
+
+func TestExtractSkipsSymlinkChains(t *testing.T) {
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "chain.tar")
+	// l/up looks like out/up lexically, but l points at out, so up -> ..
+	// would lead out of it; a -> x/.. escapes once x becomes l.
+	writeTar(t, arc, "l->.", "l/up->..", "a->x/..", "x->.", "d/", "d/back->../d", "d/f=ok")
+	out := filepath.Join(dir, "out")
+
+	err := NewManager().Extract(context.Background(), arc, out, nil)
+	if !errors.Is(err, ErrUnsafeMember) || !strings.Contains(err.Error(), "l/up") {
+		t.Fatalf("err = %v", err)
+	}
+	for _, name := range []string{"up", "a", filepath.Join("l", "up")} {
+		if _, err := os.Lstat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Errorf("escaping symlink %s created", name)
+		}
+	}
+	if got := readFile(t, filepath.Join(out, "d", "back", "f")); got != "ok" {
+		t.Errorf("inner link: %q", got)
+	}
+}
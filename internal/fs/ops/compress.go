@@ -0,0 +1,276 @@
+package ops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+)
+
+// Compress writes the files and directories srcs, each under its own name,
+// to a new archive dst in the format its name selects: zip, tar, tar.gz,
+// tar.xz or tar.zst. The archive is written to a temporary file next to dst
+// and only renamed to dst once complete. progress, if not nil, is called
+// with the bytes of the sources read so far.
+func (m Manager) Compress(ctx context.Context, dst string, srcs []string, progress func(Progress)) (err error) {
+	format := archive.Detect(dst)
+	switch format {
+	case archive.Zip, archive.Tar, archive.TarGz, archive.TarXz, archive.TarZst:
+	case "":
+		return fmt.Errorf("%s: unknown archive format (zip, tar, tar.gz, tar.xz, tar.zst)", filepath.Base(dst))
+	default:
+		return fmt.Errorf("%s: cannot write %s archives", filepath.Base(dst), format)
+	}
+	if archive.IsVirtual(dst) {
+		return ErrReadOnly
+	}
+	for _, src := range srcs {
+		if archive.IsVirtual(src) {
+			return fmt.Errorf("%s: copy archive members out before compressing them", src)
+		}
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.PathError{Op: "compress", Path: dst, Err: fs.ErrExist}
+	}
+	total, err := sourceBytes(ctx, srcs)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	w, err := newArchiveWriter(tmp, format)
+	if err != nil {
+		return err
+	}
+	var done int64
+	for _, src := range srcs {
+		src = filepath.Clean(src)
+		base := filepath.Dir(src)
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if p == tmp.Name() {
+				return nil // the archive being written lies among the sources
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(base, p)
+			return w.add(ctx, filepath.ToSlash(rel), p, fi, func(n int64) {
+				done += n
+				if progress != nil {
+					progress(Progress{Done: done, Total: total, Name: rel})
+				}
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	// CreateTemp leaves the file private to its owner.
+	if err := tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// sourceBytes sums the sizes of the regular files in srcs.
+func sourceBytes(ctx context.Context, srcs []string) (int64, error) {
+	var total int64
+	for _, src := range srcs {
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				fi, err := d.Info()
+				if err != nil {
+					return err
+				}
+				total += fi.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// archiveWriter adds files to an archive being written.
+type archiveWriter struct {
+	add   func(ctx context.Context, name, path string, fi fs.FileInfo, count func(int64)) error
+	close []func() error // run in order by Close
+}
+
+func (w *archiveWriter) Close() error {
+	for _, c := range w.close {
+		if err := c(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newArchiveWriter starts an archive in format on f.
+func newArchiveWriter(f io.Writer, format archive.Format) (*archiveWriter, error) {
+	if format == archive.Zip {
+		zw := zip.NewWriter(f)
+		return &archiveWriter{add: zipAdder(zw), close: []func() error{zw.Close}}, nil
+	}
+	w := &archiveWriter{}
+	out := f
+	switch format {
+	case archive.TarGz:
+		gz := gzip.NewWriter(f)
+		out, w.close = gz, append(w.close, gz.Close)
+	case archive.TarXz:
+		xw, err := xz.NewWriter(f)
+		if err != nil {
+			return nil, err
+		}
+		out, w.close = xw, append(w.close, xw.Close)
+	case archive.TarZst:
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			return nil, err
+		}
+		out, w.close = zw, append(w.close, zw.Close)
+	}
+	tw := tar.NewWriter(out)
+	w.add = tarAdder(tw)
+	// The tar stream ends before the compressor flushes.
+	w.close = append([]func() error{tw.Close}, w.close...)
+	return w, nil
+}
+
+func tarAdder(tw *tar.Writer) func(ctx context.Context, name, path string, fi fs.FileInfo, count func(int64)) error {
+	return func(ctx context.Context, name, path string, fi fs.FileInfo, count func(int64)) error {
+		var link string
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			link = target
+		case !fi.Mode().IsRegular() && !fi.IsDir():
+			return nil // devices, sockets and pipes are left out
+		}
+		h, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if fi.IsDir() {
+			h.Name += "/"
+		}
+		// Owner names are not looked up, as with tar --numeric-owner.
+		h.Uname, h.Gname = "", ""
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		return copyContents(ctx, tw, path, count)
+	}
+}
+
+func zipAdder(zw *zip.Writer) func(ctx context.Context, name, path string, fi fs.FileInfo, count func(int64)) error {
+	return func(ctx context.Context, name, path string, fi fs.FileInfo, count func(int64)) error {
+		if !fi.Mode().IsRegular() && !fi.IsDir() && fi.Mode()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		h, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if fi.IsDir() {
+			h.Name += "/"
+		} else {
+			h.Method = zip.Deflate
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			// Zip stores the target of a symlink as its contents.
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, target)
+			return err
+		case fi.IsDir():
+			return nil
+		}
+		return copyContents(ctx, w, path, count)
+	}
+}
+
+// copyContents copies the file at path to w, counting the bytes, until
+// ctx is done.
+func copyContents(ctx context.Context, w io.Writer, path string, count func(int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	buf := make([]byte, 256<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			count(int64(n))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
 )
 
 // Manager manages file operations.
@@ -13,8 +15,16 @@ type Manager struct{}
 
 func NewManager() Manager { return Manager{} }
 
-// Copy copies a file or directory recursively from src to dst.
+// Copy copies a file or directory recursively from src to dst. Members of
+// archives browsed as directories are copied out; dst cannot lie inside an
+// archive (ErrReadOnly).
 func (m Manager) Copy(ctx context.Context, src, dst string) error {
+	if archive.IsVirtual(dst) {
+		return ErrReadOnly
+	}
+	if file, name, ok := virtual(src); ok {
+		return copyFromArchive(ctx, file, name, dst)
+	}
 	fi, err := os.Lstat(src)
 	if err != nil {
 		return err
@@ -79,10 +89,16 @@ func copyFile(src, dst string, fi fs.FileInfo) error {
 
 func (m Manager) Move(ctx context.Context, src, dst string) error {
 	_ = ctx
+	if archive.IsVirtual(src) || archive.IsVirtual(dst) {
+		return ErrReadOnly
+	}
 	return os.Rename(src, dst)
 }
 
 func (m Manager) Delete(ctx context.Context, path string) error {
 	_ = ctx
+	if archive.IsVirtual(path) {
+		return ErrReadOnly
+	}
 	return os.RemoveAll(path)
 }
@@ -0,0 +1,68 @@
+// Package watch reports changes in a set of directories.
+package watch
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrUnsupported is returned by New on platforms without a watcher backend.
+var ErrUnsupported = errors.New("watch: not supported on this platform")
+
+// DefaultDebounce is the delay used to coalesce bursts of events.
+const DefaultDebounce = 200 * time.Millisecond
+
+// debounce collects directory names from raw and emits them in sorted,
+// de-duplicated batches on out once no new event arrived for delay. A batch
+// that cannot be delivered yet keeps accumulating. out is closed when raw is.
+func debounce(raw <-chan string, out chan<- []string, delay time.Duration) {
+	defer close(out)
+	pending := make(map[string]struct{})
+	var ready []string
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		var send chan<- []string
+		if len(ready) > 0 {
+			send = out
+		}
+		select {
+		case d, ok := <-raw:
+			if !ok {
+				return
+			}
+			pending[d] = struct{}{}
+			if timer == nil {
+				timer = time.NewTimer(delay)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(delay)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			for d := range pending {
+				ready = appendUnique(ready, d)
+			}
+			pending = make(map[string]struct{})
+			sort.Strings(ready)
+		case send <- ready:
+			ready = nil
+		}
+	}
+}
+
+func appendUnique(ss []string, s string) []string {
+	for _, v := range ss {
+		if v == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
@@ -0,0 +1,129 @@
+//go:build linux
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// mask selects events that change a directory listing or file contents.
+const mask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR
+
+// Watcher watches directories with inotify.
+type Watcher struct {
+	fd     int
+	f      *os.File
+	mu     sync.Mutex
+	byDir  map[string]int // dir -> watch descriptor
+	byWd   map[int]string // watch descriptor -> dir
+	raw    chan string
+	events chan []string
+}
+
+// New starts an inotify watcher whose change batches are debounced by delay.
+func New(delay time.Duration) (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fd: fd,
+		// A non-blocking fd is registered with the runtime poller, so Close
+		// unblocks the pending Read.
+		f:      os.NewFile(uintptr(fd), "inotify"),
+		byDir:  make(map[string]int),
+		byWd:   make(map[int]string),
+		raw:    make(chan string, 256),
+		events: make(chan []string, 1),
+	}
+	go w.readLoop()
+	go debounce(w.raw, w.events, delay)
+	return w, nil
+}
+
+// Events returns batches of changed directories. The channel is closed
+// after Close.
+func (w *Watcher) Events() <-chan []string { return w.events }
+
+// Set replaces the watched directories with dirs. Directories that cannot be
+// watched (e.g. removed or unreadable) are skipped.
+func (w *Watcher) Set(dirs []string) {
+	want := make(map[string]struct{}, len(dirs))
+	for _, d := range dirs {
+		if d != "" {
+			want[filepath.Clean(d)] = struct{}{}
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Use the saved descriptor: File.Fd would switch it to blocking mode.
+	fd := w.fd
+	for d, wd := range w.byDir {
+		if _, ok := want[d]; !ok {
+			_, _ = unix.InotifyRmWatch(fd, uint32(wd))
+			delete(w.byDir, d)
+			delete(w.byWd, wd)
+		}
+	}
+	for d := range want {
+		if _, ok := w.byDir[d]; ok {
+			continue
+		}
+		wd, err := unix.InotifyAddWatch(fd, d, mask)
+		if err != nil {
+			continue
+		}
+		w.byDir[d] = wd
+		w.byWd[wd] = d
+	}
+}
+
+// Close stops the watcher and closes the Events channel.
+func (w *Watcher) Close() error { return w.f.Close() }
+
+func (w *Watcher) readLoop() {
+	defer close(w.raw)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+			if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// Events were lost: report every watched directory. The
+				// lock is released first, as sending may wait for the
+				// debouncer while Set holds it.
+				w.mu.Lock()
+				dirs := make([]string, 0, len(w.byDir))
+				for d := range w.byDir {
+					dirs = append(dirs, d)
+				}
+				w.mu.Unlock()
+				for _, d := range dirs {
+					w.raw <- d
+				}
+				continue
+			}
+			w.mu.Lock()
+			dir, ok := w.byWd[int(ev.Wd)]
+			if ok && ev.Mask&unix.IN_IGNORED != 0 {
+				delete(w.byWd, int(ev.Wd))
+				delete(w.byDir, dir)
+			}
+			w.mu.Unlock()
+			if ok {
+				w.raw <- dir
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherReportsCreateAndClose(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	w, err := New(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.Set([]string{dir, filepath.Join(dir, "missing")})
+	if err := os.WriteFile(filepath.Join(dir, "f"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-w.Events():
+		if len(got) != 1 || got[0] != dir {
+			t.Fatalf("batch = %v; want [%s]", got, dir)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no event for created file")
+	}
+	// Unwatched directories do not report.
+	w.Set([]string{other})
+	if err := os.WriteFile(filepath.Join(dir, "g"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-w.Events():
+		t.Fatalf("unexpected batch %v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Fatalf("events should be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Close did not stop the watcher")
+	}
+}
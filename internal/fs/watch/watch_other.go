@@ -0,0 +1,20 @@
+//go:build !linux
+
+package watch
+
+import "time"
+
+// Watcher is unavailable on this platform; New always fails.
+type Watcher struct{}
+
+// New returns ErrUnsupported.
+func New(delay time.Duration) (*Watcher, error) { return nil, ErrUnsupported }
+
+// Events returns nil.
+func (w *Watcher) Events() <-chan []string { return nil }
+
+// Set does nothing.
+func (w *Watcher) Set(dirs []string) {}
+
+// Close does nothing.
+func (w *Watcher) Close() error { return nil }
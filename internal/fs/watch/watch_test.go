@@ -0,0 +1,28 @@
+package watch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDebounceCoalescesBursts(t *testing.T) {
+	raw := make(chan string)
+	out := make(chan []string)
+	go debounce(raw, out, 20*time.Millisecond)
+	raw <- "/b"
+	raw <- "/a"
+	raw <- "/b"
+	select {
+	case got := <-out:
+		if !reflect.DeepEqual(got, []string{"/a", "/b"}) {
+			t.Fatalf("batch = %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no batch delivered")
+	}
+	close(raw)
+	if _, ok := <-out; ok {
+		t.Fatalf("out should be closed after raw")
+	}
+}
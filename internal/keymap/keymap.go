@@ -84,6 +84,22 @@ func Default() Map {
 			"gg": "top",
 			"G":  "bottom",
 			".":  "toggle-hidden",
+			// History
+			"H":         "back",
+			"L":         "forward",
+			"alt+left":  "back",
+			"alt+right": "forward",
+			// Tree view
+			"T":  "toggle-tree",
+			"zo": "tree-open",
+			"zc": "tree-close",
+			"za": "tree-toggle",
+			"zR": "tree-expand-all",
+			"zM": "tree-collapse-all",
+			// Bookmarks
+			"m": "set-mark",
+			"'": "jump-mark",
+			"`": "jump-mark",
 			// Navigation (arrows, paging)
 			"left":      "left",
 			"right":     "right",
@@ -96,18 +112,44 @@ func Default() Map {
 			"ctrl+d":    "half-page-down",
 			"ctrl+u":    "half-page-up",
 			// Tabs
-			"t": "new-tab",
-			"]": "next-tab",
-			"[": "prev-tab",
-			"w": "close-tab",
+			"t":     "new-tab",
+			"]":     "next-tab",
+			"[":     "prev-tab",
+			"w":     "close-tab",
+			"D":     "duplicate-tab",
+			"{":     "move-tab-left",
+			"}":     "move-tab-right",
+			"gt":    "next-tab",
+			"gT":    "prev-tab",
+			"g t 1": "go-tab-1",
+			"g t 2": "go-tab-2",
+			"g t 3": "go-tab-3",
+			"g t 4": "go-tab-4",
+			"g t 5": "go-tab-5",
+			"g t 6": "go-tab-6",
+			"g t 7": "go-tab-7",
+			"g t 8": "go-tab-8",
+			"g t 9": "go-tab-9",
 			// View toggles
 			"ctrl+p": "toggle-preview",
+			"v":      "cycle-preview-view",
+			"J":      "preview-down",
+			"K":      "preview-up",
+			"i":      "focus-preview",
+			"W":      "toggle-preview-wrap",
+			"n":      "search-next",
+			"N":      "search-prev",
 			"ctrl+o": "toggle-right-open-mode",
 			"ctrl+x": "close-right",
 			// Command-line (Ex) mode
 			":": "command",
-			// Focus
-			"tab": "toggle-focus",
+			// Focus and the dual-pane layout
+			"tab":    "toggle-focus",
+			"ctrl+t": "toggle-layout",
+			"S":      "swap-panes",
+			"=":      "sync-panes",
+			"f5":     "copy-to-other",
+			"f6":     "move-to-other",
 			// Quit
 			"q":      "quit",
 			"ctrl+c": "quit",
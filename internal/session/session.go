@@ -0,0 +1,159 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the format version written to session files.
+const Version = 1
+
+// Session is a snapshot of the open tabs, persisted as JSON.
+type Session struct {
+	Version int       `json:"version"`
+	Saved   time.Time `json:"saved"`
+	Layout  string    `json:"layout"` // "miller" or "dual"
+	Active  int       `json:"active"`
+	Tabs    []Tab     `json:"tabs"`
+}
+
+// Panel is a directory together with the entry under the cursor.
+type Panel struct {
+	Dir      string `json:"dir"`
+	Selected string `json:"selected,omitempty"`
+}
+
+// Tab is one tab of a session.
+type Tab struct {
+	Panel
+	Name       string   `json:"name,omitempty"`
+	ShowHidden bool     `json:"show_hidden,omitempty"`
+	History    []string `json:"history,omitempty"`
+	HistoryPos int      `json:"history_pos,omitempty"`
+	Tree       []string `json:"tree"` // open directories relative to Dir; nil unless shown as a tree
+	Flatten    *Flatten `json:"flatten,omitempty"`
+	Columns    []Panel  `json:"columns,omitempty"` // Miller columns right of the tab
+	Right      *Panel   `json:"right,omitempty"`   // second panel of the dual layout
+	Focus      string   `json:"focus,omitempty"`   // "left" or "right"
+	Preview    bool     `json:"preview"`
+}
+
+// Flatten records a flattened listing (:flatten) and its order.
+type Flatten struct {
+	Depth    int    `json:"depth,omitempty"`
+	All      bool   `json:"all,omitempty"` // include .gitignored files
+	Sort     string `json:"sort,omitempty"`
+	SortDesc bool   `json:"sort_desc,omitempty"`
+}
+
+// Dir returns the XDG-compliant directory for session files.
+func Dir() string {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tfm")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "tfm")
+}
+
+// DefaultPath returns the path of the session saved on exit.
+func DefaultPath() string { return filepath.Join(Dir(), "session.json") }
+
+// NamedPath returns the path of the session saved as name.
+func NamedPath(name string) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("invalid session name: %q", name)
+	}
+	return filepath.Join(Dir(), "sessions", name+".json"), nil
+}
+
+// ValidName reports whether name can be used for a named session: letters,
+// digits, '-', '_' and '.', not starting with a dot.
+func ValidName(name string) bool {
+	if name == "" || name[0] == '.' || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		ok := r == '-' || r == '_' || r == '.' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Names lists the named sessions, sorted.
+func Names() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(Dir(), "sessions", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, filepath.Base(f[:len(f)-len(".json")]))
+	}
+	return names, nil
+}
+
+// Load reads the session at path. A missing file yields (nil, nil).
+func Load(path string) (*Session, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Version > Version {
+		return nil, fmt.Errorf("%s: unsupported session version %d", path, s.Version)
+	}
+	if len(s.Tabs) == 0 {
+		return nil, nil
+	}
+	if s.Active < 0 || s.Active >= len(s.Tabs) {
+		s.Active = 0
+	}
+	return &s, nil
+}
+
+// Save writes s to path atomically, creating parent directories.
+func (s *Session) Save(path string) error {
+	if path == "" {
+		return nil
+	}
+	s.Version = Version
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ExistingDir returns dir, or its nearest ancestor that still exists.
+func ExistingDir(dir string) string {
+	for {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
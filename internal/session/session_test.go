@@ -0,0 +1,99 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	s := &Session{
+		Layout: "dual",
+		Active: 2,
+		Tabs: []Tab{
+			{Panel: Panel{Dir: "/a", Selected: "x"}, Name: "work", History: []string{"/", "/a"}, HistoryPos: 1},
+			// A tree with nothing expanded is still a tree.
+			{Panel: Panel{Dir: "/t"}, Tree: []string{}},
+			{
+				Panel:   Panel{Dir: "/b"},
+				Tree:    []string{"sub", "sub/deep"},
+				Flatten: &Flatten{Depth: 2, Sort: "size", SortDesc: true},
+				Columns: []Panel{{Dir: "/b/sub", Selected: "f"}},
+				Right:   &Panel{Dir: "/c"},
+				Focus:   "right",
+				Preview: true,
+			},
+		},
+	}
+	path := DefaultPath()
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Saved = s.Saved
+	if !reflect.DeepEqual(got, s) {
+		t.Fatalf("round trip:\n got %+v\nwant %+v", got, s)
+	}
+}
+
+func TestLoadMissingAndInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if s, err := Load(filepath.Join(dir, "none.json")); s != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", s, err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(bad); err == nil {
+		t.Fatalf("corrupt session accepted")
+	}
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version": 99, "tabs": [{"dir": "/"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(future); err == nil {
+		t.Fatalf("newer session version accepted")
+	}
+	clamp := filepath.Join(dir, "clamp.json")
+	if err := os.WriteFile(clamp, []byte(`{"version": 1, "active": 5, "tabs": [{"dir": "/"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Load(clamp); err != nil || s.Active != 0 {
+		t.Fatalf("active not clamped: %+v, %v", s, err)
+	}
+}
+
+func TestNamedSessions(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	for _, bad := range []string{"", ".hidden", "a/b", "../x", "with space"} {
+		if _, err := NamedPath(bad); err == nil {
+			t.Fatalf("NamedPath(%q) accepted", bad)
+		}
+	}
+	for _, name := range []string{"work", "side-project"} {
+		p, err := NamedPath(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (&Session{Tabs: []Tab{{Panel: Panel{Dir: "/"}}}}).Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := Names()
+	if err != nil || !reflect.DeepEqual(names, []string{"side-project", "work"}) {
+		t.Fatalf("Names = %v, %v", names, err)
+	}
+}
+
+func TestExistingDir(t *testing.T) {
+	root := t.TempDir()
+	if got := ExistingDir(filepath.Join(root, "gone", "deeper")); got != root {
+		t.Fatalf("ExistingDir = %q; want %q", got, root)
+	}
+}
@@ -1,10 +1,16 @@
 package cache
 
 import (
-	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
-	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
 )
 
 func TestDirCacheEviction(t *testing.T) {
@@ -29,17 +35,261 @@ func TestDirCacheEviction(t *testing.T) {
 	}
 }
 
+func TestInvalidate(t *testing.T) {
+	dc := NewDirCache(2)
+	dc.Put("a", []panels.Entry{{Name: "x"}})
+	dc.Put("b", []panels.Entry{{Name: "y"}})
+	dc.Invalidate("a")
+	if dc.Has("a") {
+		t.Fatalf("a should be invalidated")
+	}
+	// Freed slot: adding c must not evict b
+	dc.Put("c", []panels.Entry{{Name: "z"}})
+	if !dc.Has("b") || !dc.Has("c") {
+		t.Fatalf("b and c should be cached")
+	}
+	fc := NewFileCache(2, 0)
+	fc.Put("x", 0, preview.Result{Content: "1"})
+	fc.Invalidate("x")
+	if fc.Has("x") {
+		t.Fatalf("x should be invalidated")
+	}
+}
+
 func TestFileCacheEviction(t *testing.T) {
-	c := NewFileCache(1)
-	c.Put("x", preview.Result{Kind: "text", Content: "hello"})
+	c := NewFileCache(1, 0)
+	c.Put("x", 0, preview.Result{Kind: "text", Content: "hello"})
 	if !c.Has("x") {
 		t.Fatalf("cache should contain x")
 	}
-	c.Put("y", preview.Result{Kind: "info", Content: "meta"})
+	c.Put("y", 0, preview.Result{Kind: "info", Content: "meta"})
 	if c.Has("x") {
 		t.Fatalf("x should be evicted")
 	}
-	if _, ok := c.Get("y"); !ok {
+	if _, ok := c.Get("y", 0); !ok {
 		t.Fatalf("y should exist")
 	}
 }
+
+func TestDirCacheGetBumpsRecency(t *testing.T) {
+	c := NewDirCache(2)
+	c.Put("a", []panels.Entry{{Name: "a"}})
+	c.Put("b", []panels.Entry{{Name: "b"}})
+	_ = c.Get("a")
+	c.Put("c", []panels.Entry{{Name: "c"}})
+	if !c.Has("a") || c.Has("b") {
+		t.Fatalf("b should be evicted as least recently used")
+	}
+}
+
+func TestPutOverwrites(t *testing.T) {
+	dc := NewDirCache(2)
+	dc.Put("a", []panels.Entry{{Name: "old"}})
+	dc.Put("a", []panels.Entry{{Name: "new"}})
+	if got := dc.Get("a"); len(got) != 1 || got[0].Name != "new" {
+		t.Fatalf("Get(a) = %v; want new listing", got)
+	}
+	if dc.Len() != 1 {
+		t.Fatalf("Len = %d; want 1", dc.Len())
+	}
+	dc.Put("empty", nil)
+	if got := dc.Get("empty"); got == nil || len(got) != 0 {
+		t.Fatalf("empty listing should be a non-nil hit: %#v", got)
+	}
+	fc := NewFileCache(2, 0)
+	fc.Put("x", 0, preview.Result{Content: "1"})
+	fc.Put("x", 0, preview.Result{Content: "2"})
+	if r, _ := fc.Get("x", 0); r.Content != "2" {
+		t.Fatalf("Get(x) = %q; want 2", r.Content)
+	}
+}
+
+func TestEntriesValidatedAgainstDisk(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(file, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dc := NewDirCache(4)
+	dc.Put(dir, []panels.Entry{{Name: "f.txt"}})
+	fc := NewFileCache(4, 0)
+	fc.Put(file, 0, preview.Result{Content: "one"})
+	if !dc.Has(dir) || !fc.Has(file) {
+		t.Fatalf("fresh entries should be valid")
+	}
+	// Changing the size invalidates the preview; a new name changes the
+	// directory's mtime.
+	if err := os.WriteFile(file, []byte("three"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fc.Get(file, 0); ok {
+		t.Fatalf("preview of a modified file should be dropped")
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(dir, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if dc.Get(dir) != nil {
+		t.Fatalf("listing of a modified directory should be dropped")
+	}
+	// A path that does not exist stays cached while it is missing.
+	gone := filepath.Join(dir, "gone")
+	dc.Put(gone, nil)
+	if !dc.Has(gone) {
+		t.Fatalf("missing directory should stay cached")
+	}
+}
+
+func TestInvalidatePrefix(t *testing.T) {
+	dc := NewDirCache(8)
+	for _, k := range []string{"/a", "/a/b", "/a/b/c", "/ab", "/z"} {
+		dc.Put(k, []panels.Entry{{Name: k}})
+	}
+	dc.InvalidatePrefix("/a")
+	for k, want := range map[string]bool{"/a": false, "/a/b": false, "/a/b/c": false, "/ab": true, "/z": true} {
+		if dc.Has(k) != want {
+			t.Fatalf("Has(%s) = %v; want %v", k, !want, want)
+		}
+	}
+	fc := NewFileCache(8, 0)
+	fc.Put("/a/f", 0, preview.Result{Content: "x"})
+	fc.InvalidatePrefix("/a/")
+	if fc.Has("/a/f") {
+		t.Fatalf("/a/f should be invalidated")
+	}
+}
+
+func TestFileCacheByteBudget(t *testing.T) {
+	c := NewFileCache(10, 100)
+	c.Put("a", 0, preview.Result{Content: strings.Repeat("x", 40)})
+	c.Put("b", 0, preview.Result{Content: strings.Repeat("x", 40)})
+	_, _ = c.Get("a", 0)
+	c.Put("c", 0, preview.Result{Content: strings.Repeat("x", 40)})
+	if !c.Has("a") || c.Has("b") || !c.Has("c") {
+		t.Fatalf("b should be evicted to honor the budget")
+	}
+	if c.Bytes() > 100 {
+		t.Fatalf("Bytes = %d; over budget", c.Bytes())
+	}
+	c.Put("huge", 0, preview.Result{Content: strings.Repeat("x", 200)})
+	if c.Has("huge") || !c.Has("a") {
+		t.Fatalf("oversized preview should not be cached nor evict others")
+	}
+}
+
+func TestDiskCacheKeyedByStampAndWidth(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "img.png")
+	if err := os.WriteFile(src, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := preview.Result{Kind: "image", Content: "@@", Mime: "image/png"}
+	if err := d.Put(src, 40, res); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := d.Get(src, 40); !ok || got != res {
+		t.Fatalf("Get = %#v, %v", got, ok)
+	}
+	if _, ok := d.Get(src, 80); ok {
+		t.Fatalf("other width should miss")
+	}
+	if err := os.WriteFile(src, []byte("png2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get(src, 40); ok {
+		t.Fatalf("modified file should miss")
+	}
+	if st := d.Stats(); st.Entries != 1 || st.Hits != 1 || st.Misses != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+	if err := d.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if st := d.Stats(); st.Entries != 0 || st.Bytes != 0 {
+		t.Fatalf("stats after clear = %+v", st)
+	}
+}
+
+func TestDiskCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	src := t.TempDir()
+	files := make([]string, 4)
+	for i := range files {
+		files[i] = filepath.Join(src, string(rune('a'+i)))
+		if err := os.WriteFile(files[i], nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := preview.Result{Kind: "image", Content: strings.Repeat("x", 100)}
+	b, _ := json.Marshal(res)
+	d, err := OpenDisk(t.TempDir(), int64(len(b))*3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	for i, f := range files[:3] {
+		if err := d.Put(f, 0, res); err != nil {
+			t.Fatal(err)
+		}
+		// Give entries distinct ages: a is the oldest.
+		age := old.Add(time.Duration(i) * time.Minute)
+		_ = os.Chtimes(d.file(f, 0), age, age)
+	}
+	if _, ok := d.Get(files[0], 0); !ok { // a becomes the most recent
+		t.Fatalf("a should be cached")
+	}
+	if err := d.Put(files[3], 0, res); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get(files[1], 0); ok {
+		t.Fatalf("b should be evicted as least recently used")
+	}
+	for _, f := range []string{files[0], files[3]} {
+		if _, ok := d.Get(f, 0); !ok {
+			t.Fatalf("%s should be cached", f)
+		}
+	}
+	if st := d.Stats(); st.Bytes > st.Limit {
+		t.Fatalf("over limit: %+v", st)
+	}
+	// Reopening counts what is on disk.
+	d2, err := OpenDisk(d.dir, d.limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d2.Stats().Entries != d.Stats().Entries || d2.Stats().Bytes != d.Stats().Bytes {
+		t.Fatalf("reopened stats %+v != %+v", d2.Stats(), d.Stats())
+	}
+}
+
+func TestFileCacheDiskTier(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "img.png")
+	if err := os.WriteFile(src, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := NewFileCache(4, 0)
+	fc.SetDisk(d)
+	fc.Put(src, 0, preview.Result{Kind: "image", Content: "@@"})
+	fc.Put(src+".txt", 0, preview.Result{Kind: "text", Content: "plain"})
+	if d.Stats().Entries != 1 {
+		t.Fatalf("only non-text previews should be persisted: %+v", d.Stats())
+	}
+	// A fresh session finds the preview on disk and keeps it in memory.
+	next := NewFileCache(4, 0)
+	next.SetDisk(d)
+	if r, ok := next.Get(src, 0); !ok || r.Content != "@@" {
+		t.Fatalf("disk tier miss: %#v %v", r, ok)
+	}
+	if !next.Has(src) {
+		t.Fatalf("disk hit should populate memory")
+	}
+	if _, ok := next.Get(src, 30); ok {
+		t.Fatalf("width mismatch should miss")
+	}
+}
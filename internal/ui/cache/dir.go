@@ -4,34 +4,48 @@ import (
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
 )
 
+// DirCache keeps recently used directory listings. A listing is only
+// returned while the directory's mtime and size match the moment it was
+// stored. The cache shares its state between copies.
 type DirCache struct {
-	m     map[string][]panels.Entry
-	order []string
-	max   int
+	c *lru[[]panels.Entry]
 }
 
 func NewDirCache(max int) DirCache {
 	if max <= 0 {
 		max = 64
 	}
-	return DirCache{m: make(map[string][]panels.Entry), max: max}
+	return DirCache{c: newLRU[[]panels.Entry](max, 0)}
 }
 
-func (c *DirCache) Get(key string) []panels.Entry { return c.m[key] }
-func (c *DirCache) Has(key string) bool           { _, ok := c.m[key]; return ok }
+// Get returns the listing for key (nil on a miss) and marks it recently used.
+func (c *DirCache) Get(key string) []panels.Entry {
+	v, _ := c.c.get(key)
+	return v
+}
+
+func (c *DirCache) Has(key string) bool { return c.c.has(key) }
+
+// Invalidate drops the cached listing for key.
+func (c *DirCache) Invalidate(key string) { c.c.invalidate(key) }
+
+// InvalidatePrefix drops the listings of prefix and every directory below it.
+func (c *DirCache) InvalidatePrefix(prefix string) { c.c.invalidatePrefix(prefix) }
 
+// Clear drops every cached listing.
+func (c *DirCache) Clear() { c.c.clear() }
+
+// Len returns the number of cached listings.
+func (c *DirCache) Len() int { return c.c.len() }
+
+// Put stores the listing for key, replacing an older one. An empty directory
+// is stored as an empty, non-nil listing so that Get can report the hit.
 func (c *DirCache) Put(key string, val []panels.Entry) {
 	if val == nil {
-		return
-	}
-	if _, ok := c.m[key]; ok {
-		return
+		val = []panels.Entry{}
 	}
-	if len(c.order) >= c.max {
-		old := c.order[0]
-		delete(c.m, old)
-		c.order = c.order[1:]
+	if c.c == nil {
+		*c = NewDirCache(0)
 	}
-	c.order = append(c.order, key)
-	c.m[key] = val
+	c.c.put(key, val, 0)
 }
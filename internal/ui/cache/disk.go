@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// DefaultDiskLimit bounds the size of the on-disk preview cache.
+const DefaultDiskLimit = 256 << 20
+
+// DiskCache persists previews across sessions. Entries are keyed by the
+// file's path, mtime and size and the preview width, so a changed file is
+// simply a miss. File mtimes track recency: the least recently used entries
+// are removed once the cache grows past its limit.
+type DiskCache struct {
+	dir   string
+	limit int64
+
+	mu     sync.Mutex
+	bytes  int64
+	count  int
+	hits   int
+	misses int
+}
+
+// DiskStats describes the state of a DiskCache.
+type DiskStats struct {
+	Dir     string
+	Entries int
+	Bytes   int64
+	Limit   int64
+	Hits    int
+	Misses  int
+}
+
+// DefaultDiskDir returns $XDG_CACHE_HOME/tfm/preview (~/.cache/tfm/preview).
+func DefaultDiskDir() string {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tfm", "preview")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "tfm", "preview")
+}
+
+// OpenDisk opens (creating if needed) the cache in dir, holding at most
+// limit bytes (DefaultDiskLimit if limit <= 0).
+func OpenDisk(dir string, limit int64) (*DiskCache, error) {
+	if limit <= 0 {
+		limit = DefaultDiskLimit
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	d := &DiskCache{dir: dir, limit: limit}
+	for _, e := range d.scan() {
+		d.bytes += e.size
+		d.count++
+	}
+	return d, nil
+}
+
+// diskEntry is a cache file found by scan.
+type diskEntry struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+func (d *DiskCache) scan() []diskEntry {
+	var out []diskEntry
+	_ = filepath.WalkDir(d.dir, func(p string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() || strings.HasPrefix(de.Name(), ".") {
+			return nil
+		}
+		if fi, err := de.Info(); err == nil {
+			out = append(out, diskEntry{path: p, size: fi.Size(), mtime: fi.ModTime()})
+		}
+		return nil
+	})
+	return out
+}
+
+// file returns the cache file for path at width, or "" if path is gone.
+func (d *DiskCache) file(path string, width int) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%d", path, fi.ModTime().UnixNano(), fi.Size(), width)))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, name[:2], name)
+}
+
+// Get returns the cached preview of path rendered at width.
+func (d *DiskCache) Get(path string, width int) (preview.Result, bool) {
+	var res preview.Result
+	f := d.file(path, width)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f == "" {
+		d.misses++
+		return res, false
+	}
+	b, err := os.ReadFile(f)
+	if err != nil || json.Unmarshal(b, &res) != nil {
+		d.misses++
+		return preview.Result{}, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(f, now, now)
+	d.hits++
+	return res, true
+}
+
+// Put stores the preview of path rendered at width and evicts the least
+// recently used entries if the cache is over its limit.
+func (d *DiskCache) Put(path string, width int, res preview.Result) error {
+	f := d.file(path, width)
+	if f == "" {
+		return nil
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) > d.limit {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(f), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if fi, err := os.Stat(f); err == nil {
+		d.bytes -= fi.Size()
+		d.count--
+	}
+	if err := os.Rename(tmp.Name(), f); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	d.bytes += int64(len(b))
+	d.count++
+	if d.bytes > d.limit {
+		d.evict()
+	}
+	return nil
+}
+
+// evict removes the oldest entries until the cache is below 90% of its
+// limit, leaving room for a few more writes. d.mu must be held.
+func (d *DiskCache) evict() {
+	entries := d.scan()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].mtime.Before(entries[j].mtime) })
+	d.bytes, d.count = 0, len(entries)
+	for _, e := range entries {
+		d.bytes += e.size
+	}
+	target := d.limit / 10 * 9
+	for _, e := range entries {
+		if d.bytes <= target {
+			break
+		}
+		if os.Remove(e.path) == nil {
+			d.bytes -= e.size
+			d.count--
+		}
+	}
+}
+
+// Clear removes every cached preview.
+func (d *DiskCache) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ents, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range ents {
+		if err := os.RemoveAll(filepath.Join(d.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	d.bytes, d.count = 0, 0
+	return nil
+}
+
+// Stats returns the current size and hit counters of the cache.
+func (d *DiskCache) Stats() DiskStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DiskStats{Dir: d.dir, Entries: d.count, Bytes: d.bytes, Limit: d.limit, Hits: d.hits, Misses: d.misses}
+}
@@ -4,31 +4,105 @@ import (
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
 )
 
+// DefaultPreviewBudget bounds the memory held by cached previews.
+const DefaultPreviewBudget = 32 << 20
+
+// sizedResult is a preview together with the width it was rendered for.
+type sizedResult struct {
+	res   preview.Result
+	width int
+}
+
+// FileCache keeps recently used previews, bounded by an entry count and by
+// the total size of their content. A preview is only returned while the
+// file's mtime and size match the moment it was stored. An optional
+// DiskCache serves as a second tier that survives restarts. The cache
+// shares its state between copies.
 type FileCache struct {
-	m     map[string]preview.Result
-	order []string
-	max   int
+	c    *lru[sizedResult]
+	disk *DiskCache
 }
 
-func NewFileCache(max int) FileCache {
+// NewFileCache creates a cache of at most max previews whose content takes
+// at most budget bytes (DefaultPreviewBudget if budget <= 0).
+func NewFileCache(max int, budget int64) FileCache {
 	if max <= 0 {
 		max = 64
 	}
-	return FileCache{m: make(map[string]preview.Result), max: max}
+	if budget <= 0 {
+		budget = DefaultPreviewBudget
+	}
+	return FileCache{c: newLRU[sizedResult](max, budget)}
 }
 
-func (c *FileCache) Get(key string) (preview.Result, bool) { v, ok := c.m[key]; return v, ok }
-func (c *FileCache) Has(key string) bool                   { _, ok := c.m[key]; return ok }
+// SetDisk attaches d as the second tier (nil detaches it).
+func (c *FileCache) SetDisk(d *DiskCache) { c.disk = d }
+
+// Disk returns the second tier, if any.
+func (c *FileCache) Disk() *DiskCache { return c.disk }
 
-func (c *FileCache) Put(key string, val preview.Result) {
-	if _, ok := c.m[key]; ok {
-		return
+// Get returns the preview of key rendered at width (0 for previews that do
+// not depend on it) and marks it recently used. Memory misses fall back to
+// the disk tier.
+func (c *FileCache) Get(key string, width int) (preview.Result, bool) {
+	if v, ok := c.c.get(key); ok && v.width == width {
+		return v.res, true
+	}
+	if c.disk == nil {
+		return preview.Result{}, false
 	}
-	if len(c.order) >= c.max {
-		old := c.order[0]
-		delete(c.m, old)
-		c.order = c.order[1:]
+	res, ok := c.disk.Get(key, width)
+	if ok {
+		c.putMemory(key, width, res)
 	}
-	c.order = append(c.order, key)
-	c.m[key] = val
+	return res, ok
+}
+
+func (c *FileCache) Has(key string) bool { return c.c.has(key) }
+
+// Invalidate drops the cached preview for key.
+func (c *FileCache) Invalidate(key string) { c.c.invalidate(key) }
+
+// InvalidatePrefix drops the previews of prefix and every path below it.
+func (c *FileCache) InvalidatePrefix(prefix string) { c.c.invalidatePrefix(prefix) }
+
+// Clear drops every preview held in memory.
+func (c *FileCache) Clear() { c.c.clear() }
+
+// Len returns the number of cached previews.
+func (c *FileCache) Len() int { return c.c.len() }
+
+// Bytes returns the memory accounted to cached previews.
+func (c *FileCache) Bytes() int64 { return c.c.size() }
+
+// Put stores the preview of key rendered at width, replacing an older one.
+// A preview larger than the whole budget is not kept in memory. Previews
+// worth persisting are also written to the disk tier.
+func (c *FileCache) Put(key string, width int, val preview.Result) {
+	c.putMemory(key, width, val)
+	if c.disk != nil && persistable(val) {
+		_ = c.disk.Put(key, width, val)
+	}
+}
+
+func (c *FileCache) putMemory(key string, width int, val preview.Result) {
+	if c.c == nil {
+		*c = FileCache{c: NewFileCache(0, 0).c, disk: c.disk}
+	}
+	c.c.put(key, sizedResult{res: val, width: width}, resultSize(key, val))
+}
+
+// persistable reports whether res is worth a disk write: plain text and
+// file info are cheaper to produce again than to load from the cache, and
+// empty results record failures that may not last.
+func persistable(res preview.Result) bool {
+	switch res.Kind {
+	case "", "text", "info":
+		return false
+	}
+	return res.Content != ""
+}
+
+func resultSize(key string, r preview.Result) int64 {
+	return int64(len(key) + len(r.Kind) + len(r.Content) + len(r.Mime) + len(r.Lang))
 }
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"container/list"
+	"os"
+	"strings"
+)
+
+// stamp identifies a version of a file or directory on disk. A missing path
+// has the zero stamp, so a cached miss stays valid while the path is absent.
+type stamp struct {
+	mtime int64
+	size  int64
+}
+
+// statStamp returns the current stamp of path.
+func statStamp(path string) stamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{mtime: fi.ModTime().UnixNano(), size: fi.Size()}
+}
+
+type lruItem[V any] struct {
+	key   string
+	val   V
+	st    stamp
+	bytes int64
+}
+
+// lru is a least-recently-used map keyed by path. A nil *lru is an empty
+// cache. Entries are validated
+// against the path's stamp on lookup. It is bounded by an entry count and,
+// if budget > 0, by the sum of entry sizes.
+type lru[V any] struct {
+	ll     *list.List
+	items  map[string]*list.Element
+	max    int
+	budget int64
+	bytes  int64
+}
+
+func newLRU[V any](max int, budget int64) *lru[V] {
+	return &lru[V]{ll: list.New(), items: make(map[string]*list.Element), max: max, budget: budget}
+}
+
+// get returns the value for key and marks it most recently used. Entries
+// whose path changed on disk since they were stored are dropped.
+func (c *lru[V]) get(key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	it := el.Value.(*lruItem[V])
+	if statStamp(key) != it.st {
+		c.remove(el)
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	return it.val, true
+}
+
+// has reports whether a valid entry exists for key without bumping it.
+func (c *lru[V]) has(key string) bool {
+	if c == nil {
+		return false
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	if statStamp(key) != el.Value.(*lruItem[V]).st {
+		c.remove(el)
+		return false
+	}
+	return true
+}
+
+// put stores val (replacing any previous value) stamped with the current
+// state of key. Values larger than the whole budget are not cached.
+func (c *lru[V]) put(key string, val V, bytes int64) {
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if c.budget > 0 && bytes > c.budget {
+		return
+	}
+	it := &lruItem[V]{key: key, val: val, st: statStamp(key), bytes: bytes}
+	c.items[key] = c.ll.PushFront(it)
+	c.bytes += bytes
+	for c.ll.Len() > c.max || (c.budget > 0 && c.bytes > c.budget) {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *lru[V]) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// invalidatePrefix drops prefix and every path below it.
+func (c *lru[V]) invalidatePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+	dir := strings.TrimSuffix(prefix, string(os.PathSeparator)) + string(os.PathSeparator)
+	for key, el := range c.items {
+		if key == prefix || strings.HasPrefix(key, dir) {
+			c.remove(el)
+		}
+	}
+}
+
+// clear drops every entry.
+func (c *lru[V]) clear() {
+	if c == nil {
+		return
+	}
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+func (c *lru[V]) remove(el *list.Element) {
+	it := el.Value.(*lruItem[V])
+	c.ll.Remove(el)
+	delete(c.items, it.key)
+	c.bytes -= it.bytes
+}
+
+func (c *lru[V]) len() int {
+	if c == nil {
+		return 0
+	}
+	return c.ll.Len()
+}
+
+func (c *lru[V]) size() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.bytes
+}
@@ -0,0 +1,48 @@
+package panels
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+)
+
+// IsArchive reports whether path is an archive file that can be browsed
+// like a directory.
+func IsArchive(path string) bool {
+	_, name, ok := archive.Split(path)
+	return ok && name == ""
+}
+
+// ArchiveTimeout bounds how long Refresh and Chdir wait for an archive to
+// be indexed, like the default stall timeout of streamed listings. Stream
+// stops when its ctx is done.
+const ArchiveTimeout = 10 * time.Second
+
+// readArchiveDir lists dir when it lies inside an archive file (see
+// archive.Split); ok is false for other directories. Entries carry the
+// size and time recorded in the archive.
+func readArchiveDir(ctx context.Context, dir string, showHidden bool) (entries []Entry, ok bool, err error) {
+	file, name, ok := archive.Split(dir)
+	if !ok {
+		return nil, false, nil
+	}
+	a, err := archive.Load(ctx, file)
+	if err != nil {
+		return nil, true, err
+	}
+	members, err := a.ReadDir(name)
+	if err != nil {
+		return nil, true, err
+	}
+	entries = make([]Entry, 0, len(members))
+	for _, e := range members {
+		base := path.Base(e.Name)
+		if !showHidden && base[0] == '.' {
+			continue
+		}
+		entries = append(entries, Entry{Name: base, IsDir: e.IsDir, Size: e.Size, ModTime: e.ModTime, Info: true})
+	}
+	return entries, true, nil
+}
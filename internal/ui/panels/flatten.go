@@ -0,0 +1,157 @@
+package panels
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ignore"
+)
+
+// ErrTooManyEntries is returned by Flatten when the listing was cut at
+// FlattenOptions.Max entries.
+var ErrTooManyEntries = errors.New("too many entries")
+
+// FlattenOptions controls Flatten.
+type FlattenOptions struct {
+	Depth      int  // levels below dir to include (0 = unlimited)
+	ShowHidden bool // include dotfiles and dot-directories
+	GitIgnore  bool // skip paths excluded by .gitignore files
+	Max        int  // stop after this many entries (0 = unlimited)
+}
+
+// Flatten lists every descendant of dir in one slice. Names are paths
+// relative to dir and Info is filled in. Symlinks below dir are not
+// followed; dir itself may be one. On ErrTooManyEntries the entries read so
+// far are returned as well.
+func Flatten(ctx context.Context, dir string, opts FlattenOptions) ([]Entry, error) {
+	var ign *ignore.Matcher
+	if opts.GitIgnore {
+		ign = ignore.New(dir)
+	}
+	// WalkDir does not descend into a root that is a symlink.
+	root := dir
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		root = real
+	}
+	var out []Entry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if p == root {
+			return err
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if err != nil {
+			// An unreadable subdirectory was already listed; skip its contents.
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		name := d.Name()
+		skip := func() error {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !opts.ShowHidden && strings.HasPrefix(name, ".") {
+			return skip()
+		}
+		if d.IsDir() && name == ".git" {
+			return filepath.SkipDir
+		}
+		if ign != nil && ign.Ignored(rel, d.IsDir()) {
+			return skip()
+		}
+		e := Entry{Name: rel, IsDir: d.IsDir()}
+		if fi, ierr := d.Info(); ierr == nil {
+			e.Size, e.ModTime, e.Info = fi.Size(), fi.ModTime(), true
+		}
+		out = append(out, e)
+		if opts.Max > 0 && len(out) >= opts.Max {
+			return ErrTooManyEntries
+		}
+		if d.IsDir() && opts.Depth > 0 && strings.Count(rel, string(filepath.Separator))+1 >= opts.Depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return out, err
+}
+
+// FillInfo stats the entries of a listing of dir that have no Info yet.
+func FillInfo(dir string, entries []Entry) {
+	for i := range entries {
+		e := &entries[i]
+		if e.Info {
+			continue
+		}
+		if fi, err := os.Lstat(filepath.Join(dir, e.Name)); err == nil {
+			e.Size, e.ModTime, e.Info = fi.Size(), fi.ModTime(), true
+		}
+	}
+}
+
+// SortKeys lists the orders accepted by SortBy.
+var SortKeys = []string{"name", "size", "mtime", "ext"}
+
+// SortBy orders a listing of dir by key (one of SortKeys), directories
+// first if dirsFirst is set. Size and mtime fill in missing Info. Ties are
+// broken by name.
+func SortBy(dir string, entries []Entry, key string, desc, dirsFirst bool) error {
+	var less func(a, b *Entry) int
+	switch key {
+	case "name":
+		less = func(a, b *Entry) int { return 0 }
+	case "size":
+		FillInfo(dir, entries)
+		less = func(a, b *Entry) int { return cmpInt64(sortSize(a), sortSize(b)) }
+	case "mtime", "time":
+		FillInfo(dir, entries)
+		less = func(a, b *Entry) int { return a.ModTime.Compare(b.ModTime) }
+	case "ext":
+		less = func(a, b *Entry) int {
+			return strings.Compare(strings.ToLower(filepath.Ext(a.Name)), strings.ToLower(filepath.Ext(b.Name)))
+		}
+	default:
+		return errors.New("unknown sort key: " + key)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if dirsFirst && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		c := less(a, b)
+		if c == 0 {
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return nil
+}
+
+// sortSize is the size an entry sorts by; the size of a directory inode
+// says nothing about its contents, so directories count as empty.
+func sortSize(e *Entry) int64 {
+	if e.IsDir {
+		return 0
+	}
+	return e.Size
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -1,9 +1,10 @@
 package panels
 
 import (
+	"context"
 	"os"
 	"path/filepath"
-	"sort"
+	"time"
 	"unicode/utf8"
 )
 
@@ -11,6 +12,11 @@ import (
 type Entry struct {
 	Name  string
 	IsDir bool
+	// Size and ModTime are only meaningful when Info is set; plain listings
+	// leave them empty (see FillInfo).
+	Size    int64
+	ModTime time.Time
+	Info    bool
 }
 
 // Panel holds current directory state and entries.
@@ -26,7 +32,8 @@ func NewPanel(cwd string, showHidden bool) *Panel {
 	return &Panel{Cwd: cwd, ShowHidden: showHidden}
 }
 
-// Refresh reads the current directory and updates entries.
+// Refresh reads the current directory and updates entries. Directories
+// inside archive files are listed from the archive (see readArchiveDir).
 func (p *Panel) Refresh() error {
 	if p.Cwd == "" {
 		cwd, err := os.Getwd()
@@ -35,6 +42,16 @@ func (p *Panel) Refresh() error {
 		}
 		p.Cwd = cwd
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), ArchiveTimeout)
+	defer cancel()
+	if entries, ok, err := readArchiveDir(ctx, p.Cwd, p.ShowHidden); ok {
+		if err != nil {
+			return err
+		}
+		SortEntries(entries)
+		p.Entries, p.MaxDirName = entries, MaxDirNameOf(entries)
+		return nil
+	}
 	ents, err := os.ReadDir(p.Cwd)
 	if err != nil {
 		return err
@@ -55,12 +72,7 @@ func (p *Panel) Refresh() error {
 			}
 		}
 	}
-	sort.Slice(p.Entries, func(i, j int) bool {
-		if p.Entries[i].IsDir != p.Entries[j].IsDir {
-			return p.Entries[i].IsDir
-		}
-		return p.Entries[i].Name < p.Entries[j].Name
-	})
+	SortEntries(p.Entries)
 	return nil
 }
 
@@ -75,6 +87,16 @@ func (p *Panel) Chdir(dir string) error {
 	if dir == "" {
 		return os.ErrInvalid
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), ArchiveTimeout)
+	defer cancel()
+	if entries, ok, err := readArchiveDir(ctx, dir, p.ShowHidden); ok {
+		if err != nil {
+			return err
+		}
+		SortEntries(entries)
+		p.Cwd, p.Entries, p.MaxDirName = dir, entries, MaxDirNameOf(entries)
+		return nil
+	}
 	ents, err := os.ReadDir(dir)
 	if err != nil {
 		return err
@@ -96,12 +118,7 @@ func (p *Panel) Chdir(dir string) error {
 			}
 		}
 	}
-	sort.Slice(next, func(i, j int) bool {
-		if next[i].IsDir != next[j].IsDir {
-			return next[i].IsDir
-		}
-		return next[i].Name < next[j].Name
-	})
+	SortEntries(next)
 	p.Cwd = dir
 	p.Entries = next
 	p.MaxDirName = maxDir
@@ -1,8 +1,13 @@
 package panels
 
 import (
+	"archive/zip"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -86,3 +91,166 @@ func TestJoin(t *testing.T) {
 		t.Fatalf("Join returned %q", got)
 	}
 }
+
+func TestStreamBatchesAndCancel(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c", ".h"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "zdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan []Entry, 16)
+	if err := Stream(context.Background(), dir, false, 2, out); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	close(out)
+	var all []Entry
+	for b := range out {
+		if len(b) > 2 {
+			t.Fatalf("batch larger than n: %v", b)
+		}
+		all = append(all, b...)
+	}
+	SortEntries(all)
+	if len(all) != 4 || all[0].Name != "zdir" || all[1].Name != "a" {
+		t.Fatalf("entries = %v", all)
+	}
+	if got := MaxDirNameOf(all); got != 5 {
+		t.Fatalf("MaxDirNameOf = %d; want 5", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Stream(ctx, dir, true, 1, make(chan []Entry)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Stream err = %v", err)
+	}
+	if err := Stream(context.Background(), filepath.Join(dir, "missing"), false, 1, make(chan []Entry, 1)); err == nil {
+		t.Fatalf("expected error for missing dir")
+	}
+}
+
+func TestFlattenDepthHiddenAndGitignore(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a/b/c/deep.txt", "a/x.go", ".hidden/h", "logs/out.log", "top.txt", ".gitignore"} {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("logs/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names := func(es []Entry) string {
+		var out []string
+		for _, e := range es {
+			out = append(out, filepath.ToSlash(e.Name))
+		}
+		sort.Strings(out)
+		return strings.Join(out, " ")
+	}
+	all, err := Flatten(context.Background(), dir, FlattenOptions{GitIgnore: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(all), "a a/b a/b/c a/b/c/deep.txt a/x.go top.txt"; got != want {
+		t.Fatalf("Flatten = %q; want %q", got, want)
+	}
+	for _, e := range all {
+		if !e.Info {
+			t.Fatalf("%s has no info", e.Name)
+		}
+	}
+	two, _ := Flatten(context.Background(), dir, FlattenOptions{Depth: 2, ShowHidden: true})
+	if got, want := names(two), ".gitignore .hidden .hidden/h a a/b a/x.go logs logs/out.log top.txt"; got != want {
+		t.Fatalf("depth 2 = %q; want %q", got, want)
+	}
+	capped, err := Flatten(context.Background(), dir, FlattenOptions{Max: 2})
+	if !errors.Is(err, ErrTooManyEntries) || len(capped) != 2 {
+		t.Fatalf("Max: %d entries, err=%v", len(capped), err)
+	}
+	// A directory entered through a symlink is walked too.
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(filepath.Join(dir, "a"), link); err != nil {
+		t.Fatal(err)
+	}
+	linked, err := Flatten(context.Background(), link, FlattenOptions{})
+	if got, want := names(linked), "b b/c b/c/deep.txt x.go"; err != nil || got != want {
+		t.Fatalf("through a symlink = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"small.txt": 1, "big.bin": 300, "mid.go": 20} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := []Entry{{Name: "small.txt"}, {Name: "big.bin"}, {Name: "sub", IsDir: true}, {Name: "mid.go"}}
+	if err := SortBy(dir, entries, "size", true, false); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name)
+	}
+	// sub does not exist on disk: its size stays zero.
+	if strings.Join(got, " ") != "big.bin mid.go small.txt sub" {
+		t.Fatalf("size desc = %v", got)
+	}
+	if err := SortBy(dir, entries, "ext", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Name != "sub" || entries[1].Name != "big.bin" || entries[2].Name != "mid.go" {
+		t.Fatalf("ext with dirs first = %v", entries)
+	}
+	if err := SortBy(dir, entries, "colour", false, true); err == nil {
+		t.Fatalf("unknown key accepted")
+	}
+}
+
+func TestArchiveDirectories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"lib/x.go", ".hidden", "readme"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	if !IsArchive(path) || IsArchive(filepath.Join(path, "lib")) {
+		t.Fatal("IsArchive")
+	}
+	p := NewPanel(path, false)
+	if err := p.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 || p.Entries[0].Name != "lib" || !p.Entries[0].IsDir || p.Entries[1].Name != "readme" || p.Entries[1].Size != 6 {
+		t.Fatalf("archive root: %+v", p.Entries)
+	}
+	if err := p.Chdir(filepath.Join(path, "lib")); err != nil || len(p.Entries) != 1 || p.Entries[0].Name != "x.go" {
+		t.Fatalf("Chdir: %+v, %v", p.Entries, err)
+	}
+	if err := p.Chdir(filepath.Join(path, "missing")); err == nil || p.Cwd != filepath.Join(path, "lib") {
+		t.Fatalf("Chdir to a missing member: %v, cwd %q", err, p.Cwd)
+	}
+
+	out := make(chan []Entry, 1)
+	if err := Stream(context.Background(), path, true, 1, out); err != nil {
+		t.Fatal(err)
+	}
+	if batch := <-out; len(batch) != 3 {
+		t.Fatalf("Stream: %+v", batch)
+	}
+}
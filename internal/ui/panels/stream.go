@@ -0,0 +1,87 @@
+package panels
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"unicode/utf8"
+)
+
+// Stream reads dir in batches of up to n entries and sends each non-empty
+// batch to out, skipping dotfiles unless showHidden is set. Entries are sent
+// in directory order (unsorted). It returns ctx.Err() if cancelled and the
+// first read error otherwise. A directory inside an archive file is sent in
+// one batch once the archive has been indexed, which also stops with ctx.
+func Stream(ctx context.Context, dir string, showHidden bool, n int, out chan<- []Entry) error {
+	if n <= 0 {
+		n = 1024
+	}
+	if entries, ok, err := readArchiveDir(ctx, dir, showHidden); ok {
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+		select {
+		case out <- entries:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ents, rerr := f.ReadDir(n)
+		batch := make([]Entry, 0, len(ents))
+		for _, e := range ents {
+			name := e.Name()
+			if !showHidden && len(name) > 0 && name[0] == '.' {
+				continue
+			}
+			batch = append(batch, Entry{Name: name, IsDir: e.IsDir()})
+		}
+		if len(batch) > 0 {
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if errors.Is(rerr, io.EOF) {
+			return nil
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+}
+
+// SortEntries orders entries with directories first, then by name.
+func SortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// MaxDirNameOf returns the maximum rune-length of directory names (plus slash).
+func MaxDirNameOf(entries []Entry) int {
+	maxDir := 0
+	for _, e := range entries {
+		if e.IsDir {
+			if l := utf8.RuneCountInString(e.Name) + 1; l > maxDir {
+				maxDir = l
+			}
+		}
+	}
+	return maxDir
+}
@@ -0,0 +1,171 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+)
+
+// Limits of an archive listing: huge or slowly decompressing archives are
+// listed in part.
+const (
+	ArchiveMaxEntries = 10000
+	ArchiveTimeout    = 2 * time.Second
+)
+
+// ArchiveProvider previews archives as a tree of their members and leaves
+// other files, and archives it cannot read, to Next. Listing an archive can
+// take up to ArchiveTimeout, so the TUI calls it in the background.
+type ArchiveProvider struct {
+	Next Provider
+}
+
+func (p ArchiveProvider) Preview(path string, maxBytes int) (Result, error) {
+	if archive.Detect(path) != "" {
+		if res, err := ArchiveSummary(path); err == nil {
+			return res, nil
+		}
+	}
+	return p.Next.Preview(path, maxBytes)
+}
+
+// ArchiveSummary lists the archive at path as a tree with the number and
+// size of the files in each directory, within ArchiveMaxEntries and
+// ArchiveTimeout. The result has Kind "archive".
+func ArchiveSummary(path string) (Result, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ArchiveTimeout)
+	defer cancel()
+	entries, err := archive.List(ctx, path, ArchiveMaxEntries)
+	var note string
+	switch {
+	case errors.Is(err, archive.ErrTooManyEntries):
+		note = fmt.Sprintf("(first %d entries)", len(entries))
+	case errors.Is(err, context.DeadlineExceeded):
+		note = fmt.Sprintf("(entries read in %s)", ArchiveTimeout)
+	case err != nil && len(entries) == 0:
+		return Result{}, err
+	case err != nil:
+		note = "(listing stopped: " + err.Error() + ")"
+	}
+
+	root := &archiveNode{dir: true}
+	for _, e := range entries {
+		root.add(e)
+	}
+	root.sum()
+	format := archive.Detect(path)
+	lines := []string{fmt.Sprintf("%s archive: %d files, %d directories, %s (%s packed)",
+		format, root.files, root.dirs, humanSize(root.size), humanSize(fi.Size()))}
+	if note != "" {
+		lines = append(lines, note)
+	}
+	lines = append(lines, "")
+	root.render("", &lines)
+	mime := "application/x-tar"
+	if format == archive.Zip {
+		mime = "application/zip"
+	}
+	return Result{Kind: "archive", Content: strings.Join(lines, "\n"), Mime: mime}, nil
+}
+
+// archiveNode is a file or directory in the tree of an archive listing.
+// Directories sum up the files below them.
+type archiveNode struct {
+	name     string
+	entry    archive.Entry
+	dir      bool
+	children map[string]*archiveNode
+	files    int   // files below a directory (see sum)
+	dirs     int   // directories below a directory
+	size     int64 // size of the files below a directory
+}
+
+// add inserts e under n, creating the directories on its path that the
+// archive does not list itself.
+func (n *archiveNode) add(e archive.Entry) {
+	parts := strings.Split(e.Name, "/")
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if n.children == nil {
+			n.children = map[string]*archiveNode{}
+		}
+		c, ok := n.children[part]
+		if !ok {
+			c = &archiveNode{name: part, dir: !last || e.IsDir}
+			n.children[part] = c
+		}
+		if last {
+			c.entry = e
+		}
+		n = c
+	}
+}
+
+// render appends the lines of the children of n, directories first, to
+// lines.
+func (n *archiveNode) render(indent string, lines *[]string) {
+	kids := make([]*archiveNode, 0, len(n.children))
+	for _, c := range n.children {
+		kids = append(kids, c)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if kids[i].dir != kids[j].dir {
+			return kids[i].dir
+		}
+		return kids[i].name < kids[j].name
+	})
+	for _, c := range kids {
+		switch {
+		case c.dir:
+			*lines = append(*lines, fmt.Sprintf("%s%s/  (%d files, %s)", indent, c.name, c.files, humanSize(c.size)))
+			c.render(indent+"  ", lines)
+		case c.entry.Link != "":
+			*lines = append(*lines, fmt.Sprintf("%s%s -> %s", indent, c.name, c.entry.Link))
+		default:
+			*lines = append(*lines, fmt.Sprintf("%s%s  %s  %s", indent, c.name, humanSize(c.entry.Size), c.entry.ModTime.Local().Format("2006-01-02 15:04")))
+		}
+	}
+}
+
+// sum totals the files, directories and sizes below n.
+func (n *archiveNode) sum() {
+	if !n.dir || n.children == nil {
+		return
+	}
+	n.files, n.dirs, n.size = 0, 0, 0
+	for _, c := range n.children {
+		if c.dir {
+			c.sum()
+			n.dirs += 1 + c.dirs
+			n.files += c.files
+			n.size += c.size
+		} else {
+			n.files++
+			n.size += c.entry.Size
+		}
+	}
+}
+
+// humanSize formats n bytes like 1.5MiB.
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
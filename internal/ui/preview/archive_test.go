@@ -0,0 +1,61 @@
+package preview
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range map[string]string{
+		"src/main.go":   "package main\n",
+		"src/util/x.go": "package util\n",
+		"README":        strings.Repeat("x", 2048),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	res, err := ArchiveProvider{Next: BasicProvider{}}.Preview(path, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Kind != "archive" || res.Mime != "application/zip" {
+		t.Fatalf("kind %q, mime %q", res.Kind, res.Mime)
+	}
+	lines := strings.Split(res.Content, "\n")
+	if !strings.HasPrefix(lines[0], "zip archive: 3 files, 2 directories, 2.0KiB") {
+		t.Errorf("header %q", lines[0])
+	}
+	want := []string{"src/  (2 files, 26B)", "  util/  (1 files, 13B)", "    x.go  13B", "  main.go  13B", "README  2.0KiB"}
+	for i, w := range want {
+		if l := lines[2+i]; !strings.HasPrefix(l, w) {
+			t.Errorf("line %d = %q; want prefix %q", 2+i, l, w)
+		}
+	}
+}
+
+func TestArchiveProviderFallsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.zip")
+	os.WriteFile(path, []byte("not a zip\n"), 0o644)
+	res, err := ArchiveProvider{Next: BasicProvider{}}.Preview(path, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Kind == "archive" {
+		t.Errorf("broken archive previewed as %q", res.Kind)
+	}
+}
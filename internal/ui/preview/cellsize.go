@@ -0,0 +1,43 @@
+package preview
+
+// CellSize is the size of a terminal cell in pixels.
+type CellSize struct {
+	W, H int
+}
+
+// DefaultCellSize is assumed when the terminal does not report its pixel
+// size: cells twice as high as wide.
+var DefaultCellSize = CellSize{W: 8, H: 16}
+
+// TermCellSize returns the cell size of the terminal on stdout, or
+// DefaultCellSize if it is not known.
+func TermCellSize() CellSize {
+	if c, ok := termCellSize(); ok {
+		return c
+	}
+	return DefaultCellSize
+}
+
+// FitCells returns the number of cells an image of w×h pixels covers when
+// scaled to fit in cols×rows cells of size cell, keeping its aspect ratio.
+// Images are not enlarged beyond their own size.
+func FitCells(w, h, cols, rows int, cell CellSize) (int, int) {
+	if w <= 0 || h <= 0 || cols <= 0 || rows <= 0 {
+		return 0, 0
+	}
+	if cell.W <= 0 || cell.H <= 0 {
+		cell = DefaultCellSize
+	}
+	boxW, boxH := cols*cell.W, rows*cell.H
+	// Scale by the limiting side, never up.
+	sw, sh := w, h
+	if sw > boxW {
+		sw, sh = boxW, h*boxW/w
+	}
+	if sh > boxH {
+		sw, sh = w*boxH/h, boxH
+	}
+	c := (max(sw, 1) + cell.W - 1) / cell.W
+	r := (max(sh, 1) + cell.H - 1) / cell.H
+	return min(c, cols), min(r, rows)
+}
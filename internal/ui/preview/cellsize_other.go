@@ -0,0 +1,5 @@
+//go:build !unix
+
+package preview
+
+func termCellSize() (CellSize, bool) { return CellSize{}, false }
@@ -0,0 +1,17 @@
+//go:build unix
+
+package preview
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func termCellSize() (CellSize, bool) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return CellSize{}, false
+	}
+	return CellSize{W: int(ws.Xpixel / ws.Col), H: int(ws.Ypixel / ws.Row)}, true
+}
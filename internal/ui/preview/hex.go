@@ -0,0 +1,114 @@
+package preview
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// hexRowWidth is the width in cells of a hex dump row of n bytes:
+// "00000000  xx xx … xx  xx … xx  |ascii|".
+func hexRowWidth(n int) int {
+	return 8 + 2 + 3*n + (n-1)/8 + 1 + n + 2
+}
+
+// HexBytesPerRow returns the number of bytes per hex dump row: want, or
+// fewer if such rows do not fit in width cells.
+func HexBytesPerRow(width, want int) int {
+	n := want
+	if n <= 0 {
+		n = 16
+	}
+	for n > 1 && hexRowWidth(n) > width {
+		n--
+	}
+	return n
+}
+
+// HexRows returns the number of rows of perRow bytes needed for size bytes.
+func HexRows(size int64, perRow int) int {
+	if perRow <= 0 || size <= 0 {
+		return 0
+	}
+	return int((size + int64(perRow) - 1) / int64(perRow))
+}
+
+// HexDump formats rows of perRow bytes of r starting at byte offset off in
+// the style of hexdump -C: the offset, the bytes in groups of eight and
+// their printable ASCII characters. Only the requested bytes are read.
+func HexDump(r io.ReaderAt, off int64, perRow, rows int) ([][]Span, error) {
+	if perRow <= 0 || rows <= 0 {
+		return nil, nil
+	}
+	buf := make([]byte, perRow*rows)
+	n, err := r.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	buf = buf[:n]
+	out := make([][]Span, 0, (n+perRow-1)/perRow)
+	for i := 0; i < n; i += perRow {
+		row := buf[i:min(i+perRow, n)]
+		var hex, ascii strings.Builder
+		for j := 0; j < perRow; j++ {
+			if j > 0 && j%8 == 0 {
+				hex.WriteByte(' ')
+			}
+			if j < len(row) {
+				fmt.Fprintf(&hex, "%02x ", row[j])
+			} else {
+				hex.WriteString("   ")
+			}
+		}
+		for _, c := range row {
+			if c >= 0x20 && c < 0x7f {
+				ascii.WriteByte(c)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		out = append(out, []Span{
+			{Text: fmt.Sprintf("%08x  ", off+int64(i)), Class: Comment},
+			{Text: hex.String() + " ", Class: Plain},
+			{Text: "|" + ascii.String() + "|", Class: String},
+		})
+	}
+	return out, nil
+}
+
+// Info describes path: size, mode, modification time, the content type
+// sniffed from its first bytes and, for images, their metadata.
+func Info(path string) (Result, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	lines := []string{
+		fmt.Sprintf("%s (%d bytes)", fi.Name(), fi.Size()),
+		"mode: " + fi.Mode().String(),
+		"modified: " + fi.ModTime().Format(time.RFC3339),
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(path); err == nil {
+			lines = append(lines, "link: "+target)
+		}
+	}
+	mime := "application/octet-stream"
+	if fi.Mode().IsRegular() {
+		if f, err := os.Open(path); err == nil {
+			head := make([]byte, 512)
+			n, _ := io.ReadFull(f, head)
+			f.Close()
+			mime = http.DetectContentType(head[:n])
+			lines = append(lines, "type: "+mime)
+		}
+		if meta, err := ReadImageMeta(path); err == nil {
+			lines = append(lines, meta.Lines()...)
+		}
+	}
+	return Result{Kind: "info", Content: strings.Join(lines, "\n"), Mime: mime}, nil
+}
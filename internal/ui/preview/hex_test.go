@@ -0,0 +1,81 @@
+package preview
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func joinSpans(line []Span) string {
+	var b strings.Builder
+	for _, sp := range line {
+		b.WriteString(sp.Text)
+	}
+	return b.String()
+}
+
+func TestHexDump(t *testing.T) {
+	data := []byte("Hello, hex dump!\x00\x01\xff")
+	rows, err := HexDump(strings.NewReader(string(data)), 0, 16, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"00000000  48 65 6c 6c 6f 2c 20 68  65 78 20 64 75 6d 70 21  |Hello, hex dump!|",
+		"00000010  00 01 ff                                          |...|",
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %d; want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if got := joinSpans(rows[i]); got != w {
+			t.Fatalf("row %d:\n got %q\nwant %q", i, got, w)
+		}
+	}
+	if rows[0][0].Class != Comment || rows[0][2].Class != String {
+		t.Fatalf("unexpected classes: %+v", rows[0])
+	}
+
+	// Reading from an offset only returns the bytes from there.
+	rows, _ = HexDump(strings.NewReader(string(data)), 16, 8, 1)
+	if got := joinSpans(rows[0]); !strings.HasPrefix(got, "00000010  00 01 ff") {
+		t.Fatalf("offset row = %q", got)
+	}
+	if rows, _ := HexDump(strings.NewReader(string(data)), 100, 16, 4); len(rows) != 0 {
+		t.Fatalf("rows past the end: %d", len(rows))
+	}
+}
+
+func TestHexBytesPerRow(t *testing.T) {
+	if n := HexBytesPerRow(200, 16); n != 16 {
+		t.Fatalf("wide pane: %d; want 16", n)
+	}
+	if n := HexBytesPerRow(200, 0); n != 16 {
+		t.Fatalf("default: %d; want 16", n)
+	}
+	n := HexBytesPerRow(40, 16)
+	if n >= 16 || hexRowWidth(n) > 40 {
+		t.Fatalf("narrow pane: %d bytes (%d cells)", n, hexRowWidth(n))
+	}
+	if hexRowWidth(16) != 78 {
+		t.Fatalf("hexdump -C row width = %d; want 78", hexRowWidth(16))
+	}
+	if HexRows(33, 16) != 3 || HexRows(0, 16) != 0 {
+		t.Fatalf("HexRows mismatch")
+	}
+}
+
+func TestInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blob.bin")
+	if err := os.WriteFile(path, []byte("ab\x00\x01cd\nef"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Info(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Kind != "info" || !strings.HasPrefix(res.Content, "blob.bin (9 bytes)") || !strings.Contains(res.Content, "type: ") {
+		t.Fatalf("info = %+v", res)
+	}
+}
@@ -0,0 +1,593 @@
+package preview
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Class is the syntactic category of a span of highlighted text.
+type Class uint8
+
+const (
+	Plain   Class = iota
+	Keyword       // language keywords, Markdown list markers
+	Type          // built-in types, constants and functions
+	String        // string and character literals, Markdown code
+	Number        // numeric literals
+	Comment       // comments, Markdown block quotes
+	Meta          // preprocessor lines, decorators, attributes, shell variables
+	Key           // keys of JSON, YAML and TOML mappings
+	Heading       // Markdown headings and emphasis, TOML tables
+	NumClasses
+)
+
+var classNames = [NumClasses]string{"plain", "keyword", "type", "string", "number", "comment", "meta", "key", "heading"}
+
+func (c Class) String() string {
+	if c < NumClasses {
+		return classNames[c]
+	}
+	return "plain"
+}
+
+// ClassByName returns the class called name (as in [theme.syntax]).
+func ClassByName(name string) (Class, bool) {
+	for i, n := range classNames {
+		if n == name {
+			return Class(i), true
+		}
+	}
+	return Plain, false
+}
+
+// Span is a run of text of one class.
+type Span struct {
+	Text  string
+	Class Class
+}
+
+// Lexer highlights a file line by line. Constructs spanning several lines
+// (block comments, long strings, fenced code) carry over to the next call
+// of Line, so the lines must be passed in order.
+type Lexer struct {
+	lang *language
+	// open block comment or multi-line string
+	close  string
+	class  Class
+	escape bool
+	// Markdown code fence and the lexer for its contents
+	fence string
+	inner *Lexer
+}
+
+// NewLexer returns a lexer for lang (see DetectLanguage), or nil if the
+// language is not supported.
+func NewLexer(lang string) *Lexer {
+	l, ok := languages[lang]
+	if !ok {
+		return nil
+	}
+	return &Lexer{lang: l}
+}
+
+// Line highlights the next line of the file. The texts of the returned
+// spans concatenate to s.
+func (l *Lexer) Line(s string) []Span {
+	var out spans
+	switch l.lang.mode {
+	case modeMarkdown:
+		l.markdownLine(s, &out)
+	case modeYAML:
+		l.lex(s, l.yamlPrefix(s, &out), &out)
+	case modeTOML:
+		l.lex(s, l.tomlPrefix(s, &out), &out)
+	default:
+		if l.close == "" && l.lang.hashMeta && strings.HasPrefix(strings.TrimLeft(s, " \t"), "#") {
+			out.add(s, Meta)
+			break
+		}
+		l.lex(s, 0, &out)
+	}
+	return out
+}
+
+// Highlight splits text into lines and highlights them as lang. It returns
+// nil for unsupported languages.
+func Highlight(lang, text string) [][]Span {
+	l := NewLexer(lang)
+	if l == nil {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	out := make([][]Span, len(lines))
+	for i, ln := range lines {
+		out[i] = l.Line(ln)
+	}
+	return out
+}
+
+type spans []Span
+
+// add appends text, merging it into the last span of the same class.
+func (sp *spans) add(text string, c Class) {
+	if text == "" {
+		return
+	}
+	if n := len(*sp); n > 0 && (*sp)[n-1].Class == c {
+		(*sp)[n-1].Text += text
+		return
+	}
+	*sp = append(*sp, Span{Text: text, Class: c})
+}
+
+// lex highlights s[i:] as code.
+func (l *Lexer) lex(s string, i int, out *spans) {
+	lang := l.lang
+	if l.close != "" {
+		end := findClose(s, i, l.close, l.escape)
+		if end < 0 {
+			out.add(s[i:], l.class)
+			return
+		}
+		out.add(s[i:end], l.class)
+		l.close = ""
+		i = end
+	}
+	for i < len(s) {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == ' ' || c == '\t':
+			j := i
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+				j++
+			}
+			out.add(s[i:j], Plain)
+			i = j
+			continue
+		case lang.isLineComment(s, i):
+			out.add(rest, Comment)
+			return
+		case lang.block[0] != "" && strings.HasPrefix(rest, lang.block[0]):
+			end := findClose(s, i+len(lang.block[0]), lang.block[1], false)
+			if end < 0 {
+				out.add(rest, Comment)
+				l.close, l.class, l.escape = lang.block[1], Comment, false
+				return
+			}
+			out.add(s[i:end], Comment)
+			i = end
+			continue
+		case lang.rust && c == '#' && (strings.HasPrefix(rest, "#[") || strings.HasPrefix(rest, "#![")):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			out.add(rest[:end+1], Meta)
+			i += end + 1
+			continue
+		case lang.rust && c == 'r' || lang.rust && c == 'b' && strings.HasPrefix(rest, "br"):
+			if n := l.rawString(s, i, out); n > 0 {
+				i = n
+				continue
+			}
+		case lang.rust && c == '\'':
+			if !isRustChar(rest) {
+				j := i + 1
+				for j < len(s) && isIdent(s[j]) {
+					j++
+				}
+				out.add(s[i:j], Meta)
+				i = j
+				continue
+			}
+		case lang.shellVars && c == '$':
+			j := shellVarEnd(s, i)
+			out.add(s[i:j], Meta)
+			i = j
+			continue
+		case lang.decorators && c == '@' && i+1 < len(s) && isIdentStart(s[i+1]):
+			j := i + 1
+			for j < len(s) && (isIdent(s[j]) || s[j] == '.') {
+				j++
+			}
+			out.add(s[i:j], Meta)
+			i = j
+			continue
+		case lang.yaml && (c == '&' || c == '*') && i+1 < len(s) && isIdent(s[i+1]):
+			j := i + 1
+			for j < len(s) && (isIdent(s[j]) || s[j] == '-') {
+				j++
+			}
+			out.add(s[i:j], Meta)
+			i = j
+			continue
+		case lang.yaml && c == '!' && (i == 0 || s[i-1] == ' '):
+			j := i + 1
+			for j < len(s) && s[j] != ' ' {
+				j++
+			}
+			out.add(s[i:j], Meta)
+			i = j
+			continue
+		}
+		if d, ok := lang.stringAt(rest); ok {
+			end := findClose(s, i+len(d.open), d.close, d.escape)
+			if end < 0 {
+				out.add(rest, String)
+				if d.multiline {
+					l.close, l.class, l.escape = d.close, String, d.escape
+				}
+				return
+			}
+			class := String
+			if lang.keyStrings && followedByColon(s, end) {
+				class = Key
+			}
+			out.add(s[i:end], class)
+			i = end
+			continue
+		}
+		if isDigit(c) || c == '.' && i+1 < len(s) && isDigit(s[i+1]) {
+			if i == 0 || !isIdent(s[i-1]) {
+				j := numberEnd(s, i)
+				out.add(s[i:j], Number)
+				i = j
+				continue
+			}
+		}
+		if isIdentStart(c) {
+			j := i + 1
+			for j < len(s) && (isIdent(s[j]) || lang.dashIdents && s[j] == '-') {
+				j++
+			}
+			word := s[i:j]
+			switch {
+			case lang.strPrefixes != "" && j < len(s) && (s[j] == '"' || s[j] == '\'') &&
+				len(word) <= 2 && strings.Trim(strings.ToLower(word), lang.strPrefixes) == "":
+				out.add(word, String)
+			case lang.keywords[word]:
+				out.add(word, Keyword)
+			case lang.types[word]:
+				out.add(word, Type)
+			case lang.rust && j < len(s) && s[j] == '!' && (j+1 == len(s) || s[j+1] != '='):
+				out.add(s[i:j+1], Meta)
+				j++
+			default:
+				out.add(word, Plain)
+			}
+			i = j
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		out.add(s[i:i+size], Plain)
+		i += size
+	}
+}
+
+// isLineComment reports whether a line comment starts at s[i]. A '#' only
+// starts a comment at the beginning of a word in shell and YAML.
+func (lang *language) isLineComment(s string, i int) bool {
+	for _, p := range lang.lineComments {
+		if !strings.HasPrefix(s[i:], p) {
+			continue
+		}
+		if p == "#" && lang.hashWordStart && i > 0 && s[i-1] != ' ' && s[i-1] != '\t' {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// stringAt returns the string delimiter that opens at the start of s.
+func (lang *language) stringAt(s string) (delim, bool) {
+	for _, d := range lang.strs {
+		if strings.HasPrefix(s, d.open) {
+			return d, true
+		}
+	}
+	return delim{}, false
+}
+
+// rawString highlights a Rust raw string (r"…", r#"…"#, br"…") at s[i] and
+// returns the index after it, or 0 if there is none.
+func (l *Lexer) rawString(s string, i int, out *spans) int {
+	j := i + 1
+	if s[i] == 'b' {
+		j++
+	}
+	hashes := 0
+	for j < len(s) && s[j] == '#' {
+		hashes++
+		j++
+	}
+	if j >= len(s) || s[j] != '"' || i > 0 && isIdent(s[i-1]) {
+		return 0
+	}
+	close := `"` + strings.Repeat("#", hashes)
+	end := findClose(s, j+1, close, false)
+	if end < 0 {
+		out.add(s[i:], String)
+		l.close, l.class, l.escape = close, String, false
+		return len(s)
+	}
+	out.add(s[i:end], String)
+	return end
+}
+
+// isRustChar tells a character literal from a lifetime at the start of s.
+func isRustChar(s string) bool {
+	if len(s) > 1 && s[1] == '\\' {
+		return true
+	}
+	_, size := utf8.DecodeRuneInString(s[1:])
+	return len(s) > 1+size && s[1+size] == '\''
+}
+
+// shellVarEnd returns the end of the shell variable reference at s[i].
+func shellVarEnd(s string, i int) int {
+	j := i + 1
+	if j >= len(s) {
+		return j
+	}
+	switch {
+	case s[j] == '{':
+		if end := strings.IndexByte(s[j:], '}'); end >= 0 {
+			return j + end + 1
+		}
+		return len(s)
+	case s[j] == '(':
+		return j
+	case isIdentStart(s[j]):
+		for j < len(s) && isIdent(s[j]) {
+			j++
+		}
+		return j
+	case isDigit(s[j]) || strings.IndexByte("@*#?$!-", s[j]) >= 0:
+		return j + 1
+	}
+	return j
+}
+
+// findClose returns the index just past the first close in s[from:], or -1.
+// With escape, a backslash hides the next character.
+func findClose(s string, from int, close string, escape bool) int {
+	for i := from; i < len(s); i++ {
+		if escape && s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], close) {
+			return i + len(close)
+		}
+	}
+	return -1
+}
+
+// followedByColon reports whether the next non-blank character at or after
+// s[i] is a colon.
+func followedByColon(s string, i int) bool {
+	for ; i < len(s); i++ {
+		if s[i] != ' ' && s[i] != '\t' {
+			return s[i] == ':'
+		}
+	}
+	return false
+}
+
+// numberEnd returns the end of the numeric literal at s[i]. Range operators
+// ("1..10") end the number.
+func numberEnd(s string, i int) int {
+	j := i
+	for j < len(s) {
+		c := s[j]
+		switch {
+		case isIdent(c):
+		case c == '.' && !(j+1 < len(s) && s[j+1] == '.'):
+		case (c == '+' || c == '-') && j > i && (s[j-1] == 'e' || s[j-1] == 'E') && !strings.HasPrefix(s[i:], "0x"):
+		default:
+			return j
+		}
+		j++
+	}
+	return j
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isIdent(c byte) bool      { return isIdentStart(c) || isDigit(c) }
+
+// yamlPrefix highlights the document markers, sequence dashes and mapping
+// key at the start of a YAML line and returns where the value starts.
+func (l *Lexer) yamlPrefix(s string, out *spans) int {
+	if s == "---" || s == "..." || strings.HasPrefix(s, "--- ") {
+		out.add(s[:3], Meta)
+		return 3
+	}
+	i := 0
+	for {
+		j := i
+		for j < len(s) && s[j] == ' ' {
+			j++
+		}
+		out.add(s[i:j], Plain)
+		i = j
+		if i < len(s) && s[i] == '-' && (i+1 == len(s) || s[i+1] == ' ') {
+			out.add("-", Keyword)
+			i++
+			continue
+		}
+		break
+	}
+	if i >= len(s) || s[i] == '#' {
+		return i
+	}
+	end := -1
+	if s[i] == '"' || s[i] == '\'' {
+		if e := findClose(s, i+1, s[i:i+1], s[i] == '"'); e > 0 && e < len(s) && s[e] == ':' {
+			end = e
+		}
+	} else {
+		for j := i; j < len(s); j++ {
+			if s[j] == '#' && j > 0 && s[j-1] == ' ' {
+				break
+			}
+			if s[j] == ':' && (j+1 == len(s) || s[j+1] == ' ') {
+				end = j
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return i
+	}
+	out.add(s[i:end], Key)
+	out.add(":", Plain)
+	return end + 1
+}
+
+// tomlPrefix highlights a [table] header or the key of a key = value line
+// and returns where the rest of the line starts.
+func (l *Lexer) tomlPrefix(s string, out *spans) int {
+	if l.close != "" {
+		return 0
+	}
+	t := strings.TrimLeft(s, " \t")
+	i := len(s) - len(t)
+	out.add(s[:i], Plain)
+	if strings.HasPrefix(t, "[") {
+		end := strings.LastIndexByte(t, ']')
+		if end < 0 {
+			end = len(t) - 1
+		}
+		out.add(t[:end+1], Heading)
+		return i + end + 1
+	}
+	eq := strings.IndexByte(t, '=')
+	if eq <= 0 || strings.ContainsAny(t[:eq], "#{[") {
+		return i
+	}
+	key := strings.TrimRight(t[:eq], " \t")
+	out.add(key, Key)
+	return i + len(key)
+}
+
+// markdownLine highlights one line of Markdown. Fenced code is highlighted
+// as the language named after the fence, when known.
+func (l *Lexer) markdownLine(s string, out *spans) {
+	t := strings.TrimLeft(s, " ")
+	indent := len(s) - len(t)
+	if l.fence != "" {
+		if indent < 4 && strings.HasPrefix(t, l.fence) && strings.Trim(t, l.fence[:1]+" ") == "" {
+			out.add(s, Meta)
+			l.fence, l.inner = "", nil
+			return
+		}
+		if l.inner != nil {
+			*out = append(*out, l.inner.Line(s)...)
+		} else {
+			out.add(s, String)
+		}
+		return
+	}
+	if indent >= 4 {
+		out.add(s, String)
+		return
+	}
+	switch {
+	case strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~"):
+		n := 0
+		for n < len(t) && t[n] == t[0] {
+			n++
+		}
+		l.fence = t[:n]
+		info := strings.Fields(strings.Trim(t[n:], " {}."))
+		if len(info) > 0 {
+			l.inner = NewLexer(languageAlias(info[0]))
+		}
+		out.add(s, Meta)
+		return
+	case strings.HasPrefix(t, "#"):
+		n := 0
+		for n < len(t) && t[n] == '#' {
+			n++
+		}
+		if n <= 6 && (n == len(t) || t[n] == ' ') {
+			out.add(s, Heading)
+			return
+		}
+	case strings.HasPrefix(t, ">"):
+		out.add(s, Comment)
+		return
+	case len(t) >= 3 && strings.Trim(t, "=") == "":
+		out.add(s, Heading)
+		return
+	case len(t) >= 3 && (strings.Trim(t, "- ") == "" || strings.Trim(t, "* ") == "" || strings.Trim(t, "_ ") == ""):
+		out.add(s, Meta)
+		return
+	}
+	out.add(s[:indent], Plain)
+	if n := listMarker(t); n > 0 {
+		out.add(t[:n], Keyword)
+		t = t[n:]
+	}
+	markdownInline(t, out)
+}
+
+// listMarker returns the length of the list marker ("- ", "* ", "1. ")
+// at the start of s, or 0.
+func listMarker(s string) int {
+	if len(s) >= 2 && strings.IndexByte("-*+", s[0]) >= 0 && s[1] == ' ' {
+		return 2
+	}
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i > 0 && i+1 < len(s) && (s[i] == '.' || s[i] == ')') && s[i+1] == ' ' {
+		return i + 2
+	}
+	return 0
+}
+
+// markdownInline highlights code spans, strong emphasis and link targets.
+func markdownInline(s string, out *spans) {
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '`':
+			n := 0
+			for n < len(rest) && rest[n] == '`' {
+				n++
+			}
+			if end := strings.Index(rest[n:], rest[:n]); end >= 0 {
+				out.add(rest[:n+end+n], String)
+				i += n + end + n
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				out.add(rest[:end+4], Heading)
+				i += end + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "]("):
+			if end := strings.IndexByte(rest, ')'); end > 0 {
+				out.add("]", Plain)
+				out.add(rest[1:end+1], Type)
+				i += end + 1
+				continue
+			}
+		case rest[0] == '<' && (strings.HasPrefix(rest, "<http://") || strings.HasPrefix(rest, "<https://")):
+			if end := strings.IndexByte(rest, '>'); end > 0 {
+				out.add(rest[:end+1], Type)
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		out.add(s[i:i+size], Plain)
+		i += size
+	}
+}
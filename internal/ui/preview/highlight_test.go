@@ -0,0 +1,199 @@
+package preview
+
+import (
+	"strings"
+	"testing"
+)
+
+// classAt returns the class of the first byte of the first occurrence of
+// sub on line n of the highlighted text.
+func classAt(t *testing.T, lines [][]Span, n int, sub string) Class {
+	t.Helper()
+	var b strings.Builder
+	for _, sp := range lines[n] {
+		b.WriteString(sp.Text)
+	}
+	off := strings.Index(b.String(), sub)
+	if off < 0 {
+		t.Fatalf("line %d %q has no %q", n, b.String(), sub)
+	}
+	for _, sp := range lines[n] {
+		if off < len(sp.Text) {
+			return sp.Class
+		}
+		off -= len(sp.Text)
+	}
+	return Plain
+}
+
+type tokenCase struct {
+	line  int
+	sub   string
+	class Class
+}
+
+func checkTokens(t *testing.T, lang, src string, cases []tokenCase) {
+	t.Helper()
+	lines := Highlight(lang, src)
+	if lines == nil {
+		t.Fatalf("%s is not supported", lang)
+	}
+	// Spans must reproduce the text exactly.
+	for i, ln := range strings.Split(src, "\n") {
+		var b strings.Builder
+		for _, sp := range lines[i] {
+			b.WriteString(sp.Text)
+		}
+		if b.String() != ln {
+			t.Fatalf("%s line %d: spans give %q; want %q", lang, i, b.String(), ln)
+		}
+	}
+	for _, c := range cases {
+		if got := classAt(t, lines, c.line, c.sub); got != c.class {
+			t.Errorf("%s line %d %q: class %s; want %s", lang, c.line, c.sub, got, c.class)
+		}
+	}
+}
+
+func TestHighlightGo(t *testing.T) {
+	src := "package main\n\n/* block\n   comment */ var x = `raw\nstring` // note\nfunc f(n int) string { return fmt.Sprintf(\"%d\\\"\", n+0x1F) }"
+	checkTokens(t, "go", src, []tokenCase{
+		{0, "package", Keyword},
+		{0, "main", Plain},
+		{2, "block", Comment},
+		{3, "comment", Comment},
+		{3, "var", Keyword},
+		{3, "`raw", String},
+		{4, "string`", String},
+		{4, "// note", Comment},
+		{5, "int", Type},
+		{5, "Sprintf", Plain},
+		{5, `"%d`, String},
+		{5, ", n", Plain},
+		{5, "0x1F", Number},
+	})
+}
+
+func TestHighlightPython(t *testing.T) {
+	src := "@dataclass\ndef f(x=1.5e-3):\n    \"\"\"Doc\n    string\"\"\"\n    return rb'\\x00' if x else None  # done"
+	checkTokens(t, "python", src, []tokenCase{
+		{0, "@dataclass", Meta},
+		{1, "def", Keyword},
+		{1, "1.5e-3", Number},
+		{2, `"""Doc`, String},
+		{3, "string", String},
+		{4, "return", Keyword},
+		{4, "rb'", String},
+		{4, "if", Keyword},
+		{4, "None", Type},
+		{4, "# done", Comment},
+	})
+}
+
+func TestHighlightShell(t *testing.T) {
+	src := "#!/bin/sh\nfor f in *.txt; do echo \"$f\" ${HOME}/x#y; done # end"
+	checkTokens(t, "shell", src, []tokenCase{
+		{0, "#!/bin/sh", Comment},
+		{1, "for", Keyword},
+		{1, "echo", Type},
+		{1, `"$f"`, String},
+		{1, "${HOME}", Meta},
+		{1, "x#y", Plain},
+		{1, "# end", Comment},
+	})
+}
+
+func TestHighlightJSONYAMLTOML(t *testing.T) {
+	checkTokens(t, "json", `{"name": "tfm", "n": -1, "ok": true}`, []tokenCase{
+		{0, `"name"`, Key},
+		{0, `"tfm"`, String},
+		{0, "1", Number},
+		{0, "true", Type},
+	})
+	checkTokens(t, "yaml", "---\nserver:\n  - name: web # front\n    port: 8080\n    tls: yes\n    ref: *base", []tokenCase{
+		{0, "---", Meta},
+		{1, "server", Key},
+		{2, "-", Keyword},
+		{2, "name", Key},
+		{2, "web", Plain},
+		{2, "# front", Comment},
+		{3, "8080", Number},
+		{4, "yes", Type},
+		{5, "*base", Meta},
+	})
+	checkTokens(t, "toml", "[server.tls] # tables\nport = 8080\nname = \"\"\"multi\nline\"\"\"\nenabled = true", []tokenCase{
+		{0, "[server.tls]", Heading},
+		{0, "# tables", Comment},
+		{1, "port", Key},
+		{1, "8080", Number},
+		{2, "name", Key},
+		{3, "line", String},
+		{4, "true", Type},
+	})
+}
+
+func TestHighlightMarkdown(t *testing.T) {
+	src := "# Title\n\n- item with `code` and **bold**\n> quote\n```go\nfunc main() {}\n```\nsee [docs](https://example.com)"
+	checkTokens(t, "markdown", src, []tokenCase{
+		{0, "# Title", Heading},
+		{2, "- ", Keyword},
+		{2, "item", Plain},
+		{2, "`code`", String},
+		{2, "**bold**", Heading},
+		{3, "> quote", Comment},
+		{4, "```go", Meta},
+		{5, "func", Keyword},
+		{6, "```", Meta},
+		{7, "(https://example.com)", Type},
+	})
+}
+
+func TestHighlightCAndRust(t *testing.T) {
+	checkTokens(t, "c", "#include <stdio.h>\nstatic const char *s = \"hi\"; /* c */ int n = 'x';", []tokenCase{
+		{0, "#include", Meta},
+		{1, "static", Keyword},
+		{1, "char", Type},
+		{1, `"hi"`, String},
+		{1, "/* c */", Comment},
+		{1, "'x'", String},
+	})
+	checkTokens(t, "rust", "#[derive(Debug)]\nfn f<'a>(s: &'a str) -> Option<u8> { println!(r#\"raw \"q\"\"#); for i in 0..10 { let c = 'c'; } None }", []tokenCase{
+		{0, "#[derive(Debug)]", Meta},
+		{1, "fn", Keyword},
+		{1, "'a>", Meta},
+		{1, "str", Type},
+		{1, "Option", Type},
+		{1, "println!", Meta},
+		{1, `r#"raw`, String},
+		{1, "0", Number},
+		{1, "..", Plain},
+		{1, "10", Number},
+		{1, "'c'", String},
+		{1, "None", Type},
+	})
+}
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		path, head, want string
+	}{
+		{"main.go", "package main", "go"},
+		{"setup.PY", "", "python"},
+		{"run", "#!/usr/bin/env -S python3 -u\nprint(1)", "python"},
+		{"build", "#!/bin/bash\nset -e", "shell"},
+		{"notes.txt", "hello\n# vim: set ft=markdown :", "markdown"},
+		{"x.conf", "# -*- mode: yaml -*-\na: 1", "yaml"},
+		{"script.py", "# vim: ft=sh\necho", "shell"},
+		{"Cargo.lock", "", "toml"},
+		{"README", "plain text", ""},
+		{"data.bin", "#!/usr/bin/perl", ""},
+	}
+	for _, c := range cases {
+		if got := DetectLanguage(c.path, []byte(c.head)); got != c.want {
+			t.Errorf("DetectLanguage(%q, %q) = %q; want %q", c.path, c.head, got, c.want)
+		}
+	}
+	if NewLexer("") != nil || NewLexer("cobol") != nil {
+		t.Fatalf("unknown languages should have no lexer")
+	}
+}
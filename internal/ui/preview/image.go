@@ -0,0 +1,218 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+// Inline image protocols.
+const (
+	ProtocolNone   = "none"
+	ProtocolIterm2 = "iterm2" // OSC 1337 (iTerm2, WezTerm)
+	ProtocolKitty  = "kitty"  // kitty graphics protocol (kitty, Ghostty)
+	ProtocolSixel  = "sixel"  // DEC sixel graphics (foot, mlterm, xterm -ti vt340)
+)
+
+// DetectProtocol returns the inline image protocol to use. setting is the
+// configured image_protocol; "auto" (or "") guesses from the environment.
+// TFM_NO_INLINE_IMAGES and TFM_INLINE override the configuration.
+func DetectProtocol(setting string) string {
+	if os.Getenv("TFM_NO_INLINE_IMAGES") != "" {
+		return ProtocolNone
+	}
+	switch v := strings.ToLower(os.Getenv("TFM_INLINE")); v {
+	case "off", "none", "0", "false":
+		return ProtocolNone
+	case "iterm2", "wezterm":
+		return ProtocolIterm2
+	case ProtocolKitty, ProtocolSixel:
+		return v
+	}
+	switch setting = strings.ToLower(setting); setting {
+	case ProtocolNone, ProtocolIterm2, ProtocolKitty, ProtocolSixel:
+		return setting
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" ||
+		os.Getenv("TERM") == "xterm-ghostty" || strings.EqualFold(os.Getenv("TERM_PROGRAM"), "ghostty") {
+		return ProtocolKitty
+	}
+	if SupportsIterm2() {
+		return ProtocolIterm2
+	}
+	if sixelTerm(os.Getenv("TERM")) || os.Getenv("WT_SESSION") != "" {
+		return ProtocolSixel
+	}
+	return ProtocolNone
+}
+
+// sixelTerm reports whether TERM names a terminal known to draw sixels.
+// Others, like xterm started with sixel support, are set up with
+// image_protocol = "sixel" or TFM_INLINE=sixel.
+func sixelTerm(term string) bool {
+	for _, t := range []string{"foot", "mlterm", "yaft", "contour"} {
+		if term == t || strings.HasPrefix(term, t+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxImagePixels bounds the images decoded for previews. Decoding needs 4
+// bytes or more per pixel, so larger images are only described.
+const MaxImagePixels = 50 << 20
+
+// ErrImageTooLarge is returned for images of more than MaxImagePixels.
+var ErrImageTooLarge = errors.New("image too large to preview")
+
+// decodeImage decodes the image file at path with the registered decoders,
+// upright according to its EXIF orientation. The size is checked before
+// decoding: images of more than MaxImagePixels are refused.
+func decodeImage(path string) (image.Image, ImageMeta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ImageMeta{}, err
+	}
+	defer f.Close()
+	meta, err := readImageMeta(f)
+	if err != nil {
+		return nil, meta, err
+	}
+	img, err := decodeImageFile(f, meta)
+	return img, meta, err
+}
+
+// decodeImageFile decodes the open image file f described by meta (see
+// readImageMeta), so that callers that already read the header do not read
+// it again from another descriptor. The standard decoders cannot decode at
+// a reduced size, so the full image is decoded and only scaled afterwards
+// (see scaleImage); MaxImagePixels bounds the memory this takes.
+func decodeImageFile(f *os.File, meta ImageMeta) (image.Image, error) {
+	if meta.Pixels() > MaxImagePixels {
+		return nil, fmt.Errorf("%d×%d: %w", meta.Width, meta.Height, ErrImageTooLarge)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	if meta.Orientation > 1 {
+		img = orientedImage{img, meta.Orientation}
+	}
+	return img, nil
+}
+
+// orientedImage shows an image in one of the EXIF orientations without
+// copying it: only the pixels read when scaling it down are mapped.
+type orientedImage struct {
+	image.Image
+	o int // EXIF orientation, 2..8
+}
+
+func (im orientedImage) Bounds() image.Rectangle {
+	b := im.Image.Bounds()
+	if im.o >= 5 {
+		return image.Rect(0, 0, b.Dy(), b.Dx())
+	}
+	return image.Rect(0, 0, b.Dx(), b.Dy())
+}
+
+func (im orientedImage) At(x, y int) color.Color {
+	b := im.Image.Bounds()
+	w, h := b.Dx(), b.Dy()
+	switch im.o {
+	case 2: // mirrored
+		x = w - 1 - x
+	case 3: // rotated 180°
+		x, y = w-1-x, h-1-y
+	case 4: // flipped vertically
+		y = h - 1 - y
+	case 5: // transposed
+		x, y = y, x
+	case 6: // rotated 90° clockwise
+		x, y = y, h-1-x
+	case 7: // transversed
+		x, y = w-1-y, h-1-x
+	case 8: // rotated 90° counter-clockwise
+		x, y = w-1-y, x
+	}
+	return im.Image.At(b.Min.X+x, b.Min.Y+y)
+}
+
+// scaleSamples is the most source pixels per row and column that are
+// averaged into one target pixel, so that shrinking huge images stays fast.
+const scaleSamples = 4
+
+// scaleImage resizes img to w×h pixels, averaging source pixels that fall
+// into each target pixel when shrinking (at most scaleSamples² of them).
+func scaleImage(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if sw <= 0 || sh <= 0 || w <= 0 || h <= 0 {
+		return dst
+	}
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*sh/h
+		y1 := b.Min.Y + (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*sw/w
+			x1 := b.Min.X + (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy += max((y1-y0)/scaleSamples, 1) {
+				for sx := x0; sx < x1; sx += max((x1-x0)/scaleSamples, 1) {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+cr, g+cg, bl+cb, a+ca
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
+
+// fitImage scales img to the pixels of the cols×rows cells it covers (see
+// FitCells) and returns it with that cell size.
+func fitImage(img image.Image, cols, rows int, cell CellSize) (*image.RGBA, int, int) {
+	b := img.Bounds()
+	c, r := FitCells(b.Dx(), b.Dy(), cols, rows, cell)
+	if c == 0 || r == 0 {
+		return nil, 0, 0
+	}
+	if cell.W <= 0 || cell.H <= 0 {
+		cell = DefaultCellSize
+	}
+	// The largest size with the image's aspect ratio inside the cells.
+	w, h := c*cell.W, b.Dy()*c*cell.W/b.Dx()
+	if h > r*cell.H {
+		w, h = b.Dx()*r*cell.H/b.Dy(), r*cell.H
+	}
+	w, h = min(max(w, 1), b.Dx()), min(max(h, 1), b.Dy())
+	return scaleImage(img, w, h), c, r
+}
+
+// encodePNG encodes img as PNG with fast compression.
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package preview
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi/kitty"
+)
+
+// InTmux reports whether tfm runs inside tmux, where images have to pass
+// through tmux and be drawn with unicode placeholders.
+func InTmux() bool { return os.Getenv("TMUX") != "" }
+
+// kittyChunk is the largest base64 payload of one graphics command.
+const kittyChunk = 4096
+
+// KittyID returns an image id for this process. Placeholder cells carry the
+// id in a 256-color foreground, so ids stay within 1..255.
+func KittyID() uint32 { return uint32(os.Getpid()%255) + 1 }
+
+// KittyOptions describe how BuildKitty shows an image.
+type KittyOptions struct {
+	ID          uint32   // image id, replaced by every new image
+	Cols, Rows  int      // cells available
+	Cell        CellSize // pixel size of a cell
+	Placeholder bool     // draw with unicode placeholders (required in tmux)
+	Tmux        bool     // wrap commands in tmux passthrough
+}
+
+// BuildKitty renders the image at path with the kitty graphics protocol,
+// scaled to fit in opts.Cols×opts.Rows cells. It returns one line per
+// covered row, each padded to opts.Cols cells, and false if the image
+// cannot be decoded.
+func BuildKitty(path string, opts KittyOptions) ([]string, bool) {
+	if opts.Cols <= 0 || opts.Rows <= 0 {
+		return nil, false
+	}
+	img, _, err := decodeImage(path)
+	if err != nil {
+		return nil, false
+	}
+	scaled, cols, rows := fitImage(img, opts.Cols, opts.Rows, opts.Cell)
+	if scaled == nil {
+		return nil, false
+	}
+	data, err := encodePNG(scaled)
+	if err != nil {
+		return nil, false
+	}
+	return KittyLines(data, cols, rows, opts), true
+}
+
+// KittyLines lays out a PNG image of cols×rows cells: the transmission
+// goes in front of the first line and, with placeholders, every line holds
+// one row of placeholder cells.
+func KittyLines(png []byte, cols, rows int, opts KittyOptions) []string {
+	seq := KittyTransmit(opts.ID, png, cols, rows, opts.Placeholder)
+	if opts.Tmux {
+		seq = tmuxPassthrough(seq)
+	}
+	lines := make([]string, rows)
+	if opts.Placeholder {
+		pad := strings.Repeat(" ", max(opts.Cols-cols, 0))
+		for i, row := range KittyPlaceholders(opts.ID, cols, rows) {
+			lines[i] = row + pad
+		}
+	} else {
+		pad := strings.Repeat(" ", opts.Cols)
+		for i := range lines {
+			lines[i] = pad
+		}
+	}
+	lines[0] = seq + lines[0]
+	return lines
+}
+
+// KittyTransmit returns the commands transmitting a PNG image with id and
+// placing it over cols×rows cells at the cursor, split into chunks of at
+// most 4096 base64 bytes. The cursor does not move. With placeholder set
+// the placement is virtual, to be shown by KittyPlaceholders cells.
+func KittyTransmit(id uint32, png []byte, cols, rows int, placeholder bool) string {
+	payload := base64.StdEncoding.EncodeToString(png)
+	ctrl := fmt.Sprintf("a=T,f=100,t=d,i=%d,c=%d,r=%d,C=1,q=2", id, cols, rows)
+	if placeholder {
+		ctrl += ",U=1"
+	}
+	var b strings.Builder
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(kittyChunk, len(payload))]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		b.WriteString("\x1b_G")
+		if first {
+			b.WriteString(ctrl + ",")
+		}
+		fmt.Fprintf(&b, "m=%d;%s\x1b\\", more, chunk)
+	}
+	return b.String()
+}
+
+// KittyPlaceholders returns rows lines of cols unicode placeholder cells
+// showing the virtual placement of image id. The image id is the 256-color
+// foreground; the first cell of a row names its row and column with
+// diacritics, the following cells continue the row.
+func KittyPlaceholders(id uint32, cols, rows int) []string {
+	lines := make([]string, rows)
+	for r := range lines {
+		var b strings.Builder
+		fmt.Fprintf(&b, "\x1b[38;5;%dm", id)
+		b.WriteRune(kitty.Placeholder)
+		b.WriteRune(kitty.Diacritic(r))
+		b.WriteRune(kitty.Diacritic(0))
+		for c := 1; c < cols; c++ {
+			b.WriteRune(kitty.Placeholder)
+		}
+		b.WriteString("\x1b[39m")
+		lines[r] = b.String()
+	}
+	return lines
+}
+
+// KittyDelete returns the command deleting image id and its placements.
+func KittyDelete(id uint32, tmux bool) string {
+	seq := fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id)
+	if tmux {
+		seq = tmuxPassthrough(seq)
+	}
+	return seq
+}
+
+// tmuxPassthrough wraps seq so that tmux forwards it to the outer terminal
+// (this needs "set -g allow-passthrough on").
+func tmuxPassthrough(seq string) string {
+	return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+}
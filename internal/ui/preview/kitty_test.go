@@ -0,0 +1,147 @@
+package preview
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, or rewrites it with -update.
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Fatalf("output differs from %s:\n got %q\nwant %q", path, got, want)
+	}
+}
+
+// payload returns n deterministic bytes standing in for PNG data.
+func payload(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+func TestKittyTransmitGolden(t *testing.T) {
+	// 5000 bytes encode to 6668 base64 bytes: two chunks.
+	seq := KittyTransmit(42, payload(5000), 20, 10, false)
+	if n := strings.Count(seq, "\x1b_G"); n != 2 {
+		t.Fatalf("chunks = %d; want 2", n)
+	}
+	golden(t, "kitty_transmit.golden", seq)
+}
+
+func TestKittyPlaceholdersGolden(t *testing.T) {
+	opts := KittyOptions{ID: 7, Cols: 6, Rows: 5, Placeholder: true, Tmux: true}
+	lines := KittyLines(payload(30), 4, 2, opts)
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d; want 2", len(lines))
+	}
+	if w := lipgloss.Width(lines[1]); w != 6 {
+		t.Fatalf("placeholder row width = %d; want 6", w)
+	}
+	golden(t, "kitty_tmux.golden", strings.Join(lines, "\n"))
+	golden(t, "kitty_delete.golden", KittyDelete(7, false)+"\n"+KittyDelete(7, true))
+}
+
+func writePNG(t *testing.T, w, h int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "pic.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildKitty(t *testing.T) {
+	path := writePNG(t, 160, 80)
+	lines, ok := BuildKitty(path, KittyOptions{ID: 3, Cols: 40, Rows: 20, Cell: CellSize{W: 8, H: 16}})
+	if !ok {
+		t.Fatalf("BuildKitty failed")
+	}
+	// 160×80 pixels cover 20×5 cells of 8×16.
+	if len(lines) != 5 {
+		t.Fatalf("rows = %d; want 5", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "\x1b_Ga=T,f=100,t=d,i=3,c=20,r=5,C=1,q=2,") {
+		t.Fatalf("first command = %.60q", lines[0])
+	}
+	for i, ln := range lines {
+		if w := lipgloss.Width(ln); w != 40 {
+			t.Fatalf("line %d width = %d; want 40", i, w)
+		}
+	}
+	if _, ok := BuildKitty(filepath.Join(t.TempDir(), "missing.png"), KittyOptions{Cols: 4, Rows: 4}); ok {
+		t.Fatalf("BuildKitty of a missing file succeeded")
+	}
+}
+
+func TestFitCells(t *testing.T) {
+	cell := CellSize{W: 10, H: 20}
+	cases := []struct{ w, h, cols, rows, c, r int }{
+		{100, 100, 50, 50, 10, 5},   // small images keep their size
+		{1000, 500, 20, 40, 20, 5},  // width limits
+		{500, 1000, 40, 10, 10, 10}, // height limits
+	}
+	for _, tc := range cases {
+		if c, r := FitCells(tc.w, tc.h, tc.cols, tc.rows, cell); c != tc.c || r != tc.r {
+			t.Fatalf("FitCells(%d×%d in %d×%d) = %d×%d; want %d×%d", tc.w, tc.h, tc.cols, tc.rows, c, r, tc.c, tc.r)
+		}
+	}
+}
+
+func TestDetectProtocol(t *testing.T) {
+	for _, k := range []string{"TFM_NO_INLINE_IMAGES", "TFM_INLINE", "KITTY_WINDOW_ID", "TERM", "TERM_PROGRAM", "ITERM_SESSION_ID", "WEZTERM_PANE", "WT_SESSION"} {
+		t.Setenv(k, "")
+	}
+	if p := DetectProtocol("auto"); p != ProtocolNone {
+		t.Fatalf("plain terminal: %q", p)
+	}
+	t.Setenv("TERM", "foot-extra")
+	if p := DetectProtocol("auto"); p != ProtocolSixel {
+		t.Fatalf("foot: %q", p)
+	}
+	t.Setenv("KITTY_WINDOW_ID", "1")
+	if p := DetectProtocol("auto"); p != ProtocolKitty {
+		t.Fatalf("kitty: %q", p)
+	}
+	if p := DetectProtocol("none"); p != ProtocolNone {
+		t.Fatalf("config none: %q", p)
+	}
+	t.Setenv("TFM_INLINE", "iterm2")
+	if p := DetectProtocol("kitty"); p != ProtocolIterm2 {
+		t.Fatalf("TFM_INLINE override: %q", p)
+	}
+}
@@ -0,0 +1,280 @@
+package preview
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	modeCode = iota
+	modeYAML
+	modeTOML
+	modeMarkdown
+)
+
+// language describes how to highlight one language. Most of them share the
+// generic code lexer; the flags enable the few constructs that differ.
+type language struct {
+	mode          int
+	lineComments  []string
+	block         [2]string // block comment delimiters
+	strs          []delim   // longest opening delimiters first
+	keywords      map[string]bool
+	types         map[string]bool
+	keyStrings    bool   // a string followed by ':' is a key (JSON)
+	hashMeta      bool   // lines starting with '#' are preprocessor lines (C)
+	hashWordStart bool   // '#' starts a comment only at the start of a word
+	decorators    bool   // @name (Python)
+	shellVars     bool   // $name, ${…}
+	dashIdents    bool   // identifiers may contain '-'
+	rust          bool   // attributes, lifetimes, raw strings, macros
+	yaml          bool   // anchors, aliases and tags
+	strPrefixes   string // letters that may prefix a string literal
+}
+
+// delim is a string literal delimiter.
+type delim struct {
+	open, close string
+	escape      bool // backslash escapes
+	multiline   bool
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	dq = delim{open: `"`, close: `"`, escape: true}
+	sq = delim{open: `'`, close: `'`, escape: true}
+)
+
+var languages = map[string]*language{
+	"go": {
+		lineComments: []string{"//"},
+		block:        [2]string{"/*", "*/"},
+		strs:         []delim{dq, sq, {open: "`", close: "`", multiline: true}},
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var`),
+		types: words(`bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64
+			rune string uint uint8 uint16 uint32 uint64 uintptr any comparable true false nil iota
+			append cap clear close complex copy delete imag len make max min new panic print println
+			real recover`),
+	},
+	"python": {
+		lineComments: []string{"#"},
+		strs: []delim{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+			{open: `'''`, close: `'''`, escape: true, multiline: true},
+			dq, sq,
+		},
+		keywords: words(`and as assert async await break class continue def del elif else except finally
+			for from global if import in is lambda match case nonlocal not or pass raise return try
+			while with yield`),
+		types: words(`True False None self cls int float str bytes bool list dict set tuple object type
+			print len range enumerate zip map filter open super isinstance getattr setattr hasattr
+			Exception ValueError TypeError KeyError IndexError RuntimeError`),
+		decorators:  true,
+		strPrefixes: "rbfu",
+	},
+	"shell": {
+		lineComments: []string{"#"},
+		strs:         []delim{{open: `"`, close: `"`, escape: true, multiline: true}, {open: `'`, close: `'`, multiline: true}},
+		keywords: words(`if then else elif fi for while until do done case esac in function select
+			return break continue time`),
+		types: words(`echo printf read cd pwd export local readonly declare typeset unset set shift
+			source eval exec exit test trap alias unalias true false getopts wait kill let`),
+		hashWordStart: true,
+		shellVars:     true,
+		dashIdents:    true,
+	},
+	"json": {
+		lineComments: []string{"//"},
+		block:        [2]string{"/*", "*/"},
+		strs:         []delim{dq},
+		types:        words(`true false null`),
+		keyStrings:   true,
+	},
+	"yaml": {
+		mode:          modeYAML,
+		lineComments:  []string{"#"},
+		strs:          []delim{dq, {open: `'`, close: `'`}},
+		types:         words(`true false null yes no on off True False Null Yes No On Off TRUE FALSE NULL ~`),
+		hashWordStart: true,
+		dashIdents:    true,
+		yaml:          true,
+	},
+	"toml": {
+		mode:         modeTOML,
+		lineComments: []string{"#"},
+		strs: []delim{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+			{open: `'''`, close: `'''`, multiline: true},
+			dq, {open: `'`, close: `'`},
+		},
+		types: words(`true false inf nan`),
+	},
+	"markdown": {mode: modeMarkdown},
+	"c": {
+		lineComments: []string{"//"},
+		block:        [2]string{"/*", "*/"},
+		strs:         []delim{dq, sq},
+		keywords: words(`auto break case const continue default do else enum extern for goto if inline
+			register restrict return sizeof static struct switch typedef union volatile while
+			class namespace template typename public private protected virtual override new delete
+			this throw try catch using nullptr constexpr`),
+		types: words(`void char short int long float double signed unsigned bool _Bool size_t ssize_t
+			int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t uintptr_t FILE NULL
+			true false`),
+		hashMeta: true,
+	},
+	"rust": {
+		lineComments: []string{"//"},
+		block:        [2]string{"/*", "*/"},
+		strs:         []delim{{open: `"`, close: `"`, escape: true, multiline: true}, {open: `b"`, close: `"`, escape: true, multiline: true}, sq},
+		keywords: words(`as async await break const continue crate dyn else enum extern fn for if impl in
+			let loop match mod move mut pub ref return self static struct super trait type unsafe use
+			where while`),
+		types: words(`i8 i16 i32 i64 i128 isize u8 u16 u32 u64 u128 usize f32 f64 bool char str String
+			Vec Option Result Box Rc Arc Self Some None Ok Err true false`),
+		rust: true,
+	},
+}
+
+// extLanguages maps file extensions to languages.
+var extLanguages = map[string]string{
+	".go":       "go",
+	".py":       "python",
+	".pyw":      "python",
+	".pyi":      "python",
+	".sh":       "shell",
+	".bash":     "shell",
+	".zsh":      "shell",
+	".ksh":      "shell",
+	".json":     "json",
+	".jsonc":    "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".toml":     "toml",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".c":        "c",
+	".h":        "c",
+	".cc":       "c",
+	".cpp":      "c",
+	".hpp":      "c",
+	".rs":       "rust",
+}
+
+// nameLanguages maps well-known file names without a telling extension.
+var nameLanguages = map[string]string{
+	".bashrc":       "shell",
+	".bash_profile": "shell",
+	".zshrc":        "shell",
+	".profile":      "shell",
+	"PKGBUILD":      "shell",
+	"Cargo.lock":    "toml",
+	"Pipfile":       "toml",
+	".clang-format": "yaml",
+}
+
+// languageAlias maps the names used in modelines, shebangs and Markdown
+// fences to a language, or returns "".
+func languageAlias(name string) string {
+	name = strings.ToLower(name)
+	switch name {
+	case "go", "golang":
+		return "go"
+	case "python", "py", "python2", "python3":
+		return "python"
+	case "sh", "bash", "zsh", "ksh", "dash", "ash", "shell", "shell-script", "console":
+		return "shell"
+	case "json", "jsonc":
+		return "json"
+	case "yaml", "yml":
+		return "yaml"
+	case "toml", "conf-toml":
+		return "toml"
+	case "markdown", "md", "gfm":
+		return "markdown"
+	case "c", "h", "cpp", "c++", "cc":
+		return "c"
+	case "rust", "rs":
+		return "rust"
+	}
+	if strings.HasPrefix(name, "python") {
+		return "python"
+	}
+	return ""
+}
+
+var (
+	vimModeline   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([\w+-]+)`)
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*?mode:\s*)?([\w+-]+)\s*(?:;.*)?-\*-`)
+)
+
+// DetectLanguage guesses the language of a text file from a modeline in its
+// first or last lines, its name or a shebang line. head holds the start of
+// the file. It returns "" for files that are not highlighted.
+func DetectLanguage(path string, head []byte) string {
+	lines := strings.Split(string(head), "\n")
+	if lang := modelineLanguage(lines); lang != "" {
+		return lang
+	}
+	base := filepath.Base(path)
+	if lang, ok := nameLanguages[base]; ok {
+		return lang
+	}
+	if lang, ok := extLanguages[strings.ToLower(filepath.Ext(base))]; ok {
+		return lang
+	}
+	return shebangLanguage(lines[0])
+}
+
+// modelineLanguage looks for a vim or emacs modeline in the first and last
+// five lines.
+func modelineLanguage(lines []string) string {
+	check := lines
+	if len(lines) > 10 {
+		check = append(append([]string(nil), lines[:5]...), lines[len(lines)-5:]...)
+	}
+	for _, ln := range check {
+		if m := vimModeline.FindStringSubmatch(ln); m != nil {
+			if lang := languageAlias(m[1]); lang != "" {
+				return lang
+			}
+		}
+		if m := emacsModeline.FindStringSubmatch(ln); m != nil {
+			if lang := languageAlias(m[1]); lang != "" {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// shebangLanguage maps the interpreter of a "#!" line to a language.
+func shebangLanguage(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	f := strings.Fields(line[2:])
+	if len(f) == 0 {
+		return ""
+	}
+	prog := filepath.Base(f[0])
+	if prog == "env" {
+		prog = ""
+		for _, a := range f[1:] {
+			if !strings.HasPrefix(a, "-") && !strings.Contains(a, "=") {
+				prog = filepath.Base(a)
+				break
+			}
+		}
+	}
+	return languageAlias(prog)
+}
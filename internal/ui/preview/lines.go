@@ -0,0 +1,139 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// LinesChunk is how many bytes Lines reads at a time.
+	LinesChunk = 64 << 10
+	// LinesMaxBytes bounds how much of one file Lines reads.
+	LinesMaxBytes = 16 << 20
+)
+
+// Lines reads the lines of a file lazily, a chunk at a time, so that the
+// preview can page through files of any size without reading them whole.
+type Lines struct {
+	path      string
+	raw       bool // show bytes that are not printable ASCII as '.'
+	lines     []string
+	off       int64  // bytes read so far
+	rest      []byte // start of a line not terminated yet
+	eof       bool
+	truncated bool
+	err       error
+}
+
+// NewLines returns a reader of the lines of path. With raw set, bytes other
+// than printable ASCII and tabs are shown as dots (the text view of binary
+// files).
+func NewLines(path string, raw bool) *Lines {
+	return &Lines{path: path, raw: raw}
+}
+
+// StaticLines returns Lines holding the lines of s.
+func StaticLines(s string) *Lines {
+	return &Lines{lines: splitLines(s), eof: true}
+}
+
+// Len returns the number of lines read so far.
+func (l *Lines) Len() int { return len(l.lines) }
+
+// Line returns line i, which must have been read.
+func (l *Lines) Line(i int) string { return l.lines[i] }
+
+// EOF reports whether every line has been read.
+func (l *Lines) EOF() bool { return l.eof }
+
+// Truncated reports whether reading stopped at LinesMaxBytes.
+func (l *Lines) Truncated() bool { return l.truncated }
+
+// Err returns the error that stopped reading, if any.
+func (l *Lines) Err() error { return l.err }
+
+// Ensure reads chunks until at least n lines are available or the file
+// ends, and returns the number of lines read.
+func (l *Lines) Ensure(n int) int {
+	if l.eof || len(l.lines) >= n {
+		return len(l.lines)
+	}
+	f, err := os.Open(l.path)
+	if err != nil {
+		l.stop(err)
+		return len(l.lines)
+	}
+	defer f.Close()
+	buf := make([]byte, LinesChunk)
+	for !l.eof && len(l.lines) < n {
+		k, err := f.ReadAt(buf, l.off)
+		l.off += int64(k)
+		l.add(buf[:k])
+		switch {
+		case errors.Is(err, io.EOF):
+			l.stop(nil)
+		case err != nil:
+			l.stop(err)
+		case l.off >= LinesMaxBytes:
+			l.truncated = true
+			l.stop(nil)
+		}
+	}
+	return len(l.lines)
+}
+
+// add splits b into complete lines, keeping an unterminated tail in rest.
+func (l *Lines) add(b []byte) {
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			l.rest = append(l.rest, b...)
+			return
+		}
+		line := b[:i]
+		if len(l.rest) > 0 {
+			line = append(l.rest, line...)
+			l.rest = nil
+		}
+		l.lines = append(l.lines, l.text(line))
+		b = b[i+1:]
+	}
+}
+
+// stop ends reading, keeping the unterminated last line.
+func (l *Lines) stop(err error) {
+	if len(l.rest) > 0 {
+		l.lines = append(l.lines, l.text(l.rest))
+		l.rest = nil
+	}
+	l.eof, l.err = true, err
+}
+
+func (l *Lines) text(b []byte) string {
+	b = bytes.TrimSuffix(b, []byte{'\r'})
+	if !l.raw {
+		return string(b)
+	}
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c != '\t' && (c < 0x20 || c >= 0x7f) {
+			c = '.'
+		}
+		out[i] = c
+	}
+	return string(out)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, ln := range lines {
+		lines[i] = strings.TrimSuffix(ln, "\r")
+	}
+	return lines
+}
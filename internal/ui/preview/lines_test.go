@@ -0,0 +1,59 @@
+package preview
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinesReadsChunksOnDemand(t *testing.T) {
+	var b strings.Builder
+	for i := 0; b.Len() < 3*LinesChunk; i++ {
+		fmt.Fprintf(&b, "line %05d\r\n", i)
+	}
+	b.WriteString("tail")
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLines(path, false)
+	if n := l.Ensure(10); n < 10 || l.EOF() {
+		t.Fatalf("first chunk: %d lines, eof %v", n, l.EOF())
+	}
+	if l.off != LinesChunk {
+		t.Fatalf("read %d bytes; want one chunk", l.off)
+	}
+	if got := l.Line(3); got != "line 00003" {
+		t.Fatalf("line 3 = %q", got)
+	}
+	n := l.Ensure(1 << 30)
+	if !l.EOF() || l.Truncated() || l.Err() != nil {
+		t.Fatalf("eof %v truncated %v err %v", l.EOF(), l.Truncated(), l.Err())
+	}
+	if got := l.Line(n - 1); got != "tail" {
+		t.Fatalf("last line = %q", got)
+	}
+	// Lines split across chunks are joined.
+	for i := 0; i < n-1; i++ {
+		if want := fmt.Sprintf("line %05d", i); l.Line(i) != want {
+			t.Fatalf("line %d = %q; want %q", i, l.Line(i), want)
+		}
+	}
+}
+
+func TestLinesRaw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blob.bin")
+	if err := os.WriteFile(path, []byte("ab\x00\x01\tc\n\xffd"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLines(path, true)
+	l.Ensure(10)
+	if l.Len() != 2 || l.Line(0) != "ab..\tc" || l.Line(1) != ".d" {
+		t.Fatalf("raw lines = %q", l.lines)
+	}
+	if s := StaticLines("a\r\nb"); s.Len() != 2 || s.Line(0) != "a" || !s.EOF() {
+		t.Fatalf("static lines = %q", s.lines)
+	}
+}
@@ -0,0 +1,238 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+)
+
+// ImageMeta describes an image file without decoding its pixels.
+type ImageMeta struct {
+	Width, Height int
+	Format        string // decoder name: png, jpeg, gif, bmp, tiff, webp
+	Orientation   int    // EXIF orientation, 1..8 (0 = not recorded)
+	Camera        string // EXIF make and model
+	Taken         string // EXIF date and time of the shot, as recorded
+}
+
+// metaScanBytes bounds how much of a file is searched for EXIF data,
+// which cameras write near the start.
+const metaScanBytes = 256 << 10
+
+// ReadImageMeta reads the dimensions and format of the image at path and
+// the EXIF data of JPEG, TIFF, PNG and WebP files.
+func ReadImageMeta(path string) (ImageMeta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	defer f.Close()
+	return readImageMeta(f)
+}
+
+// readImageMeta implements ReadImageMeta on an open file, from its start.
+func readImageMeta(f *os.File) (ImageMeta, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ImageMeta{}, err
+	}
+	cfg, format, err := image.DecodeConfig(bufio.NewReader(f))
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	meta := ImageMeta{Width: cfg.Width, Height: cfg.Height, Format: format}
+	head := make([]byte, metaScanBytes)
+	n, _ := f.ReadAt(head, 0)
+	if exif := findExif(head[:n], format); exif != nil {
+		meta.readExif(exif)
+	}
+	return meta, nil
+}
+
+// Pixels is the number of pixels of the image.
+func (im ImageMeta) Pixels() int { return im.Width * im.Height }
+
+// orientations describe the EXIF orientations.
+var orientations = [9]string{
+	2: "mirrored",
+	3: "rotated 180°",
+	4: "flipped vertically",
+	5: "mirrored, rotated 90° CCW",
+	6: "rotated 90° CW",
+	7: "mirrored, rotated 90° CW",
+	8: "rotated 90° CCW",
+}
+
+// Lines describes the image for the preview, one property per line.
+func (im ImageMeta) Lines() []string {
+	lines := []string{fmt.Sprintf("%d×%d %s", im.Width, im.Height, im.Format)}
+	if im.Camera != "" {
+		lines = append(lines, "camera: "+im.Camera)
+	}
+	if im.Taken != "" {
+		lines = append(lines, "taken: "+im.Taken)
+	}
+	if im.Orientation > 1 && im.Orientation < len(orientations) {
+		lines = append(lines, "orientation: "+orientations[im.Orientation])
+	}
+	return lines
+}
+
+// findExif returns the EXIF data (a TIFF structure) in the first bytes of
+// an image file, or nil.
+func findExif(b []byte, format string) []byte {
+	switch format {
+	case "tiff":
+		return b
+	case "jpeg":
+		// Segments up to the start of the image data; APP1 holds EXIF.
+		for i := 2; i+4 <= len(b) && b[i] == 0xff; {
+			marker, size := b[i+1], int(binary.BigEndian.Uint16(b[i+2:]))
+			if marker == 0xda || i+2+size > len(b) {
+				break
+			}
+			seg := b[i+4 : i+2+size]
+			if marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+				return seg[6:]
+			}
+			i += 2 + size
+		}
+	case "png":
+		for i := 8; i+8 <= len(b); {
+			size := int(binary.BigEndian.Uint32(b[i:]))
+			typ := string(b[i+4 : i+8])
+			if typ == "IDAT" || size < 0 || i+12+size > len(b) {
+				break
+			}
+			if typ == "eXIf" {
+				return b[i+8 : i+8+size]
+			}
+			i += 12 + size
+		}
+	case "webp":
+		for i := 12; i+8 <= len(b); {
+			size := int(binary.LittleEndian.Uint32(b[i+4:]))
+			if size < 0 || i+8+size > len(b) {
+				break
+			}
+			if string(b[i:i+4]) == "EXIF" {
+				return bytes.TrimPrefix(b[i+8:i+8+size], []byte("Exif\x00\x00"))
+			}
+			i += 8 + size + size&1
+		}
+	}
+	return nil
+}
+
+// EXIF tags read by readExif.
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+var errExif = errors.New("malformed EXIF data")
+
+// readExif fills in the EXIF properties found in the TIFF structure b.
+// Malformed data is ignored.
+func (im *ImageMeta) readExif(b []byte) {
+	var order binary.ByteOrder
+	switch {
+	case len(b) < 8:
+		return
+	case bytes.HasPrefix(b, []byte("II*\x00")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(b, []byte("MM\x00*")):
+		order = binary.BigEndian
+	default:
+		return
+	}
+	tags := map[uint16]ifdEntry{}
+	if err := readIFD(b, order, order.Uint32(b[4:]), tags); err != nil {
+		return
+	}
+	if e, ok := tags[tagExifIFD]; ok {
+		readIFD(b, order, e.value(order), tags)
+	}
+	im.Camera = cameraName(tags[tagMake].text(b, order), tags[tagModel].text(b, order))
+	im.Taken = tags[tagDateTimeOriginal].text(b, order)
+	if im.Taken == "" {
+		im.Taken = tags[tagDateTime].text(b, order)
+	}
+	if e, ok := tags[tagOrientation]; ok {
+		if o := int(e.value(order)); o >= 1 && o <= 8 {
+			im.Orientation = o
+		}
+	}
+}
+
+// ifdEntry is a field of a TIFF image file directory.
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	raw   []byte // the 4-byte value or offset
+}
+
+// readIFD adds the fields of the directory at off in b to tags.
+func readIFD(b []byte, order binary.ByteOrder, off uint32, tags map[uint16]ifdEntry) error {
+	if int64(off)+2 > int64(len(b)) {
+		return errExif
+	}
+	n := int(order.Uint16(b[off:]))
+	start := int(off) + 2
+	if start+12*n > len(b) {
+		return errExif
+	}
+	for i := 0; i < n; i++ {
+		e := b[start+12*i:]
+		tags[order.Uint16(e)] = ifdEntry{typ: order.Uint16(e[2:]), count: order.Uint32(e[4:]), raw: e[8:12]}
+	}
+	return nil
+}
+
+// value returns a SHORT or LONG field.
+func (e ifdEntry) value(order binary.ByteOrder) uint32 {
+	switch e.typ {
+	case 3: // SHORT
+		return uint32(order.Uint16(e.raw))
+	case 4: // LONG
+		return order.Uint32(e.raw)
+	}
+	return 0
+}
+
+// text returns an ASCII field without its terminating NUL.
+func (e ifdEntry) text(b []byte, order binary.ByteOrder) string {
+	if e.typ != 2 || e.count == 0 || e.count > 256 {
+		return ""
+	}
+	data := e.raw
+	if e.count > 4 {
+		off := int64(order.Uint32(e.raw))
+		if off+int64(e.count) > int64(len(b)) {
+			return ""
+		}
+		data = b[off : off+int64(e.count)]
+	}
+	data = data[:min(int(e.count), len(data))]
+	return strings.TrimSpace(string(bytes.TrimRight(data, "\x00")))
+}
+
+// cameraName joins make and model, which often repeats the make.
+func cameraName(maker, model string) string {
+	if maker == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)) {
+		return model
+	}
+	if model == "" {
+		return maker
+	}
+	return maker + " " + model
+}
@@ -0,0 +1,160 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// exifTIFF builds little-endian EXIF data with a camera, an orientation and
+// the date of the shot in the EXIF sub-directory.
+func exifTIFF(orientation uint16) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	entry := func(tag, typ uint16, count, value uint32) {
+		binary.Write(&b, le, tag)
+		binary.Write(&b, le, typ)
+		binary.Write(&b, le, count)
+		binary.Write(&b, le, value)
+	}
+	const ifd0, subIFD, strs = 8, 8 + 2 + 4*12 + 4, 8 + 2 + 4*12 + 4 + 2 + 12 + 4
+	maker, model, date := "Canon\x00", "Canon EOS R5\x00", "2023:05:01 12:34:56\x00"
+	b.WriteString("II*\x00")
+	binary.Write(&b, le, uint32(ifd0))
+	binary.Write(&b, le, uint16(4))
+	entry(tagMake, 2, uint32(len(maker)), strs)
+	entry(tagModel, 2, uint32(len(model)), uint32(strs+len(maker)))
+	entry(tagOrientation, 3, 1, uint32(orientation))
+	entry(tagExifIFD, 4, 1, subIFD)
+	binary.Write(&b, le, uint32(0))
+	binary.Write(&b, le, uint16(1))
+	entry(tagDateTimeOriginal, 2, uint32(len(date)), uint32(strs+len(maker)+len(model)))
+	binary.Write(&b, le, uint32(0))
+	b.WriteString(maker + model + date)
+	return b.Bytes()
+}
+
+// writeJPEG writes a w×h JPEG with exif in an APP1 segment.
+func writeJPEG(t *testing.T, w, h int, exif []byte) string {
+	t.Helper()
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	app1 := append([]byte("Exif\x00\x00"), exif...)
+	var out bytes.Buffer
+	out.Write(img.Bytes()[:2]) // SOI
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(app1)+2))
+	out.Write(app1)
+	out.Write(img.Bytes()[2:])
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadImageMetaExif(t *testing.T) {
+	path := writeJPEG(t, 40, 20, exifTIFF(6))
+	meta, err := ReadImageMeta(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImageMeta{Width: 40, Height: 20, Format: "jpeg", Orientation: 6, Camera: "Canon EOS R5", Taken: "2023:05:01 12:34:56"}
+	if meta != want {
+		t.Fatalf("meta = %+v\nwant %+v", meta, want)
+	}
+	if got := strings.Join(meta.Lines(), "|"); got != "40×20 jpeg|camera: Canon EOS R5|taken: 2023:05:01 12:34:56|orientation: rotated 90° CW" {
+		t.Fatalf("lines = %q", got)
+	}
+	// Decoded images are turned upright.
+	img, _, err := decodeImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("upright bounds = %v; want 20×40", b)
+	}
+	res, _ := Info(path)
+	if !strings.Contains(res.Content, "camera: Canon EOS R5") {
+		t.Fatalf("info lacks the image metadata:\n%s", res.Content)
+	}
+}
+
+func TestOrientedImage(t *testing.T) {
+	// A 3×2 image with a marked top-left pixel.
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	mark := color.RGBA{R: 255, A: 255}
+	src.SetRGBA(0, 0, mark)
+	// Where the top-left pixel ends up, for each orientation.
+	want := map[int]image.Point{1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1}, 5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2}}
+	for o, p := range want {
+		img := orientedImage{src, o}
+		if o >= 5 && img.Bounds() != image.Rect(0, 0, 2, 3) {
+			t.Fatalf("orientation %d: bounds %v", o, img.Bounds())
+		}
+		if got := color.RGBAModel.Convert(img.At(p.X, p.Y)); got != mark {
+			t.Errorf("orientation %d: pixel %v = %v, not the marked one", o, p, got)
+		}
+	}
+}
+
+// pngHeader returns the start of a PNG file claiming w×h pixels.
+func pngHeader(w, h uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32(nil, w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	b = append(b, chunk...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(chunk))
+}
+
+func TestDecodeImageRefusesHugeImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.png")
+	if err := os.WriteFile(path, pngHeader(20000, 20000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := decodeImage(path); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("err = %v; want ErrImageTooLarge", err)
+	}
+	res, err := BasicProvider{}.Preview(path, 0)
+	if err != nil || res.Kind != "image" || !strings.Contains(res.Content, "20000×20000 png: image too large") {
+		t.Fatalf("preview = %+v, %v", res, err)
+	}
+}
+
+func TestDecodeMoreFormats(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	dir := t.TempDir()
+	for name, enc := range map[string]func(*bytes.Buffer) error{
+		"pic.bmp":  func(b *bytes.Buffer) error { return bmp.Encode(b, img) },
+		"pic.tiff": func(b *bytes.Buffer) error { return tiff.Encode(b, img, nil) },
+	} {
+		var b bytes.Buffer
+		if err := enc(&b); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		res, err := BasicProvider{}.Preview(path, 0)
+		if err != nil || res.Kind != "image" {
+			t.Errorf("%s: preview = %+v, %v", name, res, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package preview
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
@@ -11,6 +12,10 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 )
 
 // Result is a generic preview result.
@@ -18,6 +23,7 @@ type Result struct {
 	Kind    string // e.g., "text", "info"
 	Content string
 	Mime    string
+	Lang    string // language of a "text" result for highlighting (see DetectLanguage)
 }
 
 // Provider renders preview for a given path.
@@ -45,43 +51,25 @@ func (BasicProvider) Preview(path string, maxBytes int) (Result, error) {
 		return Result{}, err
 	}
 	defer f.Close()
-	cfg, format, cfgErr := image.DecodeConfig(f)
-	if cfgErr == nil && (format == "png" || format == "jpeg" || format == "gif") {
-		// Rewind and decode full image (best-effort, may be heavy for huge files)
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			// Seek failed (unlikely) — reopen
-			f.Close()
-			f, err = os.Open(path)
-			if err != nil {
-				return Result{}, err
-			}
+	if meta, err := readImageMeta(f); err == nil {
+		mime := "image/" + meta.Format
+		img, err := decodeImageFile(f, meta)
+		if errors.Is(err, ErrImageTooLarge) {
+			return Result{Kind: "image", Content: fmt.Sprintf("%d×%d %s: %v", meta.Width, meta.Height, meta.Format, ErrImageTooLarge), Mime: mime}, nil
 		}
-		img, _, err := image.Decode(f)
 		if err == nil {
 			// Produce ASCII thumbnail preview
 			targetW := 64
-			if cfg.Width > 0 && cfg.Width < targetW {
-				targetW = cfg.Width
-			}
-			ascii := asciiPreview(img, targetW)
-			mime := "image/" + format
-			return Result{Kind: "image", Content: ascii, Mime: mime}, nil
-		}
-		// If decode failed, fall through to text path: rewind for buffered read
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			// Best effort: reopen
-			f.Close()
-			if rf, er2 := os.Open(path); er2 == nil {
-				f = rf
-				defer f.Close()
+			if meta.Width > 0 && meta.Width < targetW {
+				targetW = meta.Width
 			}
+			return Result{Kind: "image", Content: asciiPreview(img, targetW), Mime: mime}, nil
 		}
-	} else {
-		// Not an image (or unknown): rewind before reading bytes
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			f.Close()
-			f, _ = os.Open(path)
-		}
+		// If decode failed, fall through to the text path.
+	}
+	// Rewind before reading bytes
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Result{}, err
 	}
 	// Try read a small chunk and detect as text
 	r := bufio.NewReader(f)
@@ -113,7 +101,7 @@ func (BasicProvider) Preview(path string, maxBytes int) (Result, error) {
 		} else {
 			s = strings.Join(lines, "\n")
 		}
-		return Result{Kind: "text", Content: s, Mime: "text/plain"}, nil
+		return Result{Kind: "text", Content: s, Mime: "text/plain", Lang: DetectLanguage(path, buf)}, nil
 	}
 	// Fallback to metadata
 	info := fmt.Sprintf("%s (%d bytes)\nmode: %s\nmodified: %s",
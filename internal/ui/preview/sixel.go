@@ -0,0 +1,256 @@
+package preview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+)
+
+// SixelIntro starts every sixel image EncodeSixel produces: P2=1 leaves
+// transparent pixels alone, P1=0 keeps the default 2:1 aspect, which the
+// raster attributes then set to 1:1.
+const SixelIntro = "\x1bP0;1;0q"
+
+// SixelColors is the palette size of encoded images; terminals commonly
+// provide 256 sixel color registers.
+const SixelColors = 256
+
+// BuildSixel renders the image at path as sixels, scaled to fit in
+// cols×rows cells. It returns the lines laid out by SixelLines and false if
+// the image cannot be decoded or there is no room for it.
+func BuildSixel(path string, cols, rows int, cell CellSize) ([]string, bool) {
+	if cols <= 0 || rows <= 1 {
+		return nil, false
+	}
+	img, _, err := decodeImage(path)
+	if err != nil {
+		return nil, false
+	}
+	// The last row carries the image, see SixelLines.
+	scaled, _, r := fitImage(img, cols, rows-1, cell)
+	if scaled == nil {
+		return nil, false
+	}
+	return SixelLines(EncodeSixel(scaled, SixelColors), r, cols), true
+}
+
+// SixelLines lays out a sixel image covering rows cells in lines of width
+// blank cells. Sixels are drawn into the text cells, so text written over
+// the image erases it: the image is sent in front of the line below it,
+// after the lines it covers have been written, from the top left corner
+// of the image and with the cursor restored afterwards.
+func SixelLines(seq string, rows, width int) []string {
+	pad := strings.Repeat(" ", width)
+	lines := make([]string, rows+1)
+	for i := range lines {
+		lines[i] = pad
+	}
+	lines[rows] = fmt.Sprintf("\x1b7\x1b[%dA%s\x1b8", rows, seq) + pad
+	return lines
+}
+
+// EncodeSixel encodes img as a sixel image with a palette of at most
+// colors entries. Pixels that are more than half transparent are not
+// drawn.
+func EncodeSixel(img *image.RGBA, colors int) string {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	palette, idx := quantize(img, colors)
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s\"1;1;%d;%d", SixelIntro, w, h)
+	for i, c := range palette {
+		fmt.Fprintf(&s, "#%d;2;%d;%d;%d", i, percent(c.R), percent(c.G), percent(c.B))
+	}
+	// Each band of 6 pixel rows is sent one color at a time; bits[c] holds
+	// the sixels of color c in the current band.
+	bits := make([][]byte, len(palette))
+	inBand := make([]bool, len(palette))
+	var used []int
+	for y0 := 0; y0 < h; y0 += 6 {
+		used = used[:0]
+		for y := y0; y < min(y0+6, h); y++ {
+			for x := 0; x < w; x++ {
+				c := idx[y*w+x]
+				if c < 0 {
+					continue
+				}
+				if !inBand[c] {
+					if bits[c] == nil {
+						bits[c] = make([]byte, w)
+					}
+					inBand[c] = true
+					used = append(used, int(c))
+				}
+				bits[c][x] |= 1 << (y - y0)
+			}
+		}
+		for i, c := range used {
+			if i > 0 {
+				s.WriteByte('$') // back to the start of the band
+			}
+			fmt.Fprintf(&s, "#%d", c)
+			writeSixels(&s, bits[c])
+			clear(bits[c])
+			inBand[c] = false
+		}
+		if y0+6 < h {
+			s.WriteByte('-') // next band
+		}
+	}
+	s.WriteString("\x1b\\")
+	return s.String()
+}
+
+// writeSixels writes one band of one color, run-length encoded. Trailing
+// empty sixels are left out.
+func writeSixels(s *strings.Builder, row []byte) {
+	end := len(row)
+	for end > 0 && row[end-1] == 0 {
+		end--
+	}
+	for i := 0; i < end; {
+		n := 1
+		for i+n < end && row[i+n] == row[i] {
+			n++
+		}
+		ch := byte('?' + row[i])
+		if n > 3 {
+			fmt.Fprintf(s, "!%d%c", n, ch)
+		} else {
+			for range n {
+				s.WriteByte(ch)
+			}
+		}
+		i += n
+	}
+}
+
+// percent converts a color channel to the 0..100 range of sixel colors.
+func percent(v uint8) int { return (int(v)*100 + 127) / 255 }
+
+// colorBox is a set of histogram entries cut out by median cut.
+type colorBox []histEntry
+
+// histEntry counts the pixels of one 15-bit color.
+type histEntry struct {
+	key uint16 // 5 bits each of red, green and blue
+	n   int
+}
+
+func channel(key uint16, ch int) int { return int(key>>(10-5*ch)) & 31 }
+
+// widest returns the channel with the largest range of values in the box
+// and that range.
+func (bx colorBox) widest() (int, int) {
+	best, bestRange := 0, -1
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := 31, 0
+		for _, e := range bx {
+			v := channel(e.key, ch)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > bestRange {
+			best, bestRange = ch, hi-lo
+		}
+	}
+	return best, bestRange
+}
+
+// mean is the pixel-weighted average color of the box.
+func (bx colorBox) mean() color.RGBA {
+	var sum [3]int
+	total := 0
+	for _, e := range bx {
+		for ch := range sum {
+			sum[ch] += channel(e.key, ch) * e.n
+		}
+		total += e.n
+	}
+	var c [3]uint8
+	for ch := range c {
+		v := sum[ch] / total
+		c[ch] = uint8(v<<3 | v>>2)
+	}
+	return color.RGBA{R: c[0], G: c[1], B: c[2], A: 0xff}
+}
+
+// quantize reduces the opaque pixels of img to at most n colors by median
+// cut over a 15-bit color histogram. It returns the palette and the palette
+// index of every pixel, row by row, with -1 for transparent pixels.
+func quantize(img *image.RGBA, n int) ([]color.RGBA, []int16) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	idx := make([]int16, w*h)
+	var hist [1 << 15]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y):]
+			a := int(p[3])
+			if a < 0x80 {
+				idx[y*w+x] = -1
+				continue
+			}
+			// Pixels are alpha-premultiplied.
+			r, g, bl := int(p[0])*255/a, int(p[1])*255/a, int(p[2])*255/a
+			key := int16(r>>3<<10 | g>>3<<5 | bl>>3)
+			hist[key]++
+			idx[y*w+x] = key
+		}
+	}
+	var all colorBox
+	for k, c := range hist {
+		if c > 0 {
+			all = append(all, histEntry{key: uint16(k), n: c})
+		}
+	}
+	if len(all) == 0 {
+		return nil, idx
+	}
+	boxes := []colorBox{all}
+	for len(boxes) < n {
+		// Split the box with the widest spread of colors at the median
+		// pixel along that channel.
+		pick, ch, spread := -1, 0, 0
+		for i, bx := range boxes {
+			if len(bx) < 2 {
+				continue
+			}
+			if c, r := bx.widest(); r > spread {
+				pick, ch, spread = i, c, r
+			}
+		}
+		if pick < 0 {
+			break
+		}
+		bx := boxes[pick]
+		slices.SortFunc(bx, func(a, b histEntry) int { return channel(a.key, ch) - channel(b.key, ch) })
+		total := 0
+		for _, e := range bx {
+			total += e.n
+		}
+		cut, seen := 1, bx[0].n
+		for cut < len(bx)-1 && seen+bx[cut].n <= total/2 {
+			seen += bx[cut].n
+			cut++
+		}
+		boxes[pick] = bx[:cut]
+		boxes = append(boxes, bx[cut:])
+	}
+	palette := make([]color.RGBA, len(boxes))
+	var lut [1 << 15]int16
+	for i, bx := range boxes {
+		palette[i] = bx.mean()
+		for _, e := range bx {
+			lut[e.key] = int16(i)
+		}
+	}
+	for i, k := range idx {
+		if k >= 0 {
+			idx[i] = lut[k]
+		}
+	}
+	return palette, idx
+}
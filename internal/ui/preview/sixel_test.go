@@ -0,0 +1,63 @@
+package preview
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestEncodeSixelGolden(t *testing.T) {
+	// Red top half, blue bottom half with a transparent column on the right.
+	img := image.NewRGBA(image.Rect(0, 0, 5, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if y >= 4 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	golden(t, "sixel.golden", EncodeSixel(img, SixelColors))
+}
+
+func TestQuantize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 4))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 4; y++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 64), B: 0x80, A: 0xff})
+		}
+	}
+	palette, idx := quantize(img, 16)
+	if len(palette) != 16 {
+		t.Fatalf("palette has %d colors; want 16", len(palette))
+	}
+	for i, c := range idx {
+		if c < 0 || int(c) >= len(palette) {
+			t.Fatalf("pixel %d has index %d", i, c)
+		}
+	}
+	// With room for every color, colors stay exact up to the histogram's
+	// 5 bits per channel.
+	palette, idx = quantize(img, 1024)
+	if got := palette[idx[0]]; got != (color.RGBA{R: 0, G: 0, B: 0x84, A: 0xff}) {
+		t.Fatalf("first pixel = %v", got)
+	}
+}
+
+func TestSixelLines(t *testing.T) {
+	lines := SixelLines("SIXEL", 2, 4)
+	if len(lines) != 3 || lines[0] != "    " || lines[1] != "    " {
+		t.Fatalf("lines = %q", lines)
+	}
+	if want := "\x1b7\x1b[2ASIXEL\x1b8    "; lines[2] != want {
+		t.Fatalf("image line = %q; want %q", lines[2], want)
+	}
+	if _, ok := BuildSixel("testdata/none.png", 10, 1, DefaultCellSize); ok {
+		t.Fatalf("image built without room for it")
+	}
+	if !strings.HasPrefix(EncodeSixel(image.NewRGBA(image.Rect(0, 0, 1, 1)), 4), SixelIntro) {
+		t.Fatalf("missing sixel intro")
+	}
+}
@@ -0,0 +1,278 @@
+package preview
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Thumbnail styles, drawing images with text for terminals without an
+// image protocol.
+const (
+	ThumbBlocks  = "blocks"  // upper half blocks, two pixels per cell
+	ThumbBraille = "braille" // braille patterns, 2×4 dots per cell
+	ThumbASCII   = "ascii"   // the provider's gray-scale character ramp
+)
+
+// ThumbnailStyle returns the thumbnail style for the configured setting
+// (auto|blocks|braille|ascii) and the color profile in use (truecolor,
+// ansi256, ansi or ascii). Half blocks need a color for each of their two
+// pixels, so with 16 colors or none auto picks braille.
+func ThumbnailStyle(setting, profile string) string {
+	switch setting = strings.ToLower(setting); setting {
+	case ThumbBraille, ThumbASCII:
+		return setting
+	case ThumbBlocks:
+		if profile != "ascii" {
+			return setting
+		}
+		return ThumbBraille
+	}
+	if profile == "truecolor" || profile == "ansi256" {
+		return ThumbBlocks
+	}
+	return ThumbBraille
+}
+
+// BuildThumbnail draws the image at path in style with at most cols×rows
+// cells, colored for profile. Lines are padded to cols cells. It returns
+// false if the image cannot be decoded.
+func BuildThumbnail(path string, cols, rows int, cell CellSize, style, profile string) ([]string, bool) {
+	if cols <= 0 || rows <= 0 {
+		return nil, false
+	}
+	img, _, err := decodeImage(path)
+	if err != nil {
+		return nil, false
+	}
+	b := img.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 {
+		return nil, false
+	}
+	c, r := thumbCells(b.Dx(), b.Dy(), cols, rows, cell)
+	var lines []string
+	if style == ThumbBraille {
+		lines = brailleLines(scaleImage(img, 2*c, 4*r), profile)
+	} else {
+		lines = blockLines(scaleImage(img, c, 2*r), profile)
+	}
+	for i, l := range lines {
+		lines[i] = l + strings.Repeat(" ", cols-c)
+	}
+	return lines, true
+}
+
+// thumbCells returns the cells an image of w×h pixels fills when scaled up
+// or down to fit in cols×rows cells, keeping its aspect ratio.
+func thumbCells(w, h, cols, rows int, cell CellSize) (int, int) {
+	if cell.W <= 0 || cell.H <= 0 {
+		cell = DefaultCellSize
+	}
+	c := cols
+	r := (h*c*cell.W + w*cell.H - 1) / (w * cell.H)
+	if r > rows {
+		r = rows
+		c = (w*r*cell.H + h*cell.W - 1) / (h * cell.W)
+	}
+	return max(min(c, cols), 1), max(r, 1)
+}
+
+// opaque reports whether a pixel is drawn: mostly transparent pixels show
+// the terminal background.
+func opaque(c color.RGBA) bool { return c.A >= 0x80 }
+
+// blockLines draws img with one upper half block per two pixels stacked
+// vertically: the foreground colors the top pixel, the background the
+// bottom one.
+func blockLines(img *image.RGBA, profile string) []string {
+	b := img.Bounds()
+	lines := make([]string, 0, b.Dy()/2)
+	for y := 0; y+1 < b.Dy(); y += 2 {
+		var s sgrWriter
+		for x := 0; x < b.Dx(); x++ {
+			top, bottom := img.RGBAAt(x, y), img.RGBAAt(x, y+1)
+			switch {
+			case opaque(top) && opaque(bottom):
+				s.cell('▀', colorSGR(top, profile, false), colorSGR(bottom, profile, true))
+			case opaque(top):
+				s.cell('▀', colorSGR(top, profile, false), "")
+			case opaque(bottom):
+				s.cell('▄', colorSGR(bottom, profile, false), "")
+			default:
+				s.cell(' ', "", "")
+			}
+		}
+		lines = append(lines, s.end())
+	}
+	return lines
+}
+
+// brailleDots are the bits of the braille pattern dots, by row and column.
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// brailleLines draws img with one braille pattern per 2×4 pixels. Dots are
+// set for pixels brighter than the average of the image and take the mean
+// color of the cell's lit pixels.
+func brailleLines(img *image.RGBA, profile string) []string {
+	b := img.Bounds()
+	var sum, n int
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if c := img.RGBAAt(x, y); opaque(c) {
+				sum += luma(c)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		n = 1
+	}
+	avg := sum / n
+	lines := make([]string, 0, b.Dy()/4)
+	for y := 0; y+3 < b.Dy(); y += 4 {
+		var s sgrWriter
+		for x := 0; x+1 < b.Dx(); x += 2 {
+			var dots rune
+			var r, g, bl, lit int
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					c := img.RGBAAt(x+dx, y+dy)
+					if !opaque(c) || luma(c) <= avg {
+						continue
+					}
+					dots |= brailleDots[dy][dx]
+					r, g, bl, lit = r+int(c.R), g+int(c.G), bl+int(c.B), lit+1
+				}
+			}
+			if lit == 0 {
+				s.cell(' ', "", "")
+				continue
+			}
+			mean := color.RGBA{R: uint8(r / lit), G: uint8(g / lit), B: uint8(bl / lit), A: 0xff}
+			s.cell(0x2800+dots, colorSGR(mean, profile, false), "")
+		}
+		lines = append(lines, s.end())
+	}
+	return lines
+}
+
+// luma is the Rec. 601 brightness of c, 0..255.
+func luma(c color.RGBA) int { return (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000 }
+
+// sgrWriter writes cells with colors, sending only the changes.
+type sgrWriter struct {
+	b      strings.Builder
+	fg, bg string
+}
+
+// cell writes r with the SGR parameters fg and bg; empty parameters stand
+// for the default colors.
+func (w *sgrWriter) cell(r rune, fg, bg string) {
+	var params []string
+	if fg != w.fg {
+		params, w.fg = append(params, cmp.Or(fg, "39")), fg
+	}
+	if bg != w.bg {
+		params, w.bg = append(params, cmp.Or(bg, "49")), bg
+	}
+	if len(params) > 0 {
+		w.b.WriteString("\x1b[" + strings.Join(params, ";") + "m")
+	}
+	w.b.WriteRune(r)
+}
+
+// end finishes the line, resetting the colors.
+func (w *sgrWriter) end() string {
+	if w.fg != "" || w.bg != "" {
+		w.b.WriteString("\x1b[m")
+	}
+	return w.b.String()
+}
+
+// colorSGR returns the SGR parameters selecting c as the foreground or
+// background color in profile, or "" for the ascii profile.
+func colorSGR(c color.RGBA, profile string, bg bool) string {
+	switch profile {
+	case "truecolor":
+		if bg {
+			return fmt.Sprintf("48;2;%d;%d;%d", c.R, c.G, c.B)
+		}
+		return fmt.Sprintf("38;2;%d;%d;%d", c.R, c.G, c.B)
+	case "ansi256":
+		if bg {
+			return fmt.Sprintf("48;5;%d", ansi256(c))
+		}
+		return fmt.Sprintf("38;5;%d", ansi256(c))
+	case "ansi":
+		i := ansi16(c)
+		base := 30
+		if bg {
+			base = 40
+		}
+		if i >= 8 {
+			base += 60 - 8 // bright colors: 90-97 and 100-107
+		}
+		return fmt.Sprint(base + i)
+	}
+	return ""
+}
+
+// cubeLevels are the channel values of the 6×6×6 color cube of the
+// 256-color palette.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// ansi256 returns the palette index closest to c among the color cube
+// and the gray ramp.
+func ansi256(c color.RGBA) int {
+	level := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := level(c.R), level(c.G), level(c.B)
+	cube := 16 + 36*r + 6*g + b
+	cubeDist := dist(c, cubeLevels[r], cubeLevels[g], cubeLevels[b])
+	gray := min(max((int(c.R)+int(c.G)+int(c.B))/3-8+5, 0)/10, 23)
+	gv := 8 + 10*gray
+	if dist(c, gv, gv, gv) < cubeDist {
+		return 232 + gray
+	}
+	return cube
+}
+
+// ansi16Colors are the usual RGB values of the 16 basic colors.
+var ansi16Colors = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// ansi16 returns the basic color closest to c, 0..15.
+func ansi16(c color.RGBA) int {
+	best, bestDist := 0, -1
+	for i, p := range ansi16Colors {
+		if d := dist(c, p[0], p[1], p[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func dist(c color.RGBA, r, g, b int) int {
+	dr, dg, db := int(c.R)-r, int(c.G)-g, int(c.B)-b
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
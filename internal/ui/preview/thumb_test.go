@@ -0,0 +1,95 @@
+package preview
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnailStyle(t *testing.T) {
+	for _, tc := range []struct{ setting, profile, want string }{
+		{"auto", "truecolor", ThumbBlocks},
+		{"auto", "ansi256", ThumbBlocks},
+		{"auto", "ansi", ThumbBraille},
+		{"", "ascii", ThumbBraille},
+		{"Blocks", "ansi", ThumbBlocks},
+		{"blocks", "ascii", ThumbBraille},
+		{"ascii", "truecolor", ThumbASCII},
+	} {
+		if got := ThumbnailStyle(tc.setting, tc.profile); got != tc.want {
+			t.Errorf("ThumbnailStyle(%q, %q) = %q; want %q", tc.setting, tc.profile, got, tc.want)
+		}
+	}
+}
+
+func TestBlockLines(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	img.SetRGBA(0, 0, red)
+	img.SetRGBA(0, 1, blue)
+	img.SetRGBA(1, 0, red)
+	img.SetRGBA(1, 1, blue)
+	img.SetRGBA(2, 1, red) // transparent top pixel
+	lines := blockLines(img, "truecolor")
+	want := "\x1b[38;2;255;0;0;48;2;0;0;255m▀▀\x1b[49m▄\x1b[m"
+	if len(lines) != 1 || lines[0] != want {
+		t.Fatalf("lines = %q; want %q", lines, want)
+	}
+	if lines := blockLines(img, "ansi256"); lines[0] != "\x1b[38;5;196;48;5;21m▀▀\x1b[49m▄\x1b[m" {
+		t.Fatalf("256 colors: %q", lines[0])
+	}
+}
+
+func TestBrailleLines(t *testing.T) {
+	// Left column white, right column black: the left dots of one cell.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 4))
+	for y := 0; y < 4; y++ {
+		img.SetRGBA(0, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		img.SetRGBA(1, y, color.RGBA{A: 255})
+	}
+	if lines := brailleLines(img, "ascii"); len(lines) != 1 || lines[0] != "⡇" {
+		t.Fatalf("lines = %q", lines)
+	}
+	if lines := brailleLines(img, "ansi"); lines[0] != "\x1b[97m⡇\x1b[m" {
+		t.Fatalf("16 colors: %q", lines[0])
+	}
+}
+
+func TestAnsi256(t *testing.T) {
+	for _, tc := range []struct {
+		c    color.RGBA
+		want int
+	}{
+		{color.RGBA{R: 255}, 196},
+		{color.RGBA{R: 128, G: 128, B: 128}, 244},
+		{color.RGBA{}, 16},
+		{color.RGBA{R: 95, G: 135, B: 255}, 69},
+	} {
+		if got := ansi256(tc.c); got != tc.want {
+			t.Errorf("ansi256(%v) = %d; want %d", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestBuildThumbnail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wide.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4:1 image: with 8×16 cells it fills 40 columns and 5 rows.
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 400, 100))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	lines, ok := BuildThumbnail(path, 40, 20, DefaultCellSize, ThumbBlocks, "truecolor")
+	if !ok || len(lines) != 5 {
+		t.Fatalf("ok %v, %d lines; want 5", ok, len(lines))
+	}
+	if _, ok := BuildThumbnail(filepath.Join(t.TempDir(), "none.png"), 40, 20, DefaultCellSize, ThumbBlocks, "truecolor"); ok {
+		t.Fatalf("thumbnail of a missing file")
+	}
+}
@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+)
+
+// Limits of the copies of archive members made for previews.
+const (
+	memberPreviewBytes   = 32 << 20
+	memberPreviewTimeout = 10 * time.Second
+	// membersKept is the number of copies kept; older ones are deleted.
+	membersKept = 16
+)
+
+// errExtracting is shown in the preview while a member is being copied.
+var errExtracting = errors.New("extracting…")
+
+// member is an archive member copied for previews. Failures are kept too,
+// so that a member that cannot be read is not tried on every frame.
+type member struct {
+	file string // the copy, once done without error
+	err  error
+	done bool
+}
+
+// memberMsg reports the end of the copy of the member at path.
+type memberMsg struct {
+	path string
+	file string
+	err  error
+}
+
+// previewSource returns the file to preview for path: path itself, or for
+// a member of an archive browsed as a directory a temporary copy of it
+// (see memberFile).
+func (m *model) previewSource(path string) (string, error) {
+	if !archive.IsVirtual(path) {
+		return path, nil
+	}
+	return m.memberFile(path)
+}
+
+// memberFile returns the temporary copy of the archive member at path.
+// The first call starts copying it in the background and reports
+// errExtracting until the copy is done. The copy keeps the member's name so
+// that previews recognize its type.
+func (m *model) memberFile(path string) (string, error) {
+	if mb, ok := m.members[path]; ok {
+		if !mb.done {
+			return "", errExtracting
+		}
+		return mb.file, mb.err
+	}
+	if m.memberDir == "" {
+		dir, err := os.MkdirTemp("", "tfm-archive-")
+		if err != nil {
+			return "", err
+		}
+		m.memberDir = dir
+	}
+	if m.members == nil {
+		m.members = make(map[string]*member)
+	}
+	m.members[path] = &member{}
+	m.memberOrder = append(m.memberOrder, path)
+	m.pruneMembers()
+	root := m.memberDir
+	m.queue(func() tea.Msg {
+		file, err := extractMember(root, path)
+		return memberMsg{path: path, file: file, err: err}
+	})
+	return "", errExtracting
+}
+
+// extractMember copies the archive member at path to a new directory in
+// root, within memberPreviewBytes and memberPreviewTimeout.
+func extractMember(root, path string) (string, error) {
+	file, name, _ := archive.Split(path)
+	dir, err := os.MkdirTemp(root, "")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(filepath.Join(dir, filepath.Base(path)))
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), memberPreviewTimeout)
+	defer cancel()
+	err = archive.ReadMember(ctx, file, name, f, memberPreviewBytes)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%s: not reached in %s", name, memberPreviewTimeout)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// onMember records a finished copy. A copy whose member was evicted
+// meanwhile is deleted.
+func (m *model) onMember(msg memberMsg) {
+	mb, ok := m.members[msg.path]
+	if !ok {
+		if msg.file != "" {
+			os.RemoveAll(filepath.Dir(msg.file))
+		}
+		return
+	}
+	mb.file, mb.err, mb.done = msg.file, msg.err, true
+}
+
+// pruneMembers deletes the oldest copies beyond membersKept.
+func (m *model) pruneMembers() {
+	for len(m.memberOrder) > membersKept {
+		old := m.memberOrder[0]
+		m.memberOrder = m.memberOrder[1:]
+		if mb := m.members[old]; mb != nil && mb.file != "" {
+			os.RemoveAll(filepath.Dir(mb.file))
+		}
+		delete(m.members, old)
+	}
+}
+
+// removeMembers deletes the members extracted for previews.
+func (m *model) removeMembers() {
+	if m.memberDir != "" {
+		os.RemoveAll(m.memberDir)
+		m.memberDir, m.members, m.memberOrder = "", nil, nil
+	}
+}
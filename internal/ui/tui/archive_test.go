@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ops"
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// writeTestZip writes an archive with docs/readme.txt and top.txt.
+func writeTestZip(t *testing.T, zipPath string) {
+	t.Helper()
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"docs/readme.txt", "top.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte("contents of " + name))
+	}
+	zw.Close()
+	f.Close()
+}
+
+func TestArchivePreviewInBackground(t *testing.T) {
+	m, root := newHistoryModel(t)
+	zipPath := filepath.Join(root, "data.zip")
+	writeTestZip(t, zipPath)
+	m.prevProv = preview.ArchiveProvider{Next: preview.BasicProvider{}}
+	m.fileCache = uicache.NewFileCache(8, 1<<20)
+
+	st := m.previewFor(zipPath)
+	if got := m.previewLines(st, "text"); got.Len() != 1 || got.Line(0) != "loading…" {
+		t.Fatalf("before listing: %d lines", got.Len())
+	}
+	if len(m.pending) != 1 {
+		t.Fatalf("listing not queued once: %d commands", len(m.pending))
+	}
+	m.providerResult(zipPath)
+	if len(m.pending) != 1 {
+		t.Fatalf("listing queued twice")
+	}
+	drain(m)
+	if res := m.providerResult(zipPath); res.Kind != "archive" {
+		t.Fatalf("after listing: kind %q", res.Kind)
+	}
+	if got := m.previewLines(st, "text"); got.Len() < 3 {
+		t.Fatalf("listing not shown: %d lines", got.Len())
+	}
+}
+
+func TestBrowseArchive(t *testing.T) {
+	m, root := newHistoryModel(t)
+	zipPath := filepath.Join(root, "data.zip")
+	writeTestZip(t, zipPath)
+	m.reloadTab(m.current())
+	drain(m)
+	defer m.removeMembers()
+
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "data.zip"))
+	m.enter()
+	drain(m)
+	p := m.current().panel
+	if p.Cwd != zipPath || len(p.Entries) != 2 || p.Entries[0].Name != "docs" || !p.Entries[0].IsDir {
+		t.Fatalf("inside archive: cwd %q, entries %+v", p.Cwd, p.Entries)
+	}
+	m.setSelected(0)
+	m.enter()
+	drain(m)
+	if p.Cwd != filepath.Join(zipPath, "docs") || len(p.Entries) != 1 {
+		t.Fatalf("archive directory: cwd %q, entries %+v", p.Cwd, p.Entries)
+	}
+
+	readme := filepath.Join(p.Cwd, "readme.txt")
+	if _, err := m.previewSource(readme); err != errExtracting {
+		t.Fatalf("before the copy: %v", err)
+	}
+	drain(m)
+	src, err := m.previewSource(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(src); string(b) != "contents of docs/readme.txt" || filepath.Base(src) != "readme.txt" {
+		t.Fatalf("preview copy %s: %q", src, b)
+	}
+
+	// Members are copied out; nothing is pasted into the archive.
+	m.copySelectedFile()
+	if msg := m.pasteFiles()(); !errors.Is(msg.(extRunDoneMsg).err, ops.ErrReadOnly) {
+		t.Fatalf("paste into archive: %v", msg)
+	}
+
+	m.up()
+	drain(m)
+	m.up()
+	drain(m)
+	if p.Cwd != root || selectedName(m.current()) != "data.zip" {
+		t.Fatalf("after leaving: cwd %q, selected %q", p.Cwd, selectedName(m.current()))
+	}
+	if msg := m.pasteFiles()(); msg.(extRunDoneMsg).err != nil {
+		t.Fatal(msg.(extRunDoneMsg).err)
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "readme.txt")); err != nil || string(b) != "contents of docs/readme.txt" {
+		t.Fatalf("copied member: %q, %v", b, err)
+	}
+}
+
+func TestMemberCopiesAreBounded(t *testing.T) {
+	m, root := newHistoryModel(t)
+	zipPath := filepath.Join(root, "many.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for i := 0; i <= membersKept; i++ {
+		w, _ := zw.Create(fmt.Sprintf("f%02d.txt", i))
+		w.Write([]byte("x"))
+	}
+	zw.Close()
+	f.Close()
+	defer m.removeMembers()
+
+	// A member that cannot be read fails once and is not tried again.
+	missing := filepath.Join(zipPath, "missing.txt")
+	m.previewSource(missing)
+	drain(m)
+	if _, err := m.previewSource(missing); err == nil || err == errExtracting {
+		t.Fatalf("missing member: %v", err)
+	}
+	if len(m.pending) != 0 {
+		t.Fatal("failed member extracted again")
+	}
+
+	var first string
+	for i := 0; i <= membersKept; i++ {
+		path := filepath.Join(zipPath, fmt.Sprintf("f%02d.txt", i))
+		m.previewSource(path)
+		drain(m)
+		src, err := m.previewSource(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = src
+		}
+	}
+	if len(m.members) != membersKept {
+		t.Fatalf("%d copies kept", len(m.members))
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Fatalf("oldest copy not deleted: %v", err)
+	}
+}
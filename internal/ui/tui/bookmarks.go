@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/bookmarks"
+)
+
+// bookmarkItem is a bookmark as listed in the :bookmarks modal.
+type bookmarkItem struct {
+	bookmarks.Bookmark
+	config bool // declared in config.toml (read-only)
+}
+
+// bookmarkList returns config bookmarks (sorted by name) followed by marks.
+func (m *model) bookmarkList() []bookmarkItem {
+	var out []bookmarkItem
+	if m.deps.Config != nil {
+		names := make([]string, 0, len(m.deps.Config.Bookmarks))
+		for name := range m.deps.Config.Bookmarks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			bm := bookmarks.Bookmark{Name: name, Path: expandPath(m.deps.Config.Bookmarks[name], "")}
+			if bookmarks.ValidKey(name) {
+				bm.Key = name
+			}
+			out = append(out, bookmarkItem{Bookmark: bm, config: true})
+		}
+	}
+	if m.deps.Bookmarks != nil {
+		for _, bm := range m.deps.Bookmarks.All() {
+			out = append(out, bookmarkItem{Bookmark: bm})
+		}
+	}
+	return out
+}
+
+// setMark binds key to the focused directory and persists the marks.
+func (m *model) setMark(key string) {
+	if m.deps.Bookmarks == nil {
+		return
+	}
+	t := m.focused()
+	if t == nil || t.panel == nil {
+		return
+	}
+	if err := m.deps.Bookmarks.Set(key, t.panel.Cwd); err != nil {
+		m.setError(err)
+		return
+	}
+	if err := m.deps.Bookmarks.Save(); err != nil {
+		m.setError(fmt.Errorf("save bookmarks: %w", err))
+		return
+	}
+	m.err = nil
+}
+
+// jumpMark navigates to the location bound to key. Marks set with m<key>
+// take precedence over single-letter config bookmarks.
+func (m *model) jumpMark(key string) tea.Cmd {
+	if m.deps.Bookmarks != nil {
+		if bm, ok := m.deps.Bookmarks.Get(key); ok {
+			return m.jumpTo(bm.Path)
+		}
+	}
+	for _, it := range m.bookmarkList() {
+		if it.config && it.Key == key {
+			return m.jumpTo(it.Path)
+		}
+	}
+	m.setError(fmt.Errorf("mark not set: %s", key))
+	return nil
+}
+
+// jumpTo opens path in the focused panel: directories are entered, files are
+// selected in their parent directory.
+func (m *model) jumpTo(path string) tea.Cmd {
+	fi, err := os.Stat(path)
+	if err != nil {
+		m.setError(err)
+		return nil
+	}
+	dir, sel := path, ""
+	if !fi.IsDir() {
+		dir, sel = filepath.Dir(path), filepath.Base(path)
+	}
+	m.err = nil
+	m.navigate(m.focused(), dir, sel)
+	return m.maybePrefetchSelected()
+}
+
+// showBookmarks opens the bookmark manager picker.
+func (m *model) showBookmarks(sel int) {
+	items := m.bookmarkList()
+	lines := make([]string, 0, len(items))
+	for _, it := range items {
+		key := " "
+		if it.Key != "" {
+			key = it.Key
+		}
+		var flags []string
+		if it.config {
+			flags = append(flags, "config")
+		}
+		if _, err := os.Stat(it.Path); err != nil {
+			flags = append(flags, "missing")
+		}
+		ln := fmt.Sprintf("[%s] %-20s %s", key, it.Name, it.Path)
+		if len(flags) > 0 {
+			ln += "  (" + strings.Join(flags, ", ") + ")"
+		}
+		lines = append(lines, ln)
+	}
+	if len(lines) == 0 {
+		lines = []string{"no bookmarks — set one with m<letter>"}
+	}
+	m.openPicker("bookmarks", "Bookmarks (Enter: jump, r: rename, d: delete, Esc: close)", lines, sel)
+}
+
+// onBookmarksKey handles picker keys beyond navigation in the bookmark manager.
+func (m *model) onBookmarksKey(key string) tea.Cmd {
+	items := m.bookmarkList()
+	if m.modalSel < 0 || m.modalSel >= len(items) {
+		return nil
+	}
+	it := items[m.modalSel]
+	switch key {
+	case "d", "x", "delete":
+		if it.config {
+			m.setError(fmt.Errorf("bookmark %q is defined in config", it.Name))
+			return nil
+		}
+		if err := m.deps.Bookmarks.Delete(it.Key); err != nil {
+			m.setError(err)
+			return nil
+		}
+		if err := m.deps.Bookmarks.Save(); err != nil {
+			m.setError(fmt.Errorf("save bookmarks: %w", err))
+		}
+		m.showBookmarks(m.modalSel)
+	case "r":
+		if it.config {
+			m.setError(fmt.Errorf("bookmark %q is defined in config", it.Name))
+			return nil
+		}
+		m.closeModal()
+		m.openPrompt("rename-bookmark", it.Key, fmt.Sprintf("Rename [%s]: ", it.Key), it.Name)
+	}
+	return nil
+}
+
+// expandPath expands a leading ~ and resolves relative paths against base.
+func expandPath(path, base string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,123 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/bookmarks"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/keymap"
+)
+
+func newBookmarksModel(t *testing.T) (*model, string, string) {
+	t.Helper()
+	m, root := newHistoryModel(t)
+	m.deps.Config = config.Default()
+	m.deps.Keymap = keymap.Default()
+	path := filepath.Join(t.TempDir(), "bookmarks")
+	m.deps.Bookmarks = bookmarks.New(path)
+	return m, root, path
+}
+
+func keyRunes(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+func TestSetAndJumpMarkViaKeys(t *testing.T) {
+	m, root, path := newBookmarksModel(t)
+	m.setSelected(1)
+	m.enter() // into "b"
+	drain(m)
+	m.onKey(keyRunes("m"))
+	drain(m)
+	m.onKey(keyRunes("x"))
+	drain(m)
+	if bm, ok := m.deps.Bookmarks.Get("x"); !ok || bm.Path != filepath.Join(root, "b") {
+		t.Fatalf("mark x = %#v, %v", bm, ok)
+	}
+	// Persisted immediately
+	if s, err := bookmarks.Load(path); err != nil || len(s.All()) != 1 {
+		t.Fatalf("mark not persisted: %v", err)
+	}
+	m.up()
+	drain(m)
+	m.onKey(keyRunes("'"))
+	drain(m)
+	m.onKey(keyRunes("x"))
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "b") {
+		t.Fatalf("cwd after jump = %q", got)
+	}
+	m.onKey(keyRunes("'"))
+	drain(m)
+	m.onKey(keyRunes("q"))
+	drain(m)
+	if m.err == nil || !strings.Contains(m.err.Error(), "mark not set") {
+		t.Fatalf("expected 'mark not set' error, got %v", m.err)
+	}
+}
+
+func TestBookmarkManagerListsConfigAndFlagsMissing(t *testing.T) {
+	m, root, _ := newBookmarksModel(t)
+	m.deps.Config.Bookmarks = map[string]string{"A": filepath.Join(root, "a")}
+	_ = m.deps.Bookmarks.Set("z", filepath.Join(root, "gone"))
+	m.showBookmarks(0)
+	if len(m.modalLines) != 2 {
+		t.Fatalf("lines = %v", m.modalLines)
+	}
+	if !strings.Contains(m.modalLines[0], "(config)") {
+		t.Fatalf("config bookmark not flagged: %q", m.modalLines[0])
+	}
+	if !strings.Contains(m.modalLines[1], "missing") {
+		t.Fatalf("missing path not flagged: %q", m.modalLines[1])
+	}
+	// Deleting a config bookmark is refused
+	m.onModalKey(keyRunes("d"))
+	drain(m)
+	if m.err == nil {
+		t.Fatalf("expected error deleting config bookmark")
+	}
+	// Delete the mark
+	m.onModalKey(keyRunes("j"))
+	drain(m)
+	m.onModalKey(keyRunes("d"))
+	drain(m)
+	if _, ok := m.deps.Bookmarks.Get("z"); ok {
+		t.Fatalf("mark z should be deleted")
+	}
+	// Jump to the config bookmark with Enter
+	m.setModalSel(0)
+	m.onModalKey(tea.KeyMsg{Type: tea.KeyEnter})
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "a") {
+		t.Fatalf("cwd after pick = %q", got)
+	}
+	// Single-letter config bookmarks work as marks
+	m.up()
+	drain(m)
+	m.jumpMark("A")
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "a") {
+		t.Fatalf("cwd after 'A = %q", got)
+	}
+}
+
+func TestRenameBookmarkViaPrompt(t *testing.T) {
+	m, root, _ := newBookmarksModel(t)
+	_ = m.deps.Bookmarks.Set("a", root)
+	m.showBookmarks(0)
+	m.onModalKey(keyRunes("r"))
+	drain(m)
+	if !m.cmdActive || m.promptKind != "rename-bookmark" {
+		t.Fatalf("rename prompt not opened")
+	}
+	m.cmdBuf = []rune("home")
+	m.onCmdKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if bm, _ := m.deps.Bookmarks.Get("a"); bm.Name != "home" {
+		t.Fatalf("name = %q; want home", bm.Name)
+	}
+	if !m.modalActive || m.modalKind != "bookmarks" {
+		t.Fatalf("bookmark manager should reopen after rename")
+	}
+}
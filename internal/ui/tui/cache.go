@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"fmt"
+)
+
+// cacheCommand implements :cache stats|clear.
+func (m *model) cacheCommand(args []string) {
+	sub := "stats"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "stats":
+		m.showCacheStats()
+	case "clear":
+		m.dirCache.Clear()
+		m.fileCache.Clear()
+		m.imgCache.Clear()
+		if d := m.fileCache.Disk(); d != nil {
+			if err := d.Clear(); err != nil {
+				m.setError(fmt.Errorf("clear preview cache: %w", err))
+				return
+			}
+		}
+		m.err = nil
+	default:
+		m.setError(fmt.Errorf("usage: :cache stats|clear"))
+	}
+}
+
+// showCacheStats lists the size of the in-memory caches and the disk tier.
+func (m *model) showCacheStats() {
+	lines := []string{
+		fmt.Sprintf("directories (memory): %d", m.dirCache.Len()),
+		fmt.Sprintf("previews (memory):    %d, %s", m.fileCache.Len(), humanBytes(m.fileCache.Bytes())),
+		fmt.Sprintf("images (memory):      %d, %s", m.imgCache.Len(), humanBytes(m.imgCache.Bytes())),
+	}
+	if d := m.fileCache.Disk(); d != nil {
+		st := d.Stats()
+		lines = append(lines,
+			fmt.Sprintf("previews (disk):      %d, %s of %s", st.Entries, humanBytes(st.Bytes), humanBytes(st.Limit)),
+			fmt.Sprintf("disk hits/misses:     %d/%d", st.Hits, st.Misses),
+			"location:             "+st.Dir,
+		)
+	} else {
+		lines = append(lines, "previews (disk):      off ([cache] disk = true)")
+	}
+	m.modalTitle = "Cache"
+	m.modalLines = lines
+	m.modalActive = true
+}
+
+// humanBytes formats n with a binary unit suffix.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
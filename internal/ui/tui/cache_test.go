@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+func TestCacheCommandStatsAndClear(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.fileCache = uicache.NewFileCache(8, 0)
+	d, err := uicache.OpenDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.fileCache.SetDisk(d)
+	img := filepath.Join(root, "img.png")
+	if err := os.WriteFile(img, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.fileCache.Put(img, 0, preview.Result{Kind: "image", Content: "@@"})
+	m.dirCache.Put(root, nil)
+
+	m.execCommand(":cache stats")
+	if !m.modalActive || !strings.Contains(strings.Join(m.modalLines, "\n"), "previews (disk):      1") {
+		t.Fatalf("stats = %v", m.modalLines)
+	}
+	m.modalActive = false
+	m.execCommand(":cache clear")
+	if m.err != nil {
+		t.Fatalf("clear: %v", m.err)
+	}
+	if m.fileCache.Len() != 0 || m.dirCache.Len() != 0 || d.Stats().Entries != 0 {
+		t.Fatalf("caches not cleared: mem=%d dirs=%d disk=%+v", m.fileCache.Len(), m.dirCache.Len(), d.Stats())
+	}
+}
+
+func TestDiskTierKeepsHighlightsAndImages(t *testing.T) {
+	t.Setenv("TFM_INLINE", "off")
+	d, err := uicache.OpenDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := func() (*model, string) {
+		m, pic := newImageModel(t)
+		m.fileCache.SetDisk(d)
+		m.imgCache.SetDisk(d)
+		return m, pic
+	}
+	m, pic := session()
+	src := filepath.Join(filepath.Dir(pic), "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\n/* a\nb */\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.renderFilePreviewBody(src, 20, 3)
+	want := m.renderFilePreviewBody(src, 20, 3)
+	m.renderFilePreviewBody(pic, 20, 10) // leaving the file saves its lines
+	drain(m)
+	m.renderFilePreviewBody(pic, 20, 10)
+
+	// The next session reads both from disk.
+	m, _ = session()
+	st := m.previewFor(src)
+	m.previewLines(st, "text")
+	if st.saved != 3 || len(st.spans) != 3 {
+		t.Fatalf("highlighted lines read from disk: %d", len(st.spans))
+	}
+	if got := m.renderFilePreviewBody(src, 20, 3); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("cached lines differ:\n%q\n%q", got, want)
+	}
+	// Lines past the cached ones continue the comment state.
+	st.scroll = 3
+	if got := st.lineSpans(3); got[0].Class != preview.Comment {
+		t.Fatalf("lexer state not restored: %+v", got)
+	}
+	if out := m.renderFilePreviewBody(pic, 20, 10); len(m.pending) != 0 || !strings.Contains(out[0], "▀") {
+		t.Fatalf("image not read from disk: %q", out)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512B", 2048: "2.0KiB", 3 << 20: "3.0MiB"} {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%d) = %q; want %q", n, got, want)
+		}
+	}
+}
@@ -19,11 +19,18 @@ func (m *model) onCmdKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.Type {
 	case tea.KeyEsc:
 		m.cmdActive = false
+		m.cmdBuf = nil
+		m.promptKind = ""
 		return nil
 	case tea.KeyEnter:
 		cmd := strings.TrimSpace(string(m.cmdBuf))
 		m.cmdActive = false
 		m.cmdBuf = nil
+		if m.promptKind != "" {
+			kind, arg := m.promptKind, m.promptArg
+			m.promptKind = ""
+			return m.onPrompt(kind, arg, cmd)
+		}
 		return m.execCommand(cmd)
 	case tea.KeyBackspace, tea.KeyCtrlH:
 		if len(m.cmdBuf) > 0 {
@@ -93,6 +100,17 @@ func (m *model) execCommand(s string) tea.Cmd {
 	case "cd":
 		if len(args) == 0 {
 			m.setError(fmt.Errorf("usage: :cd <path>"))
+			return nil
+		}
+		path := expandPath(args[0], m.focused().panel.Cwd)
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			// change directory in focused panel
+			m.err = nil
+			m.navigate(m.focused(), path, "")
+		} else if err != nil {
+			m.setError(err)
+		} else {
+			m.setError(fmt.Errorf("not a directory: %s", path))
 		}
 		return nil
 	case "copy":
@@ -105,42 +123,57 @@ func (m *model) execCommand(s string) tea.Cmd {
 		return nil
 	case "paste-path":
 		return m.pastePath()
-		path := args[0]
-		if strings.HasPrefix(path, "~") {
-			if home, err := os.UserHomeDir(); err == nil {
-				path = filepath.Join(home, strings.TrimPrefix(path, "~"))
-			}
-		}
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(m.focused().panel.Cwd, path)
-		}
-		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
-			// change directory in focused panel
-			t := m.focused()
-			if err := t.panel.Chdir(path); err != nil {
-				m.setError(err)
-			} else {
-				m.err = nil
-				t.selected = 0
-				t.scroll = 0
-			}
-		} else if err != nil {
-			m.setError(err)
-		} else {
-			m.setError(fmt.Errorf("not a directory: %s", path))
-		}
+	case "history", "hist":
+		m.showHistory()
+		return nil
+	case "z":
+		return m.zJump(args)
+	case "zi":
+		m.showFrecency(args)
+		return nil
+	case "zimport":
+		m.importFrecency()
+		return nil
+	case "bookmarks", "marks", "bm":
+		m.showBookmarks(0)
+		return nil
+	case "cache":
+		m.cacheCommand(args)
+		return nil
+	case "tree":
+		m.treeCommand(args)
+		return nil
+	case "flatten", "flatten!", "flat", "flat!":
+		m.flattenCommand(strings.HasSuffix(name, "!"), args)
+		return nil
+	case "sort":
+		m.sortCommand(args)
+		return nil
+	case "layout":
+		m.layoutCommand(args)
+		return nil
+	case "tab", "tabname", "tabmove", "tabdup":
+		m.tabCommand(name, args)
+		return nil
+	case "session":
+		m.sessionCommand(args)
+		return nil
+	case "back":
+		m.historyStep(-1)
+		return nil
+	case "forward":
+		m.historyStep(1)
 		return nil
 	case "preview":
-		if len(args) == 0 || args[0] == "toggle" {
-			m.togglePreview()
-		} else if args[0] == "on" {
-			if !m.showPrev {
-				m.togglePreview()
-			}
-		} else if args[0] == "off" {
-			if m.showPrev {
-				m.togglePreview()
-			}
+		m.previewCommand(args)
+		return nil
+	case "compress":
+		return m.compressCommand(args)
+	case "extract":
+		return m.extractCommand(args)
+	case "cancel":
+		if !m.cancelJob() {
+			m.setError(fmt.Errorf("nothing to cancel"))
 		}
 		return nil
 	case "opacity":
@@ -280,10 +313,40 @@ func (m *model) showHelp() {
 		":q | :quit | :exit    — выйти",
 		":cd <path>            — перейти в каталог",
 		":preview on|off|toggle — управлять панелью предпросмотра",
+		":preview text|hex|info|auto — вид предпросмотра файла (клавиша v — следующий)",
+		"J / K                 — прокрутить предпросмотр вниз / вверх",
+		"i                     — перейти в предпросмотр: j/k, ctrl+d/u, gg/G, / поиск, n/N, h/Esc — назад",
+		":preview wrap         — перенос длинных строк в предпросмотре (клавиша W)",
 		":copy                 — скопировать выделенный файл/папку (в буфер TFM)",
 		":paste                — вставить в текущий каталог",
 		":copy-path            — скопировать полный путь выделенного (в буфер TFM)",
 		":paste-path           — перейти по скопанному пути (cd/выделить файл)",
+		":history              — история каталогов вкладки (Enter — перейти)",
+		":back | :forward      — назад/вперёд по истории (клавиши H/L)",
+		":bookmarks            — закладки: Enter — перейти, r — переименовать, d — удалить",
+		"m<буква> / '<буква>   — поставить метку / перейти к метке",
+		":z <запрос>           — перейти в самый «частый и свежий» каталог (как zoxide)",
+		":zi [запрос]          — выбрать каталог из списка по частоте",
+		":zimport              — импортировать базы zoxide/autojump",
+		":cache stats|clear    — статистика / очистка кэшей (память и диск)",
+		":tree [on|off]        — дерево каталогов в левой панели (клавиша T)",
+		":tree expand [N]      — раскрыть дерево на N уровней (zR), :tree collapse (zM)",
+		"zo / zc / za          — раскрыть / свернуть / переключить узел дерева",
+		":flatten [N]          — все файлы подкаталогов одним списком (до N уровней), h — выйти",
+		":flatten! [N]         — то же, включая файлы из .gitignore; :flatten off — выйти",
+		":sort name|size|mtime|ext [desc] — сортировка списка",
+		":layout dual|miller   — две панели в стиле Midnight Commander / колонки (ctrl+t)",
+		"tab / S / =           — другая панель / поменять панели местами / открыть тот же каталог",
+		"F5 / F6               — копировать / переместить в каталог другой панели",
+		":tabname [имя]        — назвать вкладку (без имени — по каталогу)",
+		":tab N | gt<N>        — перейти на вкладку N; gt / gT — следующая / предыдущая",
+		":tabmove +N|-N|N      — переместить вкладку ({ / } — влево / вправо)",
+		":tabdup               — дублировать вкладку (D)",
+		":session save|load [имя] — сохранить / открыть сессию (вкладки, каталоги, раскладку)",
+		":session list         — список сохранённых сессий",
+		":compress <имя>       — упаковать выделенное в архив (.zip, .tar.gz, .tar.xz, .tar.zst)",
+		":extract [каталог]    — распаковать выделенный архив (по умолчанию — в каталог с его именем)",
+		":cancel | Esc         — остановить упаковку / распаковку",
 		"",
 		"Кастомные команды [commands] в config.toml:",
 		"  name = \"shell snippet\"",
@@ -317,6 +380,34 @@ func runShell(cmd string) (string, error) {
 // Suitable for editors/pagers like nano, vim, less.
 // runInteractive was replaced by tea.ExecProcess; kept here previously, now removed.
 
+// openPrompt starts free-form input on the command line. On Enter the
+// entered text is passed to onPrompt together with kind and arg.
+func (m *model) openPrompt(kind, arg, label, initial string) {
+	m.promptKind = kind
+	m.promptArg = arg
+	m.promptLabel = label
+	m.cmdBuf = []rune(initial)
+	m.cmdActive = true
+}
+
+// onPrompt completes a prompt started with openPrompt.
+func (m *model) onPrompt(kind, arg, value string) tea.Cmd {
+	switch kind {
+	case "copy-to", "move-to":
+		return m.runTransfer(strings.TrimSuffix(kind, "-to"), arg, value)
+	case "preview-search":
+		m.setPreviewQuery(value)
+	case "rename-bookmark":
+		if err := m.deps.Bookmarks.Rename(arg, value); err != nil {
+			m.setError(err)
+		} else if err := m.deps.Bookmarks.Save(); err != nil {
+			m.setError(fmt.Errorf("save bookmarks: %w", err))
+		}
+		m.showBookmarks(0)
+	}
+	return nil
+}
+
 func (m *model) setError(err error) {
 	m.err = err
 }
@@ -0,0 +1,203 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+// The dual layout shows two equally sized, independent panels in the style
+// of Norton/Midnight Commander. The left panel is the active tab, the right
+// one lives in rightT; focus "right" selects it. Each panel keeps its own
+// scroll offset in tab.scroll, and vp.YOffset mirrors the focused one.
+
+// setLayout switches between Miller columns and the dual-pane layout.
+func (m *model) setLayout(dual bool) {
+	if dual == m.dual {
+		return
+	}
+	if !dual {
+		m.cancelLoad(&m.rightT)
+		m.dual = false
+		m.closeRight()
+		m.vp.YOffset = m.current().scroll
+		return
+	}
+	// The right panel starts where the last Miller column was, or next to
+	// the left one.
+	dir := m.current().panel.Cwd
+	if n := len(m.rightCols); n > 0 {
+		dir = m.rightCols[n-1].panel.Cwd
+	}
+	m.closeRight()
+	m.current().scroll = m.vp.YOffset
+	m.dual = true
+	m.rightMode = "panel"
+	m.openDualPane(dir)
+	m.focus = "left"
+}
+
+// openDualPane sets up the right panel of the dual layout at dir.
+func (m *model) openDualPane(dir string) {
+	m.rightT = tab{panel: panels.NewPanel(dir, m.current().panel.ShowHidden)}
+	t := &m.rightT
+	t.hist.visit(dir)
+	if entries := m.dirCache.Get(dir); entries != nil {
+		t.panel.Entries = entries
+		t.panel.MaxDirName = computeMaxDirName(entries)
+		m.restoreCursor(t)
+		return
+	}
+	m.startLoad(t, dir, "")
+}
+
+// layoutCommand implements :layout [dual|miller|toggle].
+func (m *model) layoutCommand(args []string) {
+	sub := "toggle"
+	if len(args) > 0 {
+		sub = strings.ToLower(args[0])
+	}
+	switch sub {
+	case "toggle":
+		m.setLayout(!m.dual)
+	case "dual", "commander", "mc":
+		m.setLayout(true)
+	case "miller", "columns":
+		m.setLayout(false)
+	default:
+		m.setError(fmt.Errorf("usage: :layout dual|miller|toggle"))
+		return
+	}
+	m.ensureVisible()
+}
+
+// paneTab returns the panel that pane-level commands (tree, flatten) act on:
+// the focused panel in the dual layout, the active tab otherwise.
+func (m *model) paneTab() *tab {
+	if m.dual {
+		return m.focused()
+	}
+	return m.current()
+}
+
+// otherPane returns the panel without focus in the dual layout, or nil.
+func (m *model) otherPane() *tab {
+	if !m.dual {
+		return nil
+	}
+	if m.focus == "right" {
+		return m.current()
+	}
+	return &m.rightT
+}
+
+// setFocus moves the focus to side ("left" or "right"), swapping the
+// viewport offset between the panels.
+func (m *model) setFocus(side string) {
+	if !m.dual && side == "right" && !(m.rightMode == "panel" && m.rightT.panel != nil) {
+		side = "left"
+	}
+	if side == m.focus {
+		return
+	}
+	m.focused().scroll = m.vp.YOffset
+	m.focus = side
+	m.vp.YOffset = m.focused().scroll
+	m.ensureVisible()
+}
+
+// swapPanes exchanges the directories (and all state) of the two panels.
+func (m *model) swapPanes() {
+	if !m.dual {
+		return
+	}
+	m.focused().scroll = m.vp.YOffset
+	l := m.current()
+	*l, m.rightT = m.rightT, *l
+	m.vp.YOffset = m.focused().scroll
+	m.ensureVisible()
+}
+
+// syncPanes opens the focused panel's directory in the other panel.
+func (m *model) syncPanes() {
+	o := m.otherPane()
+	if o == nil {
+		return
+	}
+	ft := m.focused()
+	m.navigate(o, ft.panel.Cwd, selectedName(ft))
+}
+
+// transferToOther asks for the destination of a copy ("copy") or move
+// ("move") of the focused entry, defaulting to the other panel's directory
+// (the focused one outside the dual layout).
+func (m *model) transferToOther(op string) {
+	t := m.focused()
+	if t == nil || t.panel == nil || len(t.panel.Entries) == 0 {
+		return
+	}
+	src := t.panel.Join(t.panel.Entries[t.selected].Name)
+	dest := t.panel.Cwd
+	if o := m.otherPane(); o != nil {
+		dest = o.panel.Cwd
+	}
+	label := "Copy"
+	if op == "move" {
+		label = "Move"
+	}
+	m.openPrompt(op+"-to", src, fmt.Sprintf("%s %s to: ", label, filepath.Base(src)), dest+string(filepath.Separator))
+}
+
+// runTransfer copies or moves src to dest in the background. A destination
+// that is an existing directory receives src under its own name.
+func (m *model) runTransfer(op, src, dest string) tea.Cmd {
+	base := m.focused().panel.Cwd
+	dest = expandPath(strings.TrimSpace(dest), base)
+	if fi, err := os.Stat(dest); err == nil && fi.IsDir() {
+		if op == "move" && dest == filepath.Dir(src) {
+			return nil
+		}
+		dest = uniqueDestPath(dest, filepath.Base(src))
+	}
+	if dest == src {
+		return nil
+	}
+	fs := m.deps.FS
+	return func() tea.Msg {
+		ctx := context.Background()
+		var err error
+		if op == "move" {
+			err = fs.Move(ctx, src, dest)
+		} else {
+			err = fs.Copy(ctx, src, dest)
+		}
+		return extRunDoneMsg{err: err}
+	}
+}
+
+// renderDual renders both panels side by side, each from its own offset.
+// The window handed to the viewport starts at the focused panel's offset.
+func (m *model) renderDual(totalW int) {
+	leftW := (totalW - 1) / 2
+	if leftW < 10 {
+		leftW = 10
+	}
+	rightW := totalW - 1 - leftW
+	if rightW < 10 {
+		rightW = 10
+	}
+	vh := m.viewportHeight()
+	m.focused().scroll = m.vp.YOffset
+	l, r := m.current(), &m.rightT
+	cols := [][]string{
+		renderPanelRows(m, *l, leftW, m.focus == "left", l.scroll, l.scroll+vh),
+		renderPanelRows(m, *r, rightW, m.focus == "right", r.scroll, r.scroll+vh),
+	}
+	m.setWindowContent(mergeColumns(cols, []int{leftW, rightW}, "│"), m.vp.YOffset)
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDualLayoutIndependentPanels(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.width = 60
+	m.execCommand(":layout dual")
+	drain(m)
+	if !m.dual || m.rightT.panel == nil || m.rightT.panel.Cwd != root {
+		t.Fatalf("dual layout not set up: dual=%v", m.dual)
+	}
+	// tab moves to the right panel; entering a directory there leaves the
+	// left one alone.
+	m.doAction("toggle-focus")
+	m.setSelected(indexOfEntry(m.focused().panel.Entries, "b"))
+	m.enter()
+	drain(m)
+	if got := m.rightT.panel.Cwd; got != filepath.Join(root, "b") {
+		t.Fatalf("right cwd = %q", got)
+	}
+	if got := m.current().panel.Cwd; got != root {
+		t.Fatalf("left cwd changed to %q", got)
+	}
+	m.refreshContent()
+	if view := m.windowView(); !strings.Contains(view, "│") || !strings.Contains(view, "a/") {
+		t.Fatalf("dual view:\n%s", view)
+	}
+	if h := headerContent(m); !strings.Contains(h, "* "+filepath.Join(root, "b")) {
+		t.Fatalf("header does not mark the focused panel: %q", h)
+	}
+
+	m.swapPanes()
+	if m.current().panel.Cwd != filepath.Join(root, "b") || m.rightT.panel.Cwd != root {
+		t.Fatalf("swap: left=%s right=%s", m.current().panel.Cwd, m.rightT.panel.Cwd)
+	}
+	// Sync opens the focused (right) directory on the left.
+	m.syncPanes()
+	drain(m)
+	if m.current().panel.Cwd != root {
+		t.Fatalf("sync: left=%s", m.current().panel.Cwd)
+	}
+
+	m.execCommand(":layout miller")
+	if m.dual || m.rightT.panel != nil || m.focus != "left" {
+		t.Fatalf("leaving dual: dual=%v focus=%s", m.dual, m.focus)
+	}
+}
+
+func TestDualCopyMoveDefaultToOtherPanel(t *testing.T) {
+	m, root := newHistoryModel(t)
+	if err := os.WriteFile(filepath.Join(root, "f.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.reloadTab(m.current())
+	drain(m)
+	m.setLayout(true)
+	drain(m)
+	m.navigate(&m.rightT, filepath.Join(root, "b"), "")
+	drain(m)
+
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "f.txt"))
+	m.transferToOther("copy")
+	if want := filepath.Join(root, "b") + string(filepath.Separator); m.promptKind != "copy-to" || string(m.cmdBuf) != want {
+		t.Fatalf("prompt %q default %q; want %q", m.promptKind, string(m.cmdBuf), want)
+	}
+	if msg := m.onPrompt(m.promptKind, m.promptArg, string(m.cmdBuf))(); msg.(extRunDoneMsg).err != nil {
+		t.Fatal(msg.(extRunDoneMsg).err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b", "f.txt")); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	m.transferToOther("move")
+	if msg := m.onPrompt(m.promptKind, m.promptArg, "a"); msg().(extRunDoneMsg).err != nil {
+		t.Fatal("move failed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "f.txt")); err != nil {
+		t.Fatalf("move to a relative directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "f.txt")); !os.IsNotExist(err) {
+		t.Fatalf("source still present after move: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+// flattenMaxEntries bounds a flattened listing so that :flatten on / or
+// $HOME cannot exhaust memory.
+const flattenMaxEntries = 100000
+
+// flatState is the state of a tab showing its directory flattened: every
+// descendant in one list, named by its path relative to the panel's
+// directory (like tree rows), so file operations work unchanged. The
+// listing is a snapshot; it is not updated by the watcher.
+type flatState struct {
+	depth     int    // levels included (0 = unlimited)
+	all       bool   // include files excluded by .gitignore
+	sortKey   string // order applied after every walk ("" = by name)
+	sortDesc  bool
+	truncated bool   // the walk stopped at flattenMaxEntries
+	selName   string // row to select once the walk completes (restored sessions)
+
+	id     int // sequence of the walk in flight (0 = none)
+	cancel context.CancelFunc
+
+	// plain listing restored when leaving the flattened view
+	prevEntries  []panels.Entry
+	prevMaxDir   int
+	prevSelected int
+	prevHidden   bool
+}
+
+// flattenMsg delivers the result of a background walk.
+type flattenMsg struct {
+	id      int
+	entries []panels.Entry
+	err     error
+}
+
+// flattenCommand implements :flatten [depth|off] and :flatten! [depth],
+// which also lists files excluded by .gitignore.
+func (m *model) flattenCommand(all bool, args []string) {
+	t := m.paneTab()
+	depth := 0
+	if len(args) > 0 {
+		if args[0] == "off" {
+			m.leaveFlatten(t)
+			m.ensureVisible()
+			return
+		}
+		if _, err := fmt.Sscan(args[0], &depth); err != nil || depth < 0 {
+			m.setError(fmt.Errorf("usage: :flatten[!] [depth|off]"))
+			return
+		}
+	}
+	if t.load != nil {
+		m.setError(fmt.Errorf("%s is still loading", t.panel.Cwd))
+		return
+	}
+	if t.tree != nil {
+		m.toggleTree()
+	}
+	if !m.dual {
+		if m.rightMode == "panel" {
+			m.closeRight()
+		}
+		m.focus = "left"
+	}
+	if t.flat == nil {
+		t.flat = &flatState{
+			prevEntries:  t.panel.Entries,
+			prevMaxDir:   t.panel.MaxDirName,
+			prevSelected: t.selected,
+			prevHidden:   t.panel.ShowHidden,
+		}
+	}
+	t.flat.depth, t.flat.all = depth, all
+	m.startFlatten(t)
+}
+
+// startFlatten walks t's directory in the background with the options in
+// t.flat. The current rows stay on screen until the walk completes.
+func (m *model) startFlatten(t *tab) {
+	fs := t.flat
+	if fs.cancel != nil {
+		fs.cancel()
+	}
+	m.loadSeq++
+	ctx, cancel := context.WithCancel(context.Background())
+	fs.id, fs.cancel = m.loadSeq, cancel
+	id, dir := fs.id, t.panel.Cwd
+	opts := panels.FlattenOptions{
+		Depth:      fs.depth,
+		ShowHidden: t.panel.ShowHidden,
+		GitIgnore:  !fs.all,
+		Max:        flattenMaxEntries,
+	}
+	m.queue(func() tea.Msg {
+		entries, err := panels.Flatten(ctx, dir, opts)
+		return flattenMsg{id: id, entries: entries, err: err}
+	})
+}
+
+// onFlatten applies a finished walk. Results of cancelled walks are ignored.
+func (m *model) onFlatten(msg flattenMsg) {
+	var t *tab
+	for _, c := range m.loadingTabs() {
+		if c.flat != nil && c.flat.id == msg.id {
+			t = c
+			break
+		}
+	}
+	if t == nil {
+		return
+	}
+	fs := t.flat
+	fs.cancel()
+	fs.id, fs.cancel = 0, nil
+	fs.truncated = errors.Is(msg.err, panels.ErrTooManyEntries)
+	if msg.err != nil && !fs.truncated {
+		m.setError(fmt.Errorf("flatten %s: %w", t.panel.Cwd, msg.err))
+		if len(msg.entries) == 0 {
+			return
+		}
+	} else {
+		m.err = nil
+	}
+	cur := selectedName(t)
+	if fs.selName != "" {
+		cur, fs.selName = fs.selName, ""
+	}
+	p := t.panel
+	p.Entries = msg.entries
+	key := fs.sortKey
+	if key == "" {
+		key = "name"
+	}
+	_ = panels.SortBy(p.Cwd, p.Entries, key, fs.sortDesc, false)
+	p.MaxDirName = panels.MaxDirNameOf(p.Entries)
+	t.selected = 0
+	if i := indexOfEntry(p.Entries, cur); i >= 0 {
+		t.selected = i
+	}
+	if t == m.focused() {
+		m.ensureVisible()
+	}
+}
+
+// leaveFlatten restores the plain listing of t, selecting the top-level
+// entry that contains the flattened cursor.
+func (m *model) leaveFlatten(t *tab) bool {
+	fs := t.flat
+	if fs == nil {
+		return false
+	}
+	if fs.cancel != nil {
+		fs.cancel()
+	}
+	t.flat = nil
+	p := t.panel
+	top := selectedName(t)
+	if i := strings.IndexRune(top, filepath.Separator); i >= 0 {
+		top = top[:i]
+	}
+	p.Entries, p.MaxDirName = fs.prevEntries, fs.prevMaxDir
+	if p.ShowHidden != fs.prevHidden || fs.prevEntries == nil {
+		// The filter changed, or the tab was restored flattened and never
+		// listed plainly: list it again.
+		m.startReload(t, top)
+	}
+	t.selected = fs.prevSelected
+	if i := indexOfEntry(p.Entries, top); i >= 0 {
+		t.selected = i
+	}
+	if t.selected >= len(p.Entries) {
+		t.selected = 0
+	}
+	return true
+}
+
+// resetFlatten drops the flattened state of t when it changes directory.
+func resetFlatten(t *tab) {
+	if t.flat != nil && t.flat.cancel != nil {
+		t.flat.cancel()
+	}
+	t.flat = nil
+}
+
+// sortMsg delivers a listing sorted in the background.
+type sortMsg struct {
+	panel   *panels.Panel
+	from    []panels.Entry // the rows that were sorted
+	entries []panels.Entry
+}
+
+// sortCommand implements :sort name|size|mtime|ext [desc]. A flattened
+// listing keeps the order across re-walks; a plain listing keeps it until
+// the directory is read again. Sorting by size or mtime stats the entries,
+// so it runs in the background.
+func (m *model) sortCommand(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		m.setError(fmt.Errorf("usage: :sort %s [desc]", strings.Join(panels.SortKeys, "|")))
+		return
+	}
+	desc := false
+	if len(args) == 2 {
+		switch args[1] {
+		case "desc", "rev", "reverse":
+			desc = true
+		case "asc":
+		default:
+			m.setError(fmt.Errorf("usage: :sort %s [desc]", strings.Join(panels.SortKeys, "|")))
+			return
+		}
+	}
+	key := args[0]
+	if err := panels.SortBy("", nil, key, desc, false); err != nil {
+		m.setError(err)
+		return
+	}
+	t := m.focused()
+	p := t.panel
+	if t.flat != nil {
+		t.flat.sortKey, t.flat.sortDesc = key, desc
+	}
+	m.err = nil
+	from := p.Entries
+	// The listing may be shared with the directory cache; sort a copy.
+	entries := append([]panels.Entry(nil), from...)
+	dir, dirsFirst := p.Cwd, t.flat == nil
+	m.queue(func() tea.Msg {
+		_ = panels.SortBy(dir, entries, key, desc, dirsFirst)
+		return sortMsg{panel: p, from: from, entries: entries}
+	})
+}
+
+// onSort shows a sorted listing, keeping the cursor on the same row. It is
+// dropped if the rows changed meanwhile.
+func (m *model) onSort(msg sortMsg) {
+	var t *tab
+	for _, c := range m.loadingTabs() {
+		if c.panel == msg.panel && c.load == nil && sameEntries(c.panel.Entries, msg.from) {
+			t = c
+			break
+		}
+	}
+	if t == nil {
+		return
+	}
+	cur := selectedName(t)
+	t.panel.Entries = msg.entries
+	t.selected = 0
+	if i := indexOfEntry(msg.entries, cur); i >= 0 {
+		t.selected = i
+	}
+	if t == m.focused() {
+		m.ensureVisible()
+	}
+}
+
+// sameEntries reports whether a and b are the same slice.
+func sameEntries(a, b []panels.Entry) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// flatLabel renders a flattened row: the relative path and, for files, the
+// size aligned to the right edge when there is room for it.
+func flatLabel(e panels.Entry, width int) string {
+	name := e.Name
+	if e.IsDir {
+		return trimToWidth(name+"/", width)
+	}
+	if !e.Info || width < 24 {
+		return trimToWidth(name, width)
+	}
+	size := humanBytes(e.Size)
+	name = trimToWidth(name, width-len(size)-1)
+	pad := width - len(size) - lipgloss.Width(name)
+	if pad < 1 {
+		pad = 1
+	}
+	return name + strings.Repeat(" ", pad) + size
+}
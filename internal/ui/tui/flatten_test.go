@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlattenEnterSortAndLeave(t *testing.T) {
+	m, root := newHistoryModel(t)
+	files := map[string]int{"a/inner/big": 300, "a/small": 1, "b/mid": 20, "junk.log": 500}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(root, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.reloadTab(m.current())
+	drain(m)
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "b"))
+
+	m.execCommand(":flatten")
+	drain(m)
+	if got, want := treeNames(m), "a a/inner a/inner/big a/small b b/mid"; got != want {
+		t.Fatalf("flatten = %q; want %q", got, want)
+	}
+	if got := selectedName(m.current()); got != "b" {
+		t.Fatalf("selection after flatten = %q", got)
+	}
+
+	// Directories sort as empty, ties by name.
+	m.execCommand(":sort size desc")
+	if got := treeNames(m); got != "a a/inner a/inner/big a/small b b/mid" {
+		t.Fatalf("sorted on the update goroutine: %q", got)
+	}
+	drain(m)
+	if got, want := treeNames(m), "a/inner/big b/mid a/small b a/inner a"; got != want {
+		t.Fatalf("sort size desc = %q; want %q", got, want)
+	}
+	// The order survives a re-walk, and :flatten! adds gitignored files.
+	m.execCommand(":flatten!")
+	drain(m)
+	if got := m.current().panel.Entries[0].Name; got != "junk.log" {
+		t.Fatalf("largest after :flatten! = %q (%s)", got, treeNames(m))
+	}
+
+	// Operations see the full path of a nested row.
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "b/mid"))
+	m.copySelectedPath()
+	if got := m.clip.Items(); len(got) != 1 || got[0] != filepath.Join(root, "b", "mid") {
+		t.Fatalf("copy-path on flat row = %v", got)
+	}
+
+	// h leaves the view and selects the top-level ancestor.
+	m.up()
+	if m.current().flat != nil || m.current().panel.Cwd != root {
+		t.Fatalf("h did not leave flatten: cwd=%s", m.current().panel.Cwd)
+	}
+	if got := selectedName(m.current()); got != "b" {
+		t.Fatalf("selection after leaving = %q", got)
+	}
+	if got := treeNames(m); got != "a b junk.log" {
+		t.Fatalf("listing after leaving = %q", got)
+	}
+}
+
+func TestFlattenDepthAndNavigate(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.execCommand(":flatten 1")
+	drain(m)
+	if got := treeNames(m); got != "a b" {
+		t.Fatalf("depth 1 = %q", got)
+	}
+	m.execCommand(":flatten")
+	drain(m)
+	if got := treeNames(m); got != "a a/inner b" {
+		t.Fatalf("unlimited = %q", got)
+	}
+	// Entering a nested directory leaves the flattened view.
+	m.setSelected(1)
+	m.enter()
+	drain(m)
+	if m.current().flat != nil || m.current().panel.Cwd != filepath.Join(root, "a", "inner") {
+		t.Fatalf("enter on a/inner: cwd=%s flat=%v", m.current().panel.Cwd, m.current().flat != nil)
+	}
+	m.execCommand(":flatten x")
+	if m.err == nil {
+		t.Fatalf("bad depth accepted")
+	}
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/frecency"
+)
+
+// frecencySaveEvery throttles writes of the frecency database; the final
+// save happens when tfm exits.
+const frecencySaveEvery = 30 * time.Second
+
+// recordVisit adds dir to the frecency database.
+func (m *model) recordVisit(dir string) {
+	db := m.deps.Frecency
+	if db == nil {
+		return
+	}
+	now := time.Now()
+	db.Add(dir, now)
+	if now.Sub(m.frecencySaved) >= frecencySaveEvery {
+		if err := db.Save(); err != nil {
+			m.deps.Logger.Warnf("frecency: %v", err)
+		}
+		m.frecencySaved = now
+	}
+}
+
+// frecencyMatches returns existing directories matching keywords, best first.
+// Directories that no longer exist are dropped from the database.
+func (m *model) frecencyMatches(keywords []string) []frecency.Entry {
+	db := m.deps.Frecency
+	if db == nil {
+		return nil
+	}
+	var out []frecency.Entry
+	for _, e := range db.Query(keywords, time.Now()) {
+		if fi, err := os.Stat(e.Path); err != nil || !fi.IsDir() {
+			db.Remove(e.Path)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// zJump navigates to the best frecency match for keywords, skipping the
+// current directory. Without keywords it goes to the home directory.
+func (m *model) zJump(keywords []string) tea.Cmd {
+	if len(keywords) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			m.setError(err)
+			return nil
+		}
+		return m.jumpTo(home)
+	}
+	if m.deps.Frecency == nil {
+		m.setError(fmt.Errorf("frecency is disabled"))
+		return nil
+	}
+	cwd := m.focused().panel.Cwd
+	for _, e := range m.frecencyMatches(keywords) {
+		if e.Path != cwd {
+			return m.jumpTo(e.Path)
+		}
+	}
+	m.setError(fmt.Errorf("z: no match for %v", keywords))
+	return nil
+}
+
+// showFrecency opens a picker with frecency matches for keywords.
+func (m *model) showFrecency(keywords []string) {
+	if m.deps.Frecency == nil {
+		m.setError(fmt.Errorf("frecency is disabled"))
+		return
+	}
+	now := time.Now()
+	matches := m.frecencyMatches(keywords)
+	lines := make([]string, 0, len(matches))
+	paths := make([]string, 0, len(matches))
+	for _, e := range matches {
+		lines = append(lines, fmt.Sprintf("%8.1f  %s", frecency.Score(e, now), e.Path))
+		paths = append(paths, e.Path)
+	}
+	if len(lines) == 0 {
+		lines = []string{"no matches"}
+	}
+	m.openPicker("frecency", "Frecent directories (Enter: jump, Esc: close)", lines, 0)
+	m.modalItems = paths
+}
+
+// importFrecency merges zoxide/autojump databases into the frecency database.
+func (m *model) importFrecency() {
+	db := m.deps.Frecency
+	if db == nil {
+		m.setError(fmt.Errorf("frecency is disabled"))
+		return
+	}
+	n, err := db.ImportDefaults()
+	if err != nil {
+		m.setError(err)
+	}
+	if serr := db.Save(); serr != nil && err == nil {
+		m.setError(serr)
+	}
+	m.modalTitle = "Frecency import"
+	m.modalLines = []string{
+		fmt.Sprintf("imported %d entries", n),
+		"zoxide:   " + frecency.ZoxidePath(),
+		"autojump: " + frecency.AutojumpPath(),
+	}
+	m.modalActive = true
+}
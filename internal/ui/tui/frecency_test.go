@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/frecency"
+)
+
+func TestNavigateRecordsVisitsAndZJumps(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.deps.Frecency = frecency.New(filepath.Join(t.TempDir(), "frecency"))
+	m.frecencySaved = time.Now() // avoid writing during the test
+	m.enter()                    // a
+	drain(m)
+	m.enter() // a/inner
+	drain(m)
+	m.up()
+	drain(m)
+	m.up()
+	drain(m)
+	inner := filepath.Join(root, "a", "inner")
+	m.deps.Frecency.Merge([]frecency.Entry{{Path: filepath.Join(root, "gone"), Rank: 100, LastAccess: time.Now().Unix()}})
+
+	m.zJump([]string{"inn"})
+	drain(m)
+	if got := m.current().panel.Cwd; got != inner {
+		t.Fatalf("cwd after :z inn = %q; want %q", got, inner)
+	}
+	// Matching only the current directory is an error, not a no-op jump.
+	m.zJump([]string{"inner"})
+	drain(m)
+	if m.err == nil || !strings.Contains(m.err.Error(), "no match") {
+		t.Fatalf("expected no match error, got %v", m.err)
+	}
+	// Missing directories are pruned from the picker and the database.
+	m.showFrecency(nil)
+	for _, p := range m.modalItems {
+		if strings.HasSuffix(p, "gone") {
+			t.Fatalf("missing dir listed: %v", m.modalItems)
+		}
+	}
+	if len(m.deps.Frecency.Query([]string{"gone"}, time.Now())) != 0 {
+		t.Fatalf("missing dir not pruned")
+	}
+	_ = m.onPick("frecency", indexOf(m.modalItems, root), m.modalItems)
+	drain(m)
+	if got := m.current().panel.Cwd; got != root {
+		t.Fatalf("cwd after pick = %q; want %q", got, root)
+	}
+}
+
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,6 +1,14 @@
 package tui
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tabBarPrefix precedes the tab bar in the header.
+const tabBarPrefix = "tfm | "
 
 func headerContent(m *model) string {
 	if len(m.tabs) == 0 {
@@ -8,5 +16,33 @@ func headerContent(m *model) string {
 	}
 	ft := m.focused()
 	cwd := ft.panel.Cwd
-	return fmt.Sprintf("tfm | tab %d/%d | %s", m.active+1, len(m.tabs), cwd)
+	if m.dual {
+		return dualHeader(m)
+	}
+	return fmt.Sprintf("%s%s | %s", tabBarPrefix, strings.Join(tabBarItems(m), ""), cwd)
+}
+
+// dualHeader shows the directory of each panel above it; the focused one is
+// marked with an asterisk.
+func dualHeader(m *model) string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	leftW := (width - 1) / 2
+	mark := func(side string) string {
+		if m.focus == side {
+			return "* "
+		}
+		return "  "
+	}
+	left := mark("left") + m.current().panel.Cwd
+	if len(m.tabs) > 1 {
+		left = strings.Join(tabBarItems(m), "") + " " + left
+	}
+	left = trimToWidth(left, leftW)
+	if pad := leftW - lipgloss.Width(left); pad > 0 {
+		left += strings.Repeat(" ", pad)
+	}
+	return left + "│" + mark("right") + m.rightT.panel.Cwd
 }
@@ -23,9 +23,14 @@ func TestHeaderContent_BasicAndNoTabs(t *testing.T) {
 	p := &panels.Panel{Cwd: "/tmp"}
 	m.tabs = []tab{{panel: p}}
 	got := headerContent(m)
-	if !strings.Contains(got, "tfm | tab 1/1 | /tmp") {
+	if !strings.Contains(got, "tfm | [1:tmp] | /tmp") {
 		t.Fatalf("header content unexpected: %q", got)
 	}
+	// The tab bar lists every tab; named tabs show their name.
+	m.tabs = append(m.tabs, tab{panel: &panels.Panel{Cwd: "/usr/src"}, name: "code"})
+	if got := headerContent(m); !strings.Contains(got, "tfm | [1:tmp] 2:code  | /tmp") {
+		t.Fatalf("tab bar unexpected: %q", got)
+	}
 }
 
 func TestStatusContent_BasicFlagsFileAndError(t *testing.T) {
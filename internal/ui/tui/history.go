@@ -0,0 +1,83 @@
+package tui
+
+// historyMax bounds the number of remembered locations per tab.
+const historyMax = 100
+
+// histItem is a visited directory together with the entry that was selected
+// when the directory was left.
+type histItem struct {
+	Dir  string
+	Name string
+}
+
+// history is a bounded back/forward stack of visited directories.
+// pos points at the current location; items after pos are the forward stack.
+type history struct {
+	items []histItem
+	pos   int
+}
+
+// visit records dir as the new current location, dropping the forward stack.
+// Visiting the current location again is a no-op.
+func (h *history) visit(dir string) {
+	if len(h.items) > 0 && h.items[h.pos].Dir == dir {
+		return
+	}
+	if len(h.items) > 0 {
+		h.items = h.items[:h.pos+1]
+	}
+	h.items = append(h.items, histItem{Dir: dir})
+	if len(h.items) > historyMax {
+		h.items = append(h.items[:0], h.items[len(h.items)-historyMax:]...)
+	}
+	h.pos = len(h.items) - 1
+}
+
+// setName remembers the selected entry name for the current location.
+func (h *history) setName(name string) {
+	if len(h.items) == 0 {
+		return
+	}
+	h.items[h.pos].Name = name
+}
+
+// historyStep moves the focused tab delta steps through its history.
+func (m *model) historyStep(delta int) {
+	t := m.focused()
+	m.historyJump(t, t.hist.pos+delta)
+}
+
+// historyJump switches t to history item i, restoring the selection that was
+// active there. If the directory cannot be listed the position is restored
+// when the load fails (see failLoad).
+func (m *model) historyJump(t *tab, i int) {
+	if t == nil || t.panel == nil {
+		return
+	}
+	if i < 0 || i >= len(t.hist.items) || i == t.hist.pos {
+		return
+	}
+	t.hist.setName(selectedName(t))
+	it := t.hist.items[i]
+	m.err = nil
+	m.loadDir(t, it.Dir, it.Name)
+	t.hist.pos = i
+}
+
+// showHistory opens a picker with the focused tab's history, newest first.
+func (m *model) showHistory() {
+	t := m.focused()
+	if len(t.hist.items) == 0 {
+		t.hist.visit(t.panel.Cwd)
+	}
+	n := len(t.hist.items)
+	lines := make([]string, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		mark := "  "
+		if i == t.hist.pos {
+			mark = "> "
+		}
+		lines = append(lines, mark+t.hist.items[i].Dir)
+	}
+	m.openPicker("history", "History (Enter: jump, Esc: close)", lines, n-1-t.hist.pos)
+}
@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+func TestHistoryVisitTruncatesForwardAndCaps(t *testing.T) {
+	var h history
+	h.visit("/a")
+	h.visit("/b")
+	h.visit("/b") // duplicate of current is ignored
+	h.visit("/c")
+	if len(h.items) != 3 || h.pos != 2 {
+		t.Fatalf("items=%v pos=%d", h.items, h.pos)
+	}
+	h.pos = 0
+	h.visit("/d")
+	if len(h.items) != 2 || h.items[1].Dir != "/d" || h.pos != 1 {
+		t.Fatalf("forward stack not dropped: %v pos=%d", h.items, h.pos)
+	}
+	for i := 0; i < historyMax+10; i++ {
+		h.visit(filepath.Join("/x", string(rune('a'+i%26)), string(rune('0'+i%10)), string(rune(i))))
+	}
+	if len(h.items) != historyMax || h.pos != historyMax-1 {
+		t.Fatalf("len=%d pos=%d; want %d", len(h.items), h.pos, historyMax)
+	}
+}
+
+func newHistoryModel(t *testing.T) (*model, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, d := range []string{"a", "b", "a/inner"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := panels.NewPanel(root, false)
+	if err := p.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	tb := tab{panel: p}
+	tb.hist.visit(root)
+	m := &model{tabs: []tab{tb}, focus: "left", height: 20}
+	m.deps.Config = config.Default()
+	m.dirCache = uicache.NewDirCache(8)
+	m.prefetching = make(map[string]struct{})
+	return m, root
+}
+
+func TestBackForwardRestoresDirAndSelection(t *testing.T) {
+	m, root := newHistoryModel(t)
+	// select "b" and enter it
+	m.setSelected(1)
+	m.enter()
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "b") {
+		t.Fatalf("cwd after enter = %q", got)
+	}
+	m.historyStep(-1)
+	drain(m)
+	if got := m.current().panel.Cwd; got != root {
+		t.Fatalf("cwd after back = %q", got)
+	}
+	if name := selectedName(m.current()); name != "b" {
+		t.Fatalf("selection after back = %q; want b", name)
+	}
+	m.historyStep(1)
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "b") {
+		t.Fatalf("cwd after forward = %q", got)
+	}
+	// forward at the end is a no-op
+	m.historyStep(1)
+	drain(m)
+	if m.current().hist.pos != 1 {
+		t.Fatalf("pos = %d; want 1", m.current().hist.pos)
+	}
+}
+
+func TestHistoryPickerJumps(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.enter() // into "a"
+	drain(m)
+	m.enter() // into "a/inner"
+	drain(m)
+	m.showHistory()
+	if !m.modalActive || m.modalKind != "history" || len(m.modalLines) != 3 {
+		t.Fatalf("history modal not opened: %v %q %v", m.modalActive, m.modalKind, m.modalLines)
+	}
+	// newest first: inner, a, root -> pick the oldest
+	_ = m.onPick("history", 2, nil)
+	drain(m)
+	if got := m.current().panel.Cwd; got != root {
+		t.Fatalf("cwd after pick = %q; want %q", got, root)
+	}
+	if m.current().hist.pos != 0 {
+		t.Fatalf("pos = %d; want 0", m.current().hist.pos)
+	}
+}
+
+func TestHistoryJumpKeepsPositionOnError(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.setSelected(1)
+	m.enter() // into "b"
+	drain(m)
+	if err := os.Remove(root + "/b"); err != nil {
+		t.Fatal(err)
+	}
+	m.historyStep(-1) // back to root works
+	drain(m)
+	m.historyStep(1) // "b" is gone
+	drain(m)
+	if m.err == nil {
+		t.Fatalf("expected error for removed directory")
+	}
+	if m.current().hist.pos != 0 || m.current().panel.Cwd != root {
+		t.Fatalf("position moved on failed jump: pos=%d cwd=%q", m.current().hist.pos, m.current().panel.Cwd)
+	}
+}
@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// imageProtocol returns the inline image protocol in use: none when
+// inline_images is off, otherwise image_protocol or what the terminal
+// looks like (see preview.DetectProtocol).
+func (m *model) imageProtocol() string {
+	cfg := m.deps.Config
+	if cfg == nil || !cfg.InlineImages {
+		return preview.ProtocolNone
+	}
+	return preview.DetectProtocol(cfg.ImageProtocol)
+}
+
+// imageKey is the width under which an image rendered over cols×rows cells
+// is stored in imgCache, which keeps one size per path in memory and every
+// size on disk.
+func imageKey(cols, rows int) int { return cols<<16 | rows }
+
+// inlineImage renders the image at path with the terminal's image protocol
+// into at most cols×rows cells, or draws it with text when there is none.
+// Images are decoded and encoded in the background (see imageInBackground)
+// and cached per path and size; until then a placeholder line is shown.
+func (m *model) inlineImage(path string, cols, rows int) ([]string, bool) {
+	proto := m.imageProtocol()
+	kind, style := proto, ""
+	switch proto {
+	case preview.ProtocolIterm2:
+		return preview.BuildIterm2Inline(path, cols, rows)
+	case preview.ProtocolKitty:
+		kind += fmt.Sprintf("/%d", m.kittyImageID())
+	case preview.ProtocolSixel:
+	default:
+		if style = m.thumbnailStyle(); style == preview.ThumbASCII {
+			// The provider's character ramp, shown as text.
+			return nil, false
+		}
+		kind = style + "/" + m.colorProfile
+	}
+	// Images are also kept on disk across sessions: the image id and cell
+	// size they were drawn for are part of their kind.
+	kind += fmt.Sprintf("/%dx%d", m.cell.W, m.cell.H)
+	key := imageKey(cols, rows)
+	if res, ok := m.imgCache.Get(path, key); ok && res.Kind == kind {
+		if res.Content == "" {
+			return nil, false // the image could not be drawn
+		}
+		m.imgPlaced = proto == preview.ProtocolKitty || proto == preview.ProtocolSixel
+		return strings.Split(res.Content, "\n"), true
+	}
+	cell, profile := m.cell, m.colorProfile
+	var build func() ([]string, bool)
+	switch proto {
+	case preview.ProtocolSixel:
+		build = func() ([]string, bool) { return preview.BuildSixel(path, cols, rows, cell) }
+	case preview.ProtocolKitty:
+		tmux := preview.InTmux()
+		opts := preview.KittyOptions{
+			ID: m.kittyImageID(), Cols: cols, Rows: rows, Cell: cell,
+			Placeholder: tmux, Tmux: tmux,
+		}
+		build = func() ([]string, bool) { return preview.BuildKitty(path, opts) }
+	default:
+		build = func() ([]string, bool) { return preview.BuildThumbnail(path, cols, rows, cell, style, profile) }
+	}
+	m.imageInBackground(imageMsg{path: path, key: key, kind: kind, cell: cell}, build)
+	return []string{m.renderSpans([]preview.Span{{Text: "loading…", Class: preview.Comment}}, cols)}, true
+}
+
+// imageMsg delivers an image drawn in the background.
+type imageMsg struct {
+	path  string
+	key   int              // see imageKey
+	kind  string           // protocol or thumbnail style it was drawn for
+	cell  preview.CellSize // cell size it was drawn for
+	lines []string         // nil when the image could not be drawn
+}
+
+// imageInBackground queues build, which draws the image described by msg,
+// once per path and size. Decoding a large image takes a while.
+func (m *model) imageInBackground(msg imageMsg, build func() ([]string, bool)) {
+	inflight := fmt.Sprintf("%s\x00%d", msg.path, msg.key)
+	if _, ok := m.prefetching[inflight]; ok {
+		return
+	}
+	m.prefetching[inflight] = struct{}{}
+	m.queue(func() tea.Msg {
+		if lines, ok := build(); ok {
+			msg.lines = lines
+		}
+		return msg
+	})
+}
+
+// onImage caches an image drawn in the background. Failures are cached as
+// an empty result, so the text preview is shown instead of trying again.
+// Images drawn for another cell size are dropped.
+func (m *model) onImage(msg imageMsg) {
+	delete(m.prefetching, fmt.Sprintf("%s\x00%d", msg.path, msg.key))
+	if msg.cell != m.cell {
+		return
+	}
+	m.imgCache.Put(msg.path, msg.key, preview.Result{Kind: msg.kind, Content: strings.Join(msg.lines, "\n")})
+}
+
+// thumbnailStyle is how images are drawn without an image protocol (see
+// preview.ThumbnailStyle).
+func (m *model) thumbnailStyle() string {
+	setting := "auto"
+	if m.deps.Config != nil {
+		setting = m.deps.Config.Thumbnails
+	}
+	return preview.ThumbnailStyle(setting, m.colorProfile)
+}
+
+// kittyImageID returns the id of the single kitty image tfm shows; every new
+// image replaces the previous one.
+func (m *model) kittyImageID() uint32 {
+	if m.kittyID == 0 {
+		m.kittyID = preview.KittyID()
+	}
+	return m.kittyID
+}
+
+// updateImageClear decides after rendering whether the frame has to delete
+// the kitty image left on screen: images placed at the cursor stay until
+// deleted, even when the text around them is redrawn.
+func (m *model) updateImageClear() {
+	m.imgClear = ""
+	if m.imgPlaced || m.imageProtocol() != preview.ProtocolKitty {
+		return
+	}
+	m.imgClear = preview.KittyDelete(m.kittyImageID(), preview.InTmux())
+}
+
+// setCellSize records the pixel size of the terminal's cells; images
+// encoded for another size are dropped.
+func (m *model) setCellSize(c preview.CellSize) {
+	if c != m.cell {
+		m.cell = c
+		m.imgCache.Clear()
+	}
+}
+
+// sixelFrame remembers the last frame that showed a sixel image.
+type sixelFrame struct {
+	prev []string // lines of the frame
+	flip bool     // toggles the image line, see redrawSixel
+}
+
+// redrawSixel makes sure a sixel image in frame is sent again whenever a
+// line it covers is rewritten. Sixels live in the text cells, and the
+// renderer only rewrites the lines that changed: when a line above the one
+// carrying the image (see preview.SixelLines) changed, that line is marked
+// with a no-op reset so that it differs from the last frame too.
+func (m *model) redrawSixel(frame string) string {
+	if !m.imgPlaced || m.sixel == nil || !strings.Contains(frame, preview.SixelIntro) {
+		return frame
+	}
+	lines := strings.Split(frame, "\n")
+	at := slices.IndexFunc(lines, func(l string) bool { return strings.Contains(l, preview.SixelIntro) })
+	st := m.sixel
+	if len(st.prev) <= at || !slices.Equal(lines[:at], st.prev[:at]) {
+		st.flip = !st.flip
+	}
+	st.prev = slices.Clone(lines)
+	if st.flip {
+		lines[at] += "\x1b[m"
+	}
+	return join(lines, "\n")
+}
@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ops"
+)
+
+// jobProgressEvery limits how often a job's progress redraws the screen.
+const jobProgressEvery = 100 * time.Millisecond
+
+// job is a long file operation (:compress, :extract) running in the
+// background. Only one runs at a time; Esc or :cancel stops it.
+type job struct {
+	id       int
+	label    string // shown in the status line, e.g. "compressing a.zip"
+	cancel   context.CancelFunc
+	progress chan ops.Progress
+	done     chan error
+	last     ops.Progress
+}
+
+// jobMsg reports the progress or the end of a job.
+type jobMsg struct {
+	id       int
+	progress ops.Progress
+	done     bool
+	err      error
+}
+
+// startJob runs fn in the background as the current job. fn reports its
+// progress with the function it is given.
+func (m *model) startJob(label string, fn func(ctx context.Context, progress func(ops.Progress)) error) tea.Cmd {
+	if m.job != nil {
+		m.setError(fmt.Errorf("%s is still running (Esc cancels)", m.job.label))
+		return nil
+	}
+	m.loadSeq++
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:       m.loadSeq,
+		label:    label,
+		cancel:   cancel,
+		progress: make(chan ops.Progress, 1),
+		done:     make(chan error, 1),
+	}
+	m.job = j
+	m.err = nil
+	go func() {
+		var sent time.Time
+		j.done <- fn(ctx, func(p ops.Progress) {
+			if time.Since(sent) < jobProgressEvery {
+				return
+			}
+			select {
+			case j.progress <- p:
+				sent = time.Now()
+			default:
+			}
+		})
+	}()
+	return waitJob(j)
+}
+
+// waitJob waits for the next progress report or the end of j.
+func waitJob(j *job) tea.Cmd {
+	id, progress, done := j.id, j.progress, j.done
+	return func() tea.Msg {
+		select {
+		case p := <-progress:
+			return jobMsg{id: id, progress: p}
+		case err := <-done:
+			return jobMsg{id: id, done: true, err: err}
+		}
+	}
+}
+
+// onJob applies a job report. When the job ends the panels are reloaded,
+// as after other file operations.
+func (m *model) onJob(msg jobMsg) tea.Cmd {
+	j := m.job
+	if j == nil || j.id != msg.id {
+		return nil
+	}
+	if !msg.done {
+		j.last = msg.progress
+		return waitJob(j)
+	}
+	j.cancel()
+	m.job = nil
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.setError(fmt.Errorf("%s: cancelled", j.label))
+	case msg.err != nil:
+		m.setError(fmt.Errorf("%s: %w", j.label, msg.err))
+	default:
+		m.err = nil
+	}
+	m.reloadPanes()
+	return m.maybePrefetchSelected()
+}
+
+// cancelJob stops the running job, reporting whether there was one.
+func (m *model) cancelJob() bool {
+	if m.job == nil {
+		return false
+	}
+	m.job.cancel()
+	return true
+}
+
+// status describes the running job for the status line.
+func (j *job) status() string {
+	p := j.last
+	switch {
+	case p.Total > 0:
+		return fmt.Sprintf("%s %d%% (%s of %s), Esc cancels", j.label, p.Done*100/p.Total, humanBytes(p.Done), humanBytes(p.Total))
+	case p.Done > 0:
+		return fmt.Sprintf("%s %s, Esc cancels", j.label, humanBytes(p.Done))
+	}
+	return j.label + "…, Esc cancels"
+}
+
+// compressCommand implements :compress <name>, writing the selected entry
+// to a new archive whose format follows from name (zip, tar, tar.gz, tar.xz,
+// tar.zst). name is relative to the panel's directory.
+func (m *model) compressCommand(args []string) tea.Cmd {
+	if len(args) != 1 {
+		m.setError(fmt.Errorf("usage: :compress <name.zip|.tar.gz|.tar.xz|.tar.zst>"))
+		return nil
+	}
+	t := m.focused()
+	name := selectedName(t)
+	if name == "" {
+		m.setError(fmt.Errorf("nothing selected to compress"))
+		return nil
+	}
+	src := t.panel.Join(name)
+	dst := expandPath(args[0], t.panel.Cwd)
+	fs := m.deps.FS
+	return m.startJob("compressing "+filepath.Base(dst), func(ctx context.Context, progress func(ops.Progress)) error {
+		return fs.Compress(ctx, dst, []string{src}, progress)
+	})
+}
+
+// extractCommand implements :extract [dest] for the selected archive. By
+// default it is extracted to a new directory named after it, next to it.
+func (m *model) extractCommand(args []string) tea.Cmd {
+	if len(args) > 1 {
+		m.setError(fmt.Errorf("usage: :extract [dest]"))
+		return nil
+	}
+	t := m.focused()
+	name := selectedName(t)
+	src := t.panel.Join(name)
+	if name == "" || archive.Detect(name) == "" {
+		m.setError(fmt.Errorf("not an archive: %s", name))
+		return nil
+	}
+	var dst string
+	if len(args) == 1 {
+		dst = expandPath(args[0], t.panel.Cwd)
+	} else {
+		dst = uniqueDestPath(t.panel.Cwd, archive.Stem(filepath.Base(name)))
+	}
+	if fi, err := os.Stat(dst); err == nil && !fi.IsDir() {
+		m.setError(fmt.Errorf("not a directory: %s", dst))
+		return nil
+	}
+	fs := m.deps.FS
+	return m.startJob("extracting "+filepath.Base(name), func(ctx context.Context, progress func(ops.Progress)) error {
+		return fs.Extract(ctx, src, dst, progress)
+	})
+}
@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ops"
+)
+
+// runJob feeds the messages of a job to the model until it ends.
+func runJob(m *model, cmd tea.Cmd) {
+	for cmd != nil {
+		msg, ok := cmd().(jobMsg)
+		if !ok {
+			return
+		}
+		if cmd = m.onJob(msg); msg.done {
+			drain(m)
+			return
+		}
+	}
+}
+
+func TestCompressAndExtractCommands(t *testing.T) {
+	m, root := newHistoryModel(t)
+	if err := os.WriteFile(filepath.Join(root, "a", "f.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "a"))
+	cmd := m.execCommand(":compress a.tar.zst")
+	if m.job == nil || !strings.HasPrefix(m.job.status(), "compressing a.tar.zst") {
+		t.Fatalf("no job started: %v", m.err)
+	}
+	runJob(m, cmd)
+	if m.job != nil || m.err != nil {
+		t.Fatalf("compress: job %v, err %v", m.job, m.err)
+	}
+	i := indexOfEntry(m.current().panel.Entries, "a.tar.zst")
+	if i < 0 {
+		t.Fatal("archive not listed after compressing")
+	}
+
+	m.setSelected(i)
+	runJob(m, m.execCommand(":extract"))
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	// "a" exists, so the archive goes to a new directory.
+	if b, err := os.ReadFile(filepath.Join(root, "a copy 1", "a", "f.txt")); err != nil || string(b) != "hello" {
+		t.Fatalf("extracted file: %q, %v", b, err)
+	}
+
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "b"))
+	if m.execCommand(":extract"); m.err == nil {
+		t.Error("extracted a directory")
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	m, _ := newHistoryModel(t)
+	cmd := m.startJob("waiting", func(ctx context.Context, progress func(ops.Progress)) error {
+		progress(ops.Progress{Done: 1, Total: 4})
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if m.startJob("second", nil) != nil || m.err == nil {
+		t.Fatal("started a second job")
+	}
+	m.onKey(tea.KeyMsg{Type: tea.KeyEsc})
+	runJob(m, cmd)
+	if m.job != nil || m.err == nil || !strings.Contains(m.err.Error(), "waiting: cancelled") {
+		t.Fatalf("job %v, err %v", m.job, m.err)
+	}
+}
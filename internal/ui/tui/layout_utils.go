@@ -1,7 +1,6 @@
 package tui
 
 import (
-	"github.com/charmbracelet/lipgloss"
 	"strings"
 )
 
@@ -11,18 +10,11 @@ func autoColumnWidths(cols []tab, totalW int, sep int) []int {
 	widths := make([]int, n)
 	desired := make([]int, n)
 	for i, c := range cols {
+		// MaxDirName is kept up to date whenever a listing changes; scanning
+		// the entries here would make every frame O(n).
 		maxLen := 10
 		if c.panel != nil && c.panel.MaxDirName > 0 {
 			maxLen = c.panel.MaxDirName
-		} else if c.panel != nil {
-			for _, e := range c.panel.Entries {
-				if e.IsDir {
-					l := lipgloss.Width(e.Name) + 1
-					if l > maxLen {
-						maxLen = l
-					}
-				}
-			}
 		}
 		desired[i] = maxLen + 2
 		if desired[i] < 10 {
@@ -0,0 +1,355 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+const (
+	// listBatchSize is the number of entries read per ReadDir call.
+	listBatchSize = 1024
+	// listCoalesceMax caps the entries delivered to the model in one message.
+	listCoalesceMax = 32768
+)
+
+// errLoadStalled is reported when a listing makes no progress for the
+// configured timeout (e.g. a hung network mount).
+var errLoadStalled = errors.New("directory listing timed out")
+
+// dirLoad tracks an in-flight asynchronous listing of a tab's directory.
+type dirLoad struct {
+	id      int
+	dir     string
+	selName string // entry to select once the listing completes
+	record  bool   // record a frecency visit on success
+	reload  bool   // keep the rows on screen until done (see startReload)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	batches chan []panels.Entry
+	errc    chan error
+	count   int
+	// rows of a reload read so far, and the children of its open tree rows,
+	// read once the listing is complete
+	entries []panels.Entry
+	kids    map[string][]panels.Entry
+	// state restored if the directory cannot be listed at all
+	prevCwd      string
+	prevEntries  []panels.Entry
+	prevMaxDir   int
+	prevSelected int
+	prevHist     history
+	prevFlat     *flatState
+}
+
+// dirLoadMsg delivers a chunk of a streaming listing to the model.
+type dirLoadMsg struct {
+	id      int
+	entries []panels.Entry
+	done    bool
+	err     error
+}
+
+// queue schedules cmd to be returned from the current Update.
+func (m *model) queue(cmd tea.Cmd) {
+	if cmd != nil {
+		m.pending = append(m.pending, cmd)
+	}
+}
+
+// loadTimeout returns the stall timeout for directory listings.
+func (m *model) loadTimeout() time.Duration {
+	if m.deps.Config != nil && m.deps.Config.DirLoadTimeout > 0 {
+		return time.Duration(m.deps.Config.DirLoadTimeout) * time.Second
+	}
+	return 0
+}
+
+// startLoad switches t to dir immediately with an empty listing and streams
+// the entries in the background. Any previous load of t is cancelled.
+func (m *model) startLoad(t *tab, dir, selName string) {
+	m.listDir(t, dir, selName, false)
+}
+
+// startReload lists t's directory again in the background. The rows stay on
+// screen until the listing is complete; then the open directories of a tree
+// are opened again and the cursor moves to selName or, if it is "", stays on
+// the same name (or the same index if the entry disappeared).
+func (m *model) startReload(t *tab, selName string) {
+	m.listDir(t, t.panel.Cwd, selName, true)
+}
+
+// listDir implements startLoad and startReload.
+func (m *model) listDir(t *tab, dir, selName string, reload bool) {
+	m.cancelLoad(t)
+	prevFlat := t.flat
+	if !reload {
+		resetFlatten(t)
+	}
+	m.loadSeq++
+	p := t.panel
+	hist := t.hist
+	hist.items = append([]histItem(nil), t.hist.items...)
+	ctx, cancel := context.WithCancel(context.Background())
+	ld := &dirLoad{
+		id:           m.loadSeq,
+		dir:          dir,
+		selName:      selName,
+		reload:       reload,
+		ctx:          ctx,
+		cancel:       cancel,
+		batches:      make(chan []panels.Entry, 4),
+		errc:         make(chan error, 1),
+		prevCwd:      p.Cwd,
+		prevEntries:  p.Entries,
+		prevMaxDir:   p.MaxDirName,
+		prevSelected: t.selected,
+		prevHist:     hist,
+		prevFlat:     prevFlat,
+	}
+	var open []string
+	if reload && t.tree != nil {
+		for rel := range t.tree.expanded {
+			open = append(open, rel)
+		}
+		sort.Strings(open)
+	}
+	showHidden := p.ShowHidden
+	go func() {
+		err := panels.Stream(ctx, dir, showHidden, listBatchSize, ld.batches)
+		if err == nil && len(open) > 0 {
+			// Set before errc is sent: onDirLoad reads it once err arrives.
+			ld.kids, _ = listTree(ctx, dir, showHidden, open, nil, 0)
+		}
+		ld.errc <- err
+		close(ld.batches)
+	}()
+	if !reload {
+		p.Cwd = dir
+		p.Entries = nil
+		p.MaxDirName = 0
+	}
+	t.load = ld
+	m.queue(waitLoad(ld, m.loadTimeout()))
+}
+
+// previewListing returns the cached listing of dir for the preview column.
+// On a cache miss it queues a background prefetch and reports false.
+func (m *model) previewListing(dir string, showHidden bool) ([]panels.Entry, bool) {
+	if m.dirCache.Has(dir) {
+		return m.dirCache.Get(dir), true
+	}
+	if _, inflight := m.prefetching[dir]; !inflight {
+		m.prefetching[dir] = struct{}{}
+		m.queue(func() tea.Msg {
+			dp := panels.NewPanel(dir, showHidden)
+			_ = dp.Refresh()
+			return dirPrefetchMsg{path: dir, entries: dp.Entries}
+		})
+	}
+	return nil, false
+}
+
+// cancelLoad stops t's in-flight listing, if any.
+func (m *model) cancelLoad(t *tab) {
+	if t.load != nil {
+		t.load.cancel()
+		t.load = nil
+	}
+}
+
+// waitLoad waits for the next chunk of ld's listing, coalescing whatever
+// else is already available. A stall longer than timeout ends the load.
+func waitLoad(ld *dirLoad, timeout time.Duration) tea.Cmd {
+	id, ctx, batches, errc := ld.id, ld.ctx, ld.batches, ld.errc
+	return func() tea.Msg {
+		var stall <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			stall = timer.C
+		}
+		msg := dirLoadMsg{id: id}
+		select {
+		case b, ok := <-batches:
+			if !ok {
+				return dirLoadMsg{id: id, done: true, err: <-errc}
+			}
+			msg.entries = b
+		case <-ctx.Done():
+			return dirLoadMsg{id: id, done: true, err: ctx.Err()}
+		case <-stall:
+			return dirLoadMsg{id: id, done: true, err: errLoadStalled}
+		}
+		for len(msg.entries) < listCoalesceMax {
+			select {
+			case b, ok := <-batches:
+				if !ok {
+					msg.done = true
+					msg.err = <-errc
+					return msg
+				}
+				msg.entries = append(msg.entries, b...)
+			default:
+				return msg
+			}
+		}
+		return msg
+	}
+}
+
+// loadingTabs returns every tab that may own a listing, including the
+// columns of background tabs.
+func (m *model) loadingTabs() []*tab {
+	var out []*tab
+	for i := range m.tabs {
+		out = append(out, m.tabTree(&m.tabs[i])...)
+	}
+	return out
+}
+
+// onDirLoad applies a listing chunk. Stale chunks (cancelled loads) are ignored.
+func (m *model) onDirLoad(msg dirLoadMsg) tea.Cmd {
+	var t *tab
+	for _, c := range m.loadingTabs() {
+		if c.load != nil && c.load.id == msg.id {
+			t = c
+			break
+		}
+	}
+	if t == nil {
+		return nil
+	}
+	ld := t.load
+	p := t.panel
+	switch {
+	case len(msg.entries) == 0:
+	case ld.reload:
+		ld.entries = append(ld.entries, msg.entries...)
+		ld.count += len(msg.entries)
+	default:
+		p.Entries = append(p.Entries, msg.entries...)
+		ld.count += len(msg.entries)
+		if l := panels.MaxDirNameOf(msg.entries); l > p.MaxDirName {
+			p.MaxDirName = l
+		}
+	}
+	if !msg.done {
+		return waitLoad(ld, m.loadTimeout())
+	}
+	ld.cancel()
+	t.load = nil
+	if msg.err != nil && ld.count == 0 {
+		if ld.reload {
+			// The rows on screen are kept.
+			m.setError(msg.err)
+			return nil
+		}
+		m.failLoad(t, ld, msg.err)
+		return nil
+	}
+	if ld.reload {
+		return m.finishReload(t, ld, msg.err)
+	}
+	if t.tree != nil {
+		t.tree.stop()
+		t.tree = newTreeState()
+	}
+	if msg.err != nil {
+		m.setError(fmt.Errorf("%s: listing incomplete: %w", ld.dir, msg.err))
+	} else {
+		m.err = nil
+	}
+	// Keep the entry the user moved to while loading; otherwise apply the
+	// requested or remembered cursor.
+	moved := t.selected != 0
+	cur := selectedName(t)
+	panels.SortEntries(p.Entries)
+	p.MaxDirName = panels.MaxDirNameOf(p.Entries)
+	t.selected = 0
+	if moved {
+		if i := indexOfEntry(p.Entries, cur); i >= 0 {
+			t.selected = i
+		}
+	} else {
+		m.restoreCursor(t)
+		if i := indexOfEntry(p.Entries, ld.selName); i >= 0 {
+			t.selected = i
+		}
+	}
+	if msg.err == nil {
+		m.dirCache.Put(ld.dir, p.Entries)
+		if ld.record {
+			m.recordVisit(ld.dir)
+		}
+	}
+	m.ensureVisible()
+	return m.maybePrefetchSelected()
+}
+
+// finishReload shows the rows read by a complete reload of t (see
+// startReload). err reports a listing cut short.
+func (m *model) finishReload(t *tab, ld *dirLoad, err error) tea.Cmd {
+	p := t.panel
+	name, old := ld.selName, t.selected
+	if name == "" {
+		name = selectedName(t)
+	}
+	panels.SortEntries(ld.entries)
+	p.Entries = ld.entries
+	p.MaxDirName = panels.MaxDirNameOf(p.Entries)
+	if err != nil {
+		m.setError(fmt.Errorf("%s: listing incomplete: %w", ld.dir, err))
+	} else {
+		m.dirCache.Put(ld.dir, p.Entries)
+	}
+	if t.tree != nil {
+		t.tree.stop()
+		t.tree.expanded = make(map[string]bool)
+		if err == nil {
+			treeInsert(t, ld.kids)
+		}
+	}
+	idx := indexOfEntry(p.Entries, name)
+	if idx < 0 {
+		idx = old
+	}
+	if n := len(p.Entries); idx >= n {
+		idx = n - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	t.selected = idx
+	m.ensureVisible()
+	return m.maybePrefetchSelected()
+}
+
+// failLoad restores t to where it was before the load and reports err.
+// A right column that could not be listed is closed instead.
+func (m *model) failLoad(t *tab, ld *dirLoad, err error) {
+	m.setError(err)
+	for i := range m.rightCols {
+		if &m.rightCols[i] == t {
+			m.rightCols = append(m.rightCols[:i], m.rightCols[i+1:]...)
+			if len(m.rightCols) == 0 {
+				m.closeRight()
+			}
+			return
+		}
+	}
+	p := t.panel
+	p.Cwd = ld.prevCwd
+	p.Entries = ld.prevEntries
+	p.MaxDirName = ld.prevMaxDir
+	t.selected = ld.prevSelected
+	t.hist = ld.prevHist
+	t.flat = ld.prevFlat
+	m.ensureVisible()
+}
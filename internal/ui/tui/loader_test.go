@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// drain runs the commands queued on m and feeds directory listing results
+// back into it until no loads or prefetches are pending.
+func drain(m *model) {
+	for len(m.pending) > 0 {
+		cmds := m.pending
+		m.pending = nil
+		for _, cmd := range cmds {
+			for cmd != nil {
+				switch msg := cmd().(type) {
+				case dirLoadMsg:
+					cmd = m.onDirLoad(msg)
+				case flattenMsg:
+					m.onFlatten(msg)
+					cmd = nil
+				case treeLoadMsg:
+					m.onTreeLoad(msg)
+					cmd = nil
+				case sortMsg:
+					m.onSort(msg)
+					cmd = nil
+				case dirPrefetchMsg:
+					delete(m.prefetching, msg.path)
+					m.dirCache.Put(msg.path, msg.entries)
+					cmd = nil
+				case imageMsg:
+					m.onImage(msg)
+					cmd = nil
+				case memberMsg:
+					m.onMember(msg)
+					cmd = nil
+				case previewResultMsg:
+					m.onPreviewResult(msg)
+					cmd = nil
+				default:
+					cmd = nil
+				}
+			}
+		}
+	}
+}
+
+func TestLoadStreamsLargeDirectory(t *testing.T) {
+	m, root := newHistoryModel(t)
+	big := filepath.Join(root, "a", "inner")
+	const n = 3*listBatchSize + 7
+	for i := 0; i < n; i++ {
+		if err := os.WriteFile(filepath.Join(big, fmt.Sprintf("f%05d", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.navigate(m.current(), big, "f00042")
+	tb := m.current()
+	if tb.load == nil || tb.panel.Cwd != big || len(tb.panel.Entries) != 0 {
+		t.Fatalf("expected an in-flight load of %s", big)
+	}
+	drain(m)
+	if tb.load != nil {
+		t.Fatalf("load not finished")
+	}
+	if len(tb.panel.Entries) != n {
+		t.Fatalf("entries = %d; want %d", len(tb.panel.Entries), n)
+	}
+	if got := selectedName(tb); got != "f00042" {
+		t.Fatalf("selected %q; want f00042", got)
+	}
+	if !m.dirCache.Has(big) {
+		t.Fatalf("finished listing not cached")
+	}
+}
+
+func TestLoadIgnoresStaleResults(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.navigate(m.current(), filepath.Join(root, "a"), "")
+	stale := m.pending
+	m.pending = nil
+	m.navigate(m.current(), filepath.Join(root, "b"), "")
+	// Results of the cancelled load must not leak into the new listing.
+	for _, cmd := range stale {
+		if msg, ok := cmd().(dirLoadMsg); ok {
+			m.onDirLoad(msg)
+		}
+	}
+	drain(m)
+	tb := m.current()
+	if tb.panel.Cwd != filepath.Join(root, "b") || len(tb.panel.Entries) != 0 {
+		t.Fatalf("cwd=%q entries=%v", tb.panel.Cwd, tb.panel.Entries)
+	}
+}
+
+func TestLoadFailureRevertsTab(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.setSelected(1)
+	m.navigate(m.current(), filepath.Join(root, "missing"), "")
+	drain(m)
+	tb := m.current()
+	if m.err == nil {
+		t.Fatalf("expected an error")
+	}
+	if tb.panel.Cwd != root || len(tb.panel.Entries) != 2 || tb.selected != 1 {
+		t.Fatalf("tab not restored: cwd=%q entries=%d selected=%d", tb.panel.Cwd, len(tb.panel.Entries), tb.selected)
+	}
+	if len(tb.hist.items) != 1 {
+		t.Fatalf("failed load recorded in history: %v", tb.hist.items)
+	}
+}
+
+func TestReloadsRunInBackground(t *testing.T) {
+	m, root := newHistoryModel(t)
+	if err := os.Mkdir(filepath.Join(root, ".hidden"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.setSelected(1) // b
+	m.toggleHidden()
+	if tb := m.current(); tb.load == nil || len(tb.panel.Entries) != 2 {
+		t.Fatalf("toggling hidden files listed synchronously: %d entries", len(tb.panel.Entries))
+	}
+	drain(m)
+	if got := selectedName(m.current()); len(m.current().panel.Entries) != 3 || got != "b" {
+		t.Fatalf("after toggle: %d entries, cursor on %q", len(m.current().panel.Entries), got)
+	}
+
+	m.dirCache.Invalidate(root)
+	m.newTab()
+	if m.current().load == nil {
+		t.Fatalf("new tab listed synchronously")
+	}
+	drain(m)
+	if len(m.current().panel.Entries) != 3 {
+		t.Fatalf("new tab: %d entries", len(m.current().panel.Entries))
+	}
+	// A reload of a directory that vanished keeps the rows on screen.
+	m.navigate(m.current(), filepath.Join(root, "a", "inner"), "")
+	drain(m)
+	if err := os.Mkdir(filepath.Join(root, "a", "inner", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.reloadTab(m.current())
+	drain(m)
+	if err := os.RemoveAll(filepath.Join(root, "a", "inner")); err != nil {
+		t.Fatal(err)
+	}
+	m.reloadTab(m.current())
+	drain(m)
+	if m.err == nil || len(m.current().panel.Entries) != 1 || m.current().panel.Cwd != filepath.Join(root, "a", "inner") {
+		t.Fatalf("failed reload: err=%v entries=%v", m.err, m.current().panel.Entries)
+	}
+}
+
+func TestClosingColumnsCancelsLoads(t *testing.T) {
+	m, root := newHistoryModel(t)
+	inner := filepath.Join(root, "a", "inner")
+	open := func() *dirLoad {
+		m.openRightPanel(inner)
+		m.focus = "right"
+		ld := m.rightCols[len(m.rightCols)-1].load
+		if ld == nil {
+			t.Fatalf("column listed synchronously")
+		}
+		return ld
+	}
+	check := func(how string, ld *dirLoad) {
+		t.Helper()
+		if ld.ctx.Err() == nil {
+			t.Errorf("%s: load of a closed column not cancelled", how)
+		}
+	}
+
+	ld := open()
+	m.up()
+	check("up", ld)
+
+	ld = open()
+	m.closeRight()
+	check("closeRight", ld)
+
+	m.openRightPanel(filepath.Join(root, "a"))
+	drain(m)
+	ld = open()
+	m.focusColumn(1)
+	check("focusColumn", ld)
+	if len(m.rightCols) != 1 {
+		t.Fatalf("columns after click: %d", len(m.rightCols))
+	}
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openPicker shows an interactive list modal. kind selects the handler that
+// runs when a line is picked (see onPick).
+func (m *model) openPicker(kind, title string, lines []string, sel int) {
+	m.modalKind = kind
+	m.modalTitle = title
+	m.modalLines = lines
+	m.modalActive = true
+	m.modalSel = 0
+	m.modalItems = nil
+	m.vp.YOffset = 0
+	m.setModalSel(sel)
+}
+
+// closeModal hides any modal and resets picker state.
+func (m *model) closeModal() {
+	m.modalActive = false
+	m.modalKind = ""
+	m.modalSel = 0
+	m.modalLines = nil
+	m.modalItems = nil
+	m.vp.YOffset = 0
+	m.ensureVisible()
+}
+
+// setModalSel clamps and applies the picker selection, scrolling it into view.
+func (m *model) setModalSel(idx int) {
+	n := len(m.modalLines)
+	if idx >= n {
+		idx = n - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	m.modalSel = idx
+	// The title occupies the first line of the modal.
+	row := idx
+	if m.modalTitle != "" {
+		row++
+	}
+	vh := m.viewportHeight()
+	if row < m.vp.YOffset {
+		m.vp.YOffset = row
+	} else if row >= m.vp.YOffset+vh {
+		m.vp.YOffset = row - vh + 1
+	}
+	if m.vp.YOffset < 0 {
+		m.vp.YOffset = 0
+	}
+}
+
+// onModalKey handles keys while a picker modal is open.
+func (m *model) onModalKey(msg tea.KeyMsg) tea.Cmd {
+	switch normalizeKey(msg.String()) {
+	case "esc", "q", "ctrl+c":
+		m.closeModal()
+	case "j", "down", "ctrl+n":
+		m.setModalSel(m.modalSel + 1)
+	case "k", "up", "ctrl+p":
+		m.setModalSel(m.modalSel - 1)
+	case "g", "home":
+		m.setModalSel(0)
+	case "G", "end":
+		m.setModalSel(len(m.modalLines) - 1)
+	case "ctrl+d", "pgdown":
+		m.setModalSel(m.modalSel + m.viewportHeight()/2)
+	case "ctrl+u", "pgup":
+		m.setModalSel(m.modalSel - m.viewportHeight()/2)
+	case "enter", "l", "right":
+		if len(m.modalLines) == 0 {
+			m.closeModal()
+			return nil
+		}
+		kind, idx, items := m.modalKind, m.modalSel, m.modalItems
+		m.closeModal()
+		return m.onPick(kind, idx, items)
+	default:
+		if m.modalKind == "bookmarks" {
+			return m.onBookmarksKey(normalizeKey(msg.String()))
+		}
+	}
+	return nil
+}
+
+// onPick runs the action for the picked line of a picker modal. items is the
+// picker's per-line payload, if any.
+func (m *model) onPick(kind string, idx int, items []string) tea.Cmd {
+	switch kind {
+	case "history":
+		t := m.focused()
+		m.historyJump(t, len(t.hist.items)-1-idx)
+		return m.maybePrefetchSelected()
+	case "bookmarks":
+		list := m.bookmarkList()
+		if idx < 0 || idx >= len(list) {
+			return nil
+		}
+		return m.jumpTo(list[idx].Path)
+	case "frecency":
+		if idx < 0 || idx >= len(items) {
+			return nil
+		}
+		return m.jumpTo(items[idx])
+	}
+	return nil
+}
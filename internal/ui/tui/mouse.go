@@ -0,0 +1,275 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickInterval is the longest pause between two clicks on the same
+// row that still counts as a double click.
+const doubleClickInterval = 400 * time.Millisecond
+
+// wheelStep is the number of rows one notch of the wheel scrolls.
+const wheelStep = 3
+
+// clickState remembers the last left click to detect double clicks.
+type clickState struct {
+	col, row int
+	at       time.Time
+}
+
+// screenColumn is a column of the main area as it is laid out on screen.
+type screenColumn struct {
+	x, width int
+	index    int // panel column (0 = the tab's own panel), -1 for the preview
+}
+
+// screenColumns mirrors the layout of tryRefreshMulti and renderDual: the
+// panel columns from left to right, followed by the preview if shown.
+func (m *model) screenColumns() []screenColumn {
+	totalW := m.vp.Width
+	if totalW <= 0 {
+		totalW = m.width
+	}
+	if totalW <= 0 {
+		totalW = 80
+	}
+	if m.dual {
+		leftW := (totalW - 1) / 2
+		if leftW < 10 {
+			leftW = 10
+		}
+		return []screenColumn{{x: 0, width: leftW, index: 0}, {x: leftW + 1, width: totalW - 1 - leftW, index: 1}}
+	}
+	cols := m.panelColumns()
+	var widths []int
+	prevW := 0
+	switch {
+	case len(cols) == 1 && !m.showPrev:
+		widths = []int{totalW}
+	case len(cols) == 1:
+		prevW = clampPreviewWidth(totalW*m.rightPct/100, totalW)
+		leftW := totalW - prevW - 1
+		if leftW < 10 {
+			leftW = 10
+		}
+		widths = []int{leftW}
+	case m.showPrev:
+		prevW = clampPreviewWidth(totalW*m.rightPct/100, totalW)
+		avail := totalW - prevW - 1
+		if avail < 10 {
+			avail = 10
+		}
+		tabs := make([]tab, len(cols))
+		for i, c := range cols {
+			tabs[i] = *c
+		}
+		widths = autoColumnWidths(tabs, avail, 1)
+	default:
+		tabs := make([]tab, len(cols))
+		for i, c := range cols {
+			tabs[i] = *c
+		}
+		widths = autoColumnWidths(tabs, totalW, 1)
+	}
+	out := make([]screenColumn, 0, len(widths)+1)
+	x := 0
+	for i, w := range widths {
+		out = append(out, screenColumn{x: x, width: w, index: i})
+		x += w + 1
+	}
+	if prevW > 0 {
+		out = append(out, screenColumn{x: x, width: prevW, index: -1})
+	}
+	return out
+}
+
+// clampPreviewWidth applies the bounds tryRefreshMulti uses for the preview.
+func clampPreviewWidth(w, totalW int) int {
+	if w < 10 {
+		w = 10
+	}
+	if w > totalW-10 {
+		w = totalW - 10
+	}
+	return w
+}
+
+// panelColumns returns the panel columns shown in the main area: the
+// active tab followed by its Miller columns or the second panel.
+func (m *model) panelColumns() []*tab {
+	cols := []*tab{m.current()}
+	if m.rightMode != "panel" {
+		return cols
+	}
+	for i := range m.rightCols {
+		cols = append(cols, &m.rightCols[i])
+	}
+	if len(m.rightCols) == 0 && m.rightT.panel != nil {
+		cols = append(cols, &m.rightT)
+	}
+	return cols
+}
+
+// columnAt returns the screen column containing x, or false for the
+// separators between columns.
+func (m *model) columnAt(x int) (screenColumn, bool) {
+	for _, c := range m.screenColumns() {
+		if x >= c.x && x < c.x+c.width {
+			return c, true
+		}
+	}
+	return screenColumn{}, false
+}
+
+// headerTabAt returns the tab whose label in the header covers x, or -1.
+func (m *model) headerTabAt(x int) int {
+	pos := len(tabBarPrefix)
+	if m.dual {
+		if len(m.tabs) < 2 {
+			return -1
+		}
+		pos = 0
+	}
+	for i, item := range tabBarItems(m) {
+		w := lipgloss.Width(item)
+		if x >= pos && x < pos+w {
+			return i
+		}
+		pos += w
+	}
+	return -1
+}
+
+// onMouse handles clicks and the wheel: a click selects the entry under the
+// pointer in whichever column it lands, a double click opens it like l, the
+// wheel scrolls the column or file preview under the pointer and a click on
+// a tab in the header switches to it.
+func (m *model) onMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.modalActive {
+		// The viewport only holds the rendered window; scroll relative to it.
+		var cmd tea.Cmd
+		m.vp.YOffset -= m.winStart
+		m.vp, cmd = m.vp.Update(msg)
+		m.vp.YOffset += m.winStart
+		return cmd
+	}
+	if m.cmdActive || msg.Action != tea.MouseActionPress {
+		return nil
+	}
+	if msg.Y < m.header {
+		if msg.Button == tea.MouseButtonLeft {
+			if i := m.headerTabAt(msg.X); i >= 0 {
+				m.switchTab(i)
+			}
+		}
+		return nil
+	}
+	row := msg.Y - m.header
+	if row >= m.viewportHeight() {
+		return nil
+	}
+	col, ok := m.columnAt(msg.X)
+	if !ok {
+		return nil
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		delta := wheelStep
+		if msg.Button == tea.MouseButtonWheelUp {
+			delta = -wheelStep
+		}
+		if col.index < 0 {
+			m.scrollPreview(delta)
+		} else {
+			m.scrollColumn(col.index, delta)
+		}
+	case tea.MouseButtonLeft:
+		if col.index < 0 {
+			if !m.pvFocus {
+				m.togglePreviewFocus()
+			}
+			return nil
+		}
+		now := time.Now()
+		double := m.lastClick.col == col.index && m.lastClick.row == row &&
+			now.Sub(m.lastClick.at) <= doubleClickInterval
+		m.lastClick = clickState{col: col.index, row: row, at: now}
+		if !m.clickEntry(col.index, row) {
+			return nil
+		}
+		if double {
+			m.lastClick = clickState{}
+			return m.doAction("right")
+		}
+		return m.maybePrefetchSelected()
+	}
+	return nil
+}
+
+// clickEntry focuses panel column index and selects the entry on screen row
+// row. Miller columns to the right of the clicked one are closed. It reports
+// whether an entry was hit.
+func (m *model) clickEntry(index, row int) bool {
+	m.focusColumn(index)
+	t := m.focused()
+	i := m.vp.YOffset + row
+	if t.panel == nil || i >= len(t.panel.Entries) {
+		return false
+	}
+	m.setSelected(i)
+	return true
+}
+
+// focusColumn moves the focus to panel column index (0 = the tab's own
+// panel), keeping every column's scroll offset.
+func (m *model) focusColumn(index int) {
+	m.pvFocus = false
+	if index == 0 {
+		m.setFocus("left")
+		return
+	}
+	if m.dual {
+		m.setFocus("right")
+		return
+	}
+	m.focused().scroll = m.vp.YOffset
+	if n := len(m.rightCols); index < n {
+		for i := n - 1; i >= index; i-- {
+			m.rememberCursor(&m.rightCols[i])
+		}
+		m.dropColumns(index)
+	}
+	m.focus = "right"
+	m.vp.YOffset = m.focused().scroll
+}
+
+// scrollColumn scrolls panel column index by delta rows without moving its
+// cursor.
+func (m *model) scrollColumn(index, delta int) {
+	cols := m.panelColumns()
+	if m.dual {
+		cols = []*tab{m.current(), &m.rightT}
+	}
+	t := m.focused()
+	if index >= 0 && index < len(cols) {
+		t = cols[index]
+	}
+	off := t.scroll
+	if t == m.focused() {
+		off = m.vp.YOffset
+	}
+	off += delta
+	if last := len(t.panel.Entries) - m.viewportHeight(); off > last {
+		off = last
+	}
+	if off < 0 {
+		off = 0
+	}
+	t.scroll = off
+	if t == m.focused() {
+		m.vp.YOffset = off
+	}
+}
@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newMouseModel returns a model over a directory with a, b, a/inner and 40
+// files, laid out with a one-line header and status on a 20-line screen.
+func newMouseModel(t *testing.T) (*model, string) {
+	t.Helper()
+	m, root := newHistoryModel(t)
+	for i := 0; i < 40; i++ {
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("f%02d", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.header, m.status, m.width = 1, 1, 80
+	if err := m.current().panel.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return m, root
+}
+
+func press(m *model, b tea.MouseButton, x, y int) tea.Cmd {
+	return m.onMouse(tea.MouseMsg{X: x, Y: y, Button: b, Action: tea.MouseActionPress})
+}
+
+func TestMouseClickSelectsAndDoubleClickOpens(t *testing.T) {
+	m, root := newMouseModel(t)
+	press(m, tea.MouseButtonLeft, 5, 1+2)
+	if got := selectedName(m.current()); got != "f00" {
+		t.Fatalf("click selected %q; want f00", got)
+	}
+	// A click below the last entry changes nothing.
+	m.vp.YOffset = 30
+	press(m, tea.MouseButtonLeft, 5, 1+15)
+	if got := selectedName(m.current()); got != "f00" {
+		t.Fatalf("click past the end selected %q", got)
+	}
+	m.vp.YOffset = 0
+	press(m, tea.MouseButtonLeft, 5, 1+1)
+	press(m, tea.MouseButtonLeft, 5, 1+1)
+	drain(m)
+	if got := m.current().panel.Cwd; got != filepath.Join(root, "b") {
+		t.Fatalf("double click opened %q; want b", got)
+	}
+}
+
+func TestMouseWheelScrollsColumnUnderPointer(t *testing.T) {
+	m, _ := newMouseModel(t)
+	m.setLayout(true)
+	drain(m)
+	m.refreshContent()
+	// The right panel scrolls on its own.
+	press(m, tea.MouseButtonWheelDown, 60, 5)
+	press(m, tea.MouseButtonWheelDown, 60, 5)
+	if m.rightT.scroll != 2*wheelStep || m.vp.YOffset != 0 || m.focus != "left" {
+		t.Fatalf("right wheel: right %d, left %d, focus %s", m.rightT.scroll, m.vp.YOffset, m.focus)
+	}
+	press(m, tea.MouseButtonWheelDown, 5, 5)
+	if m.vp.YOffset != wheelStep {
+		t.Fatalf("left wheel: offset %d; want %d", m.vp.YOffset, wheelStep)
+	}
+	// Scrolling stops at the last page.
+	for i := 0; i < 20; i++ {
+		press(m, tea.MouseButtonWheelDown, 60, 5)
+	}
+	if want := len(m.rightT.panel.Entries) - m.viewportHeight(); m.rightT.scroll != want {
+		t.Fatalf("right scroll %d; want %d", m.rightT.scroll, want)
+	}
+	// A click selects relative to the panel's own offset.
+	press(m, tea.MouseButtonLeft, 60, 1)
+	if m.focus != "right" || m.rightT.selected != m.rightT.scroll {
+		t.Fatalf("click in right panel: focus %s, selected %d, scroll %d", m.focus, m.rightT.selected, m.rightT.scroll)
+	}
+	if m.current().scroll != wheelStep {
+		t.Fatalf("left panel lost its offset: %d", m.current().scroll)
+	}
+}
+
+func TestMouseClickMillerColumns(t *testing.T) {
+	m, root := newMouseModel(t)
+	m.openRight = true
+	m.setSelected(0) // a
+	m.enter()
+	drain(m)
+	m.enter() // a/inner
+	drain(m)
+	if len(m.rightCols) != 2 || m.focus != "right" {
+		t.Fatalf("columns %d, focus %s", len(m.rightCols), m.focus)
+	}
+	cols := m.screenColumns()
+	if len(cols) != 3 {
+		t.Fatalf("screen columns = %+v", cols)
+	}
+	// Clicking the middle column closes the one to its right.
+	press(m, tea.MouseButtonLeft, cols[1].x, 1)
+	if len(m.rightCols) != 1 || m.focused().panel.Cwd != filepath.Join(root, "a") {
+		t.Fatalf("after middle click: %d columns, focused %s", len(m.rightCols), m.focused().panel.Cwd)
+	}
+	press(m, tea.MouseButtonLeft, cols[0].x, 1+1)
+	if m.focus != "left" || selectedName(m.current()) != "b" {
+		t.Fatalf("left click: focus %s, selected %q", m.focus, selectedName(m.current()))
+	}
+	// Separators are not part of any column.
+	if _, ok := m.columnAt(cols[0].width); ok {
+		t.Fatalf("separator at x=%d hit a column", cols[0].width)
+	}
+}
+
+func TestMouseClickHeaderSwitchesTab(t *testing.T) {
+	m, _ := newMouseModel(t)
+	m.newTab()
+	m.newTab()
+	if m.active != 2 {
+		t.Fatalf("active = %d", m.active)
+	}
+	items := tabBarItems(m)
+	x := len(tabBarPrefix) + len(items[0]) + 1
+	press(m, tea.MouseButtonLeft, x, 0)
+	if m.active != 1 {
+		t.Fatalf("header click activated %d; want 1", m.active)
+	}
+	press(m, tea.MouseButtonLeft, 1, 0)
+	if m.active != 1 {
+		t.Fatalf("click on the prefix switched to %d", m.active)
+	}
+}
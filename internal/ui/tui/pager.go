@@ -0,0 +1,398 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// classMatch is the highlight class of search matches in the preview,
+// rendered with the selection style. It follows the syntax classes.
+const classMatch = preview.NumClasses
+
+// previewFocused reports whether keys page through the preview rather than
+// move the cursor.
+func (m *model) previewFocused() bool {
+	return m.pvFocus && m.showPrev && !m.dual
+}
+
+// togglePreviewFocus moves the keys to the preview of the selected file and
+// back. Directories are not paged.
+func (m *model) togglePreviewFocus() {
+	if m.pvFocus {
+		m.pvFocus = false
+		return
+	}
+	t := m.focused()
+	if !m.showPrev || m.dual || t.panel == nil || t.selected < 0 || t.selected >= len(t.panel.Entries) {
+		return
+	}
+	e := t.panel.Entries[t.selected]
+	if e.IsDir {
+		return
+	}
+	m.previewFor(filepath.Join(t.panel.Cwd, e.Name))
+	m.pvFocus = true
+}
+
+// pagerAction handles act while the preview is focused, like a pager:
+// motions scroll the preview, / searches it and h or q return to the list.
+// It reports false for actions that keep their usual meaning.
+func (m *model) pagerAction(act string) (tea.Cmd, bool) {
+	page := m.viewportHeight() - 1
+	switch act {
+	case "down":
+		m.scrollPreview(1)
+	case "up":
+		m.scrollPreview(-1)
+	case "half-page-down":
+		m.scrollPreview(page / 2)
+	case "half-page-up":
+		m.scrollPreview(-page / 2)
+	case "page-down":
+		m.scrollPreview(page)
+	case "page-up":
+		m.scrollPreview(-page)
+	case "top":
+		m.pv.scroll = 0
+	case "bottom":
+		// Clamped to the end of the file when rendering.
+		m.pv.scroll = math.MaxInt32
+	case "left", "quit", "focus-preview":
+		m.pvFocus = false
+	case "right":
+	case "filter":
+		m.openPrompt("preview-search", "", "/", "")
+	case "search-next":
+		m.searchPreview(1)
+	case "search-prev":
+		m.searchPreview(-1)
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// setPreviewQuery starts a search in the preview for q, ignoring case
+// unless q has upper-case letters, and moves to the first match.
+func (m *model) setPreviewQuery(q string) {
+	st := &m.pv
+	if q == "" {
+		st.query, st.re = "", nil
+		return
+	}
+	expr := regexp.QuoteMeta(q)
+	if strings.ToLower(q) == q {
+		expr = "(?i)" + expr
+	}
+	st.query, st.re = q, regexp.MustCompile(expr)
+	m.searchPreviewFrom(st.scroll, 1)
+}
+
+// searchPreview scrolls to the next (dir 1) or previous (dir -1) line
+// matching the query.
+func (m *model) searchPreview(dir int) {
+	m.searchPreviewFrom(m.pv.scroll+dir, dir)
+}
+
+// searchPreviewFrom scrolls to the first line matching the query at or
+// after (dir 1) or before (dir -1) line from, wrapping around the end of
+// the file. Lines are read as far as needed.
+func (m *model) searchPreviewFrom(from, dir int) {
+	st := &m.pv
+	if st.re == nil || st.path == "" {
+		return
+	}
+	view := m.previewView(st)
+	if view == "hex" {
+		m.setError(fmt.Errorf("search works in the text and info views (v)"))
+		return
+	}
+	text := m.previewLines(st, view)
+	match := func(i int) bool { return st.re.MatchString(expandTabs(text.Line(i))) }
+	if dir > 0 {
+		for i := max(from, 0); i < text.Ensure(i+1); i++ {
+			if match(i) {
+				st.scroll = i
+				return
+			}
+		}
+		for i := 0; i < from && i < text.Len(); i++ {
+			if match(i) {
+				st.scroll = i
+				return
+			}
+		}
+	} else {
+		n := text.Ensure(math.MaxInt32)
+		for i := min(from, n-1); i >= 0; i-- {
+			if match(i) {
+				st.scroll = i
+				return
+			}
+		}
+		for i := n - 1; i > from && i >= 0; i-- {
+			if match(i) {
+				st.scroll = i
+				return
+			}
+		}
+	}
+	m.setError(fmt.Errorf("pattern not found: %s", st.query))
+}
+
+// previewLines returns the lines of the text or info view of st, loading
+// them on first use.
+func (m *model) previewLines(st *previewState, view string) *preview.Lines {
+	if st.text != nil && st.textView == view {
+		return st.text
+	}
+	m.saveSpans(st)
+	st.textView, st.lex, st.spans, st.lexed, st.saved = view, nil, nil, 0, 0
+	if view == "info" {
+		res, err := preview.Info(st.path)
+		if err != nil {
+			res.Content = err.Error()
+		}
+		st.text = preview.StaticLines(res.Content)
+		return st.text
+	}
+	res := m.providerResult(st.path)
+	switch {
+	case res.Kind == "loading":
+		// Made again once the preview is ready: no view is called "".
+		st.text, st.textView = preview.StaticLines("loading…"), ""
+		return st.text
+	case res.Kind == "text":
+		st.text = preview.NewLines(st.path, false)
+		if res.Lang != "" {
+			st.lex = preview.NewLexer(res.Lang)
+		}
+		if st.lex != nil {
+			st.spans = m.loadSpans(st.path)
+			st.saved = len(st.spans)
+		}
+	case res.Kind == "info" && res.Mime == "application/octet-stream":
+		// Binary file: show its printable bytes.
+		st.text = preview.NewLines(st.path, true)
+	default:
+		st.text = preview.StaticLines(res.Content)
+	}
+	return st.text
+}
+
+// lineSpans returns line i of the text of st, highlighted. Lines are
+// highlighted in order since the lexer keeps state across them.
+func (st *previewState) lineSpans(i int) []preview.Span {
+	if i < len(st.spans) {
+		return st.spans[i]
+	}
+	if st.lex != nil && st.lexed < len(st.spans) {
+		// The lines read from disk bring the lexer to its state after them.
+		st.text.Ensure(len(st.spans))
+		for ; st.lexed < len(st.spans); st.lexed++ {
+			st.lex.Line(expandTabs(st.text.Line(st.lexed)))
+		}
+	}
+	for n := len(st.spans); n <= i; n++ {
+		l := expandTabs(st.text.Line(n))
+		if st.lex != nil {
+			st.spans = append(st.spans, st.lex.Line(l))
+			st.lexed++
+		} else {
+			st.spans = append(st.spans, []preview.Span{{Text: l}})
+		}
+	}
+	return st.spans[i]
+}
+
+// Highlighted lines are kept in the disk tier of the preview cache under
+// highlightWidth, a width no preview is rendered at, up to
+// highlightCacheLines per file.
+const (
+	highlightWidth      = -1
+	highlightCacheLines = 2000
+)
+
+// loadSpans returns the highlighted lines of path kept on disk, if any.
+func (m *model) loadSpans(path string) [][]preview.Span {
+	d := m.fileCache.Disk()
+	if d == nil {
+		return nil
+	}
+	res, ok := d.Get(path, highlightWidth)
+	if !ok || res.Kind != "highlight" {
+		return nil
+	}
+	var spans [][]preview.Span
+	if json.Unmarshal([]byte(res.Content), &spans) != nil {
+		return nil
+	}
+	return spans
+}
+
+// saveSpans writes the lines of st highlighted so far to the disk tier
+// when there are more than it holds.
+func (m *model) saveSpans(st *previewState) {
+	d := m.fileCache.Disk()
+	n := min(len(st.spans), highlightCacheLines)
+	if d == nil || st.lex == nil || n <= st.saved {
+		return
+	}
+	b, err := json.Marshal(st.spans[:n])
+	if err != nil {
+		return
+	}
+	if d.Put(st.path, highlightWidth, preview.Result{Kind: "highlight", Content: string(b)}) == nil {
+		st.saved = n
+	}
+}
+
+func expandTabs(s string) string { return strings.ReplaceAll(s, "\t", "    ") }
+
+// renderTextBody renders rows screen rows of text from the scroll position
+// of st, reading further chunks of the file as needed. Search matches are
+// highlighted and long lines wrapped when wrapping is on.
+func (m *model) renderTextBody(st *previewState, text *preview.Lines, width, rows int) []string {
+	text.Ensure(st.scroll + rows)
+	i := m.clampTextScroll(st, text, width, rows)
+	out := make([]string, 0, rows)
+	for ; i < text.Len() && len(out) < rows; i++ {
+		spans := markMatches(st.lineSpans(i), st.re)
+		if !m.pvWrap {
+			out = append(out, m.renderSpans(spans, width))
+			continue
+		}
+		for _, seg := range wrapSpans(spans, width) {
+			if len(out) >= rows {
+				break
+			}
+			out = append(out, m.renderSpans(seg, width))
+		}
+	}
+	if i == text.Len() && len(out) < rows {
+		switch {
+		case text.Err() != nil:
+			out = append(out, m.styStatus.Render(trimToWidth(text.Err().Error(), width)))
+		case text.Truncated():
+			out = append(out, m.styStatus.Render(trimToWidth(fmt.Sprintf("… (first %s)", humanBytes(preview.LinesMaxBytes)), width)))
+		}
+	}
+	return out
+}
+
+// clampTextScroll keeps the end of the text at the bottom of the preview
+// once the whole file has been read, and returns the first line to show.
+func (m *model) clampTextScroll(st *previewState, text *preview.Lines, width, rows int) int {
+	if !text.EOF() {
+		return st.scroll
+	}
+	if !m.pvWrap {
+		return st.clampScroll(text.Len(), rows)
+	}
+	h := 0
+	for i := text.Len() - 1; i >= 0; i-- {
+		h += wrappedRows(expandTabs(text.Line(i)), width)
+		if h > rows {
+			st.scroll = min(st.scroll, i+1)
+			return st.scroll
+		}
+	}
+	st.scroll = 0
+	return 0
+}
+
+// wrappedRows is the number of rows line takes when wrapped at width.
+func wrappedRows(line string, width int) int {
+	n := len([]rune(line))
+	if n == 0 || width < 1 {
+		return 1
+	}
+	return (n + width - 1) / width
+}
+
+// wrapSpans splits a highlighted line into rows of at most width runes.
+func wrapSpans(spans []preview.Span, width int) [][]preview.Span {
+	var rows [][]preview.Span
+	var row []preview.Span
+	used := 0
+	for _, sp := range spans {
+		r := []rune(sp.Text)
+		for len(r) > 0 {
+			if used == width {
+				rows = append(rows, row)
+				row, used = nil, 0
+			}
+			n := min(width-used, len(r))
+			row = append(row, preview.Span{Text: string(r[:n]), Class: sp.Class})
+			used += n
+			r = r[n:]
+		}
+	}
+	return append(rows, row)
+}
+
+// markMatches splits spans at the matches of re and gives the matched parts
+// classMatch.
+func markMatches(spans []preview.Span, re *regexp.Regexp) []preview.Span {
+	if re == nil {
+		return spans
+	}
+	var b strings.Builder
+	for _, sp := range spans {
+		b.WriteString(sp.Text)
+	}
+	locs := re.FindAllStringIndex(b.String(), -1)
+	if len(locs) == 0 {
+		return spans
+	}
+	out := make([]preview.Span, 0, len(spans)+2*len(locs))
+	pos := 0 // offset of the rest of the current span in the line
+	for _, sp := range spans {
+		text := sp.Text
+		for len(text) > 0 {
+			cut, class := len(text), sp.Class
+			for _, l := range locs {
+				if pos >= l[0] && pos < l[1] {
+					cut, class = min(cut, l[1]-pos), classMatch
+					break
+				}
+				if l[0] > pos {
+					cut = min(cut, l[0]-pos)
+					break
+				}
+			}
+			out = append(out, preview.Span{Text: text[:cut], Class: class})
+			text = text[cut:]
+			pos += cut
+		}
+	}
+	return out
+}
+
+// pagerStatus describes the focused preview for the status line.
+func (m *model) pagerStatus() string {
+	st := &m.pv
+	s := "preview"
+	if st.text != nil && st.textView == m.previewView(st) {
+		total := fmt.Sprint(st.text.Len())
+		if !st.text.EOF() {
+			total += "+"
+		}
+		s = fmt.Sprintf("preview %d/%s", min(st.scroll+1, st.text.Len()), total)
+	}
+	if m.pvWrap {
+		s += " wrap"
+	}
+	if st.query != "" {
+		s += " /" + st.query
+	}
+	return s + "  [j/k] scroll  [/] search  [n/N] next/prev  [h] back"
+}
@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// newPagerModel returns a model previewing log.txt, a file of 3000 numbered
+// lines (far more than the provider reads), with the cursor on it.
+func newPagerModel(t *testing.T) (*model, string) {
+	t.Helper()
+	m, root := newHistoryModel(t)
+	var b strings.Builder
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	b.WriteString("needle at the end")
+	path := filepath.Join(root, "log.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.current().panel.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m.fileCache = uicache.NewFileCache(64, 0)
+	m.prevProv = preview.BasicProvider{}
+	m.computeStyles()
+	m.showPrev, m.rightMode = true, "preview"
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "log.txt"))
+	return m, path
+}
+
+func body(m *model, path string, width, rows int) []string {
+	out := m.renderFilePreviewBody(path, width, rows)
+	for i := range out {
+		out[i] = strings.TrimRight(stripANSI(out[i]), " ")
+	}
+	return out
+}
+
+func TestPagerScrollsAndReadsOnDemand(t *testing.T) {
+	m, path := newPagerModel(t)
+	m.doAction("focus-preview")
+	if !m.previewFocused() {
+		t.Fatalf("preview not focused")
+	}
+	m.doAction("down")
+	m.doAction("down")
+	if got := body(m, path, 40, 3)[0]; got != "line 3" {
+		t.Fatalf("after jj top line = %q", got)
+	}
+	if sel := selectedName(m.current()); sel != "log.txt" {
+		t.Fatalf("cursor moved to %q while the preview was focused", sel)
+	}
+	m.doAction("bottom")
+	if got := body(m, path, 40, 3); got[2] != "needle at the end" || got[0] != "line 2999" {
+		t.Fatalf("G shows %q", got)
+	}
+	if !m.pv.text.EOF() {
+		t.Fatalf("G did not read the whole file")
+	}
+	m.doAction("top")
+	if got := body(m, path, 40, 3)[0]; got != "line 1" {
+		t.Fatalf("gg shows %q", got)
+	}
+	m.doAction("half-page-down")
+	if m.pv.scroll != (m.viewportHeight()-1)/2 {
+		t.Fatalf("ctrl+d scrolled to %d", m.pv.scroll)
+	}
+	m.doAction("left")
+	if m.previewFocused() {
+		t.Fatalf("h did not leave the preview")
+	}
+	m.doAction("down")
+	if m.pv.scroll != (m.viewportHeight()-1)/2 {
+		t.Fatalf("j scrolled the unfocused preview")
+	}
+}
+
+func TestPagerSearch(t *testing.T) {
+	m, path := newPagerModel(t)
+	m.doAction("focus-preview")
+	m.setPreviewQuery("LINE 25")
+	if m.err == nil {
+		t.Fatalf("upper-case query should match case-sensitively")
+	}
+	m.err = nil
+	m.setPreviewQuery("line 25")
+	if m.pv.scroll != 24 {
+		t.Fatalf("first match at line %d; want 25", m.pv.scroll+1)
+	}
+	m.doAction("search-next")
+	if m.pv.scroll != 249 {
+		t.Fatalf("n moved to line %d; want 250", m.pv.scroll+1)
+	}
+	m.doAction("search-prev")
+	m.doAction("search-prev")
+	// "line 2599" is the last line containing "line 25".
+	if m.pv.scroll != 2598 {
+		t.Fatalf("N did not wrap around: line %d; want 2599", m.pv.scroll+1)
+	}
+	m.setPreviewQuery("NEEDLE")
+	if m.err == nil {
+		t.Fatalf("case-sensitive query matched")
+	}
+	m.err = nil
+	m.setPreviewQuery("needle")
+	if got := body(m, path, 40, 3); got[len(got)-1] != "needle at the end" {
+		t.Fatalf("match past the first chunk not shown: %q", got)
+	}
+}
+
+func TestMarkMatchesAndWrap(t *testing.T) {
+	spans := []preview.Span{{Text: "foo bar", Class: preview.Keyword}, {Text: "bar", Class: preview.String}}
+	got := markMatches(spans, regexp.MustCompile("o b|rb"))
+	want := []preview.Span{
+		{Text: "fo", Class: preview.Keyword},
+		{Text: "o b", Class: classMatch},
+		{Text: "a", Class: preview.Keyword},
+		{Text: "r", Class: classMatch},
+		{Text: "b", Class: classMatch},
+		{Text: "ar", Class: preview.String},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("markMatches = %v; want %v", got, want)
+	}
+	rows := wrapSpans(spans, 4)
+	if len(rows) != 3 || rows[1][0].Text != "bar" || rows[1][1].Text != "b" || rows[2][0].Text != "ar" {
+		t.Fatalf("wrapSpans = %v", rows)
+	}
+	if n := len(wrapSpans(nil, 4)); n != 1 {
+		t.Fatalf("empty line wraps to %d rows", n)
+	}
+}
+
+func TestPagerWrap(t *testing.T) {
+	m, _ := newPagerModel(t)
+	path := filepath.Join(t.TempDir(), "long.txt")
+	_ = os.WriteFile(path, []byte(strings.Repeat("x", 25)+"\nend"), 0o644)
+	if got := body(m, path, 10, 4); len(got) != 2 || got[0] != "xxxxxxxxx…" {
+		t.Fatalf("unwrapped = %q", got)
+	}
+	m.doAction("toggle-preview-wrap")
+	if got := body(m, path, 10, 4); len(got) != 4 || got[2] != "xxxxx" || got[3] != "end" {
+		t.Fatalf("wrapped = %q", got)
+	}
+	// Scrolling past the end stops at the first line whose rows fit.
+	m.pv.scroll = 5
+	if got := body(m, path, 10, 3); len(got) != 1 || got[0] != "end" || m.pv.scroll != 1 {
+		t.Fatalf("wrapped end = %q (scroll %d)", got, m.pv.scroll)
+	}
+}
@@ -0,0 +1,100 @@
+package tui
+
+// positionsMax bounds the number of directories whose cursor is remembered.
+const positionsMax = 256
+
+// cursorPos is the remembered cursor state for one directory.
+type cursorPos struct {
+	name     string // selected entry name (preferred on restore)
+	selected int    // fallback index if the entry is gone
+	scroll   int    // viewport offset
+}
+
+// positions remembers the cursor per directory with LRU eviction.
+// order holds keys from least to most recently used.
+type positions struct {
+	m     map[string]cursorPos
+	order []string
+	max   int
+}
+
+func newPositions(max int) positions {
+	if max <= 0 {
+		max = positionsMax
+	}
+	return positions{m: make(map[string]cursorPos), max: max}
+}
+
+// get returns the remembered cursor for dir and marks it recently used.
+func (p *positions) get(dir string) (cursorPos, bool) {
+	v, ok := p.m[dir]
+	if ok {
+		p.touch(dir)
+	}
+	return v, ok
+}
+
+// put stores the cursor for dir, evicting the least recently used entry.
+func (p *positions) put(dir string, v cursorPos) {
+	if p.m == nil {
+		*p = newPositions(p.max)
+	}
+	if _, ok := p.m[dir]; ok {
+		p.m[dir] = v
+		p.touch(dir)
+		return
+	}
+	if len(p.order) >= p.max {
+		delete(p.m, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.order = append(p.order, dir)
+	p.m[dir] = v
+}
+
+func (p *positions) touch(dir string) {
+	for i, k := range p.order {
+		if k == dir {
+			copy(p.order[i:], p.order[i+1:])
+			p.order[len(p.order)-1] = dir
+			return
+		}
+	}
+}
+
+// rememberCursor stores t's cursor for its current directory.
+func (m *model) rememberCursor(t *tab) {
+	if t == nil || t.panel == nil || t.panel.Cwd == "" {
+		return
+	}
+	scroll := t.scroll
+	if t == m.focused() {
+		scroll = m.vp.YOffset
+	}
+	m.positions.put(t.panel.Cwd, cursorPos{name: selectedName(t), selected: t.selected, scroll: scroll})
+}
+
+// restoreCursor applies the remembered cursor for t's directory, if any.
+// It returns false when nothing was remembered.
+func (m *model) restoreCursor(t *tab) bool {
+	pos, ok := m.positions.get(t.panel.Cwd)
+	if !ok {
+		return false
+	}
+	idx := indexOfEntry(t.panel.Entries, pos.name)
+	if idx < 0 {
+		idx = pos.selected
+	}
+	if n := len(t.panel.Entries); idx >= n {
+		idx = n - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	t.selected = idx
+	t.scroll = pos.scroll
+	if t == m.focused() {
+		m.vp.YOffset = pos.scroll
+	}
+	return true
+}
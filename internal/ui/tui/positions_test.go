@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+)
+
+func TestPositionsLRU(t *testing.T) {
+	p := newPositions(2)
+	p.put("/a", cursorPos{name: "x"})
+	p.put("/b", cursorPos{name: "y"})
+	if _, ok := p.get("/a"); !ok { // bumps /a
+		t.Fatalf("/a should be remembered")
+	}
+	p.put("/c", cursorPos{name: "z"})
+	if _, ok := p.get("/b"); ok {
+		t.Fatalf("/b should be evicted as least recently used")
+	}
+	if v, ok := p.get("/a"); !ok || v.name != "x" {
+		t.Fatalf("/a = %#v, %v", v, ok)
+	}
+	p.put("/a", cursorPos{name: "w"})
+	if v, _ := p.get("/a"); v.name != "w" {
+		t.Fatalf("put should overwrite: %#v", v)
+	}
+}
+
+func TestUpSelectsChildAndReentryRestoresCursor(t *testing.T) {
+	m, root := newHistoryModel(t)
+	a := filepath.Join(root, "a")
+	for _, f := range []string{"f1", "f2", "f3"} {
+		if err := os.WriteFile(filepath.Join(a, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.positions = newPositions(8)
+	m.enter() // into "a": inner/, f1, f2, f3
+	drain(m)
+	m.setSelected(2)
+	m.up()
+	drain(m)
+	if got := selectedName(m.current()); got != "a" {
+		t.Fatalf("after up selected %q; want a", got)
+	}
+	m.enter()
+	drain(m)
+	if got := selectedName(m.current()); got != "f2" {
+		t.Fatalf("after re-entry selected %q; want f2", got)
+	}
+	// Removing the remembered entry falls back to its index.
+	if err := os.Remove(filepath.Join(a, "f2")); err != nil {
+		t.Fatal(err)
+	}
+	m.up()
+	drain(m)
+	m.dirCache = uicache.NewDirCache(8) // drop the cached listing of a
+	m.enter()
+	drain(m)
+	if got := m.current().selected; got != 2 {
+		t.Fatalf("fallback index = %d; want 2", got)
+	}
+}
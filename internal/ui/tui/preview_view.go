@@ -0,0 +1,227 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/archive"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// previewViews are the views of a file preview, in the order v cycles them.
+var previewViews = []string{"text", "hex", "info"}
+
+// previewState is how the file in the preview column is shown. It belongs
+// to one path and is reset when another file is previewed.
+type previewState struct {
+	path   string
+	view   string // "" picks by content: hex for binaries, text otherwise
+	scroll int    // first body row (line of the text views) shown
+
+	// text and info views, read as they are scrolled (see pager.go)
+	text     *preview.Lines
+	textView string           // view text was loaded for
+	lex      *preview.Lexer   // highlighter of text, nil for plain text
+	spans    [][]preview.Span // highlighted text lines, in order
+	lexed    int              // lines fed to lex; spans read from disk were not
+	saved    int              // spans on disk (see saveSpans)
+
+	query string         // search in the preview
+	re    *regexp.Regexp // compiled query
+
+	meta     []string // image metadata shown under the picture
+	metaRead bool
+}
+
+// previewFor returns the preview state of path, starting afresh (and
+// leaving the preview) when the previewed file changed.
+func (m *model) previewFor(path string) *previewState {
+	if m.pv.path != path {
+		m.saveSpans(&m.pv)
+		m.pv = previewState{path: path}
+		m.pvFocus = false
+	}
+	return &m.pv
+}
+
+// providerResult returns the provider's preview of path. Provider output
+// does not depend on the pane width, so it is cached at width 0. Archives
+// are listed and images decoded in the background: until the preview is
+// ready the result has Kind "loading".
+func (m *model) providerResult(path string) preview.Result {
+	res, ok := m.fileCache.Get(path, 0)
+	if ok {
+		return res
+	}
+	if archive.Detect(path) != "" || isImagePath(path) {
+		m.previewInBackground(path)
+		return preview.Result{Kind: "loading"}
+	}
+	res, _ = m.prevProv.Preview(path, 8192)
+	m.fileCache.Put(path, 0, res)
+	return res
+}
+
+// previewResultMsg delivers a preview made in the background.
+type previewResultMsg struct {
+	path string
+	res  preview.Result
+}
+
+// previewInBackground queues the provider's preview of path, once.
+func (m *model) previewInBackground(path string) {
+	if _, inflight := m.prefetching[path]; inflight {
+		return
+	}
+	m.prefetching[path] = struct{}{}
+	prov := m.prevProv
+	m.queue(func() tea.Msg {
+		res, _ := prov.Preview(path, 8192)
+		return previewResultMsg{path: path, res: res}
+	})
+}
+
+// onPreviewResult stores a preview made in the background.
+func (m *model) onPreviewResult(msg previewResultMsg) {
+	delete(m.prefetching, msg.path)
+	m.fileCache.Put(msg.path, 0, msg.res)
+}
+
+// imageMeta returns the metadata lines of the image previewed by st, read
+// once per file.
+func (st *previewState) imageMeta() []string {
+	if !st.metaRead {
+		st.metaRead = true
+		if meta, err := preview.ReadImageMeta(st.path); err == nil {
+			st.meta = meta.Lines()
+		}
+	}
+	return st.meta
+}
+
+// previewView resolves the view of st: the one chosen with v or :preview,
+// or hex for files the provider could not show as text.
+func (m *model) previewView(st *previewState) string {
+	if st.view != "" {
+		return st.view
+	}
+	if res := m.providerResult(st.path); res.Kind == "info" && res.Mime == "application/octet-stream" {
+		return "hex"
+	}
+	return "text"
+}
+
+// setPreviewView switches the previewed file to view ("" = automatic) and
+// scrolls back to its start.
+func (m *model) setPreviewView(view string) {
+	m.pv.view, m.pv.scroll = view, 0
+}
+
+// cyclePreviewView moves the previewed file to the next view.
+func (m *model) cyclePreviewView() {
+	if m.pv.path == "" {
+		return
+	}
+	cur := m.previewView(&m.pv)
+	for i, v := range previewViews {
+		if v == cur {
+			m.setPreviewView(previewViews[(i+1)%len(previewViews)])
+			return
+		}
+	}
+}
+
+// scrollPreview scrolls the previewed file by delta body rows. The upper
+// bound depends on the view and pane size and is applied when rendering.
+func (m *model) scrollPreview(delta int) {
+	m.pv.scroll += delta
+	if m.pv.scroll < 0 {
+		m.pv.scroll = 0
+	}
+}
+
+// previewCommand implements :preview on|off|toggle|wrap|text|hex|info|auto.
+func (m *model) previewCommand(args []string) {
+	arg := "toggle"
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	switch arg {
+	case "toggle":
+		m.togglePreview()
+	case "on", "off":
+		if m.showPrev != (arg == "on") {
+			m.togglePreview()
+		}
+	case "wrap":
+		m.pvWrap = !m.pvWrap
+	case "text", "hex", "info", "auto":
+		if arg == "auto" {
+			arg = ""
+		}
+		m.setPreviewView(arg)
+		if !m.showPrev {
+			m.togglePreview()
+		}
+	default:
+		m.setError(fmt.Errorf("usage: :preview on|off|toggle|wrap|text|hex|info|auto"))
+	}
+}
+
+// previewLabel is appended to the preview header for views other than text.
+func (m *model) previewLabel(path string) string {
+	st := m.previewFor(path)
+	if v := m.previewView(st); v != "text" {
+		return " [" + v + "]"
+	}
+	return ""
+}
+
+// clampScroll limits st.scroll so that the last of total rows can be shown
+// at the bottom of rows and returns it.
+func (st *previewState) clampScroll(total, rows int) int {
+	if last := total - rows; st.scroll > last {
+		st.scroll = last
+	}
+	if st.scroll < 0 {
+		st.scroll = 0
+	}
+	return st.scroll
+}
+
+// hexWidth is the configured number of bytes per hex dump row.
+func (m *model) hexWidth() int {
+	if m.deps.Config != nil {
+		return m.deps.Config.HexWidth
+	}
+	return 0
+}
+
+// renderHexBody renders rows of the hex dump of st.path from its scroll
+// position. Only the bytes on screen are read, so any part of a large file
+// can be reached.
+func (m *model) renderHexBody(st *previewState, width, rows int) []string {
+	f, err := os.Open(st.path)
+	if err != nil {
+		return []string{m.styStatus.Render(trimToWidth(err.Error(), width))}
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return []string{m.styStatus.Render(trimToWidth(err.Error(), width))}
+	}
+	perRow := preview.HexBytesPerRow(width, m.hexWidth())
+	first := st.clampScroll(preview.HexRows(fi.Size(), perRow), rows)
+	dump, err := preview.HexDump(f, int64(first)*int64(perRow), perRow, rows)
+	if err != nil {
+		return []string{m.styStatus.Render(trimToWidth(err.Error(), width))}
+	}
+	out := make([]string, 0, len(dump))
+	for _, line := range dump {
+		out = append(out, m.renderSpans(line, width))
+	}
+	return out
+}
@@ -5,12 +5,98 @@ import (
 	"strings"
 )
 
-func renderPanelColumn(m *model, t tab, width int, focused bool) []string {
+// renderMargin is the number of rows rendered above and below the viewport
+// so that small scrolls (mouse wheel) have content before the next refresh.
+const renderMargin = 8
+
+// renderWindow returns the absolute row range [start, end) that has to be
+// rendered for the current viewport offset.
+func (m *model) renderWindow() (int, int) {
+	start := m.vp.YOffset - renderMargin
+	if start < 0 {
+		start = 0
+	}
+	return start, m.vp.YOffset + m.viewportHeight() + renderMargin
+}
+
+// setWindowContent hands rendered rows starting at absolute row start to the
+// viewport. vp.YOffset keeps the absolute offset; windowView translates it.
+func (m *model) setWindowContent(content string, start int) {
+	off := m.vp.YOffset
+	m.vp.SetContent(content)
+	m.vp.YOffset = off
+	m.winStart = start
+}
+
+// windowView renders the viewport with its offset made relative to the
+// rendered window.
+func (m model) windowView() string {
+	m.vp.YOffset -= m.winStart
+	if last := m.vp.TotalLineCount() - 1; m.vp.YOffset > last {
+		m.vp.YOffset = last
+	}
+	if m.vp.YOffset < 0 {
+		m.vp.YOffset = 0
+	}
+	return m.vp.View()
+}
+
+// clampRange clamps [from, to) to [0, n).
+func clampRange(from, to, n int) (int, int) {
+	if from < 0 {
+		from = 0
+	}
+	if to > n {
+		to = n
+	}
+	if from > to {
+		from = to
+	}
+	return from, to
+}
+
+// renderColumnRows renders a Miller column into the window [start, end) of
+// the focused column. Every column keeps its own scroll offset in t.scroll
+// (vp.YOffset mirrors the focused one), so the rows of an unfocused column
+// are shifted by the difference; rows above its first entry are blank.
+func renderColumnRows(m *model, t tab, width int, focused bool, start, end int) []string {
+	d := t.scroll - m.vp.YOffset
+	if focused || d == 0 {
+		return renderPanelRows(m, t, width, focused, start, end)
+	}
+	from := start + d
+	lines := renderPanelRows(m, t, width, false, from, end+d)
+	if from >= 0 {
+		return lines
+	}
+	rows := make([]string, 0, -from+len(lines))
+	blank := strings.Repeat(" ", width)
+	for i := from; i < 0; i++ {
+		rows = append(rows, blank)
+	}
+	return append(rows, lines...)
+}
+
+// renderPanelRows renders the entries of t in rows [start, end), so that the
+// cost depends on the window size rather than on the directory size.
+func renderPanelRows(m *model, t tab, width int, focused bool, start, end int) []string {
 	p := t.panel
-	lines := make([]string, 0, len(p.Entries))
-	for i, e := range p.Entries {
+	if t.load != nil && len(p.Entries) == 0 {
+		if start > 0 {
+			return nil
+		}
+		return []string{m.styStatus.Render(trimToWidth("loading…", width))}
+	}
+	from, to := clampRange(start, end, len(p.Entries))
+	lines := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		e := p.Entries[i]
 		name := e.Name
-		if e.IsDir {
+		if t.tree != nil {
+			name = treeLabel(t, e)
+		} else if t.flat != nil {
+			name = flatLabel(e, width)
+		} else if e.IsDir {
 			name += "/"
 		}
 		ln := trimToWidth(name, width)
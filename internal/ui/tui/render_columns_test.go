@@ -1,15 +1,19 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 )
 
-func TestRenderPanelColumn_StylesAndWidth(t *testing.T) {
+func TestRenderPanelRows_StylesAndWidth(t *testing.T) {
 	// Ensure lipgloss emits SGR sequences for styles
 	lipgloss.SetColorProfile(termenv.ANSI)
 
@@ -22,7 +26,7 @@ func TestRenderPanelColumn_StylesAndWidth(t *testing.T) {
 
 	p := &panels.Panel{Entries: []panels.Entry{{Name: "dir", IsDir: true}, {Name: "file.txt", IsDir: false}}}
 	ttab := tab{panel: p, selected: 0}
-	lines := renderPanelColumn(m, ttab, 6, false)
+	lines := renderPanelRows(m, ttab, 6, false, 0, len(p.Entries))
 	if len(lines) != 2 {
 		t.Fatalf("lines = %d; want 2", len(lines))
 	}
@@ -42,7 +46,7 @@ func TestRenderPanelColumn_StylesAndWidth(t *testing.T) {
 	}
 }
 
-func TestRenderPanelColumn_SelectedGetsSelectedStyle(t *testing.T) {
+func TestRenderPanelRows_SelectedGetsSelectedStyle(t *testing.T) {
 	lipgloss.SetColorProfile(termenv.ANSI)
 
 	m := &model{}
@@ -53,7 +57,7 @@ func TestRenderPanelColumn_SelectedGetsSelectedStyle(t *testing.T) {
 
 	p := &panels.Panel{Entries: []panels.Entry{{Name: "dir", IsDir: true}, {Name: "file", IsDir: false}}}
 	ttab := tab{panel: p, selected: 1}
-	lines := renderPanelColumn(m, ttab, 5, true) // focused: selected style should apply
+	lines := renderPanelRows(m, ttab, 5, true, 0, len(p.Entries)) // focused: selected style should apply
 	if len(lines) != 2 {
 		t.Fatalf("lines = %d; want 2", len(lines))
 	}
@@ -71,3 +75,115 @@ func TestRenderPanelColumn_SelectedGetsSelectedStyle(t *testing.T) {
 		}
 	}
 }
+
+// newWindowModel builds a model whose panel lists n entries; every tenth entry
+// is a directory whose cached listing also has n entries.
+func newWindowModel(tb testing.TB, n int) *model {
+	tb.Helper()
+	entries := make([]panels.Entry, n)
+	for i := range entries {
+		entries[i] = panels.Entry{Name: fmt.Sprintf("entry-%06d", i), IsDir: i%10 == 0}
+	}
+	m := &model{focus: "left", width: 120, height: 40, header: 1, status: 1, rightPct: 40}
+	m.deps.Config = config.Default()
+	m.styDir = lipgloss.NewStyle().Bold(true)
+	m.stySelected = lipgloss.NewStyle().Reverse(true)
+	m.styNormal = lipgloss.NewStyle()
+	m.styStatus = lipgloss.NewStyle()
+	m.vp.Width = m.width
+	m.vp.Height = m.viewportHeight()
+	m.dirCache = uicache.NewDirCache(8)
+	m.fileCache = uicache.NewFileCache(8, 0)
+	m.prevProv = preview.BasicProvider{}
+	m.prefetching = make(map[string]struct{})
+	p := &panels.Panel{Cwd: "/big", Entries: entries, MaxDirName: panels.MaxDirNameOf(entries)}
+	m.tabs = []tab{{panel: p}}
+	m.dirCache.Put("/big/entry-000000", entries)
+	return m
+}
+
+func TestTryRefreshMultiRendersWindowOnly(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m := newWindowModel(t, 100000)
+	for _, layout := range []string{"single", "preview", "columns"} {
+		m.showPrev = layout == "preview"
+		m.rightMode, m.rightCols = "", nil
+		if layout == "columns" {
+			m.rightMode = "panel"
+			m.rightCols = []tab{{panel: m.tabs[0].panel, selected: 50000}}
+		}
+		m.setSelected(50000)
+		m.refreshContent()
+		if n, max := m.vp.TotalLineCount(), m.viewportHeight()+2*renderMargin; n > max {
+			t.Fatalf("%s: rendered %d lines; want at most %d", layout, n, max)
+		}
+		view := m.windowView()
+		if !strings.Contains(view, "entry-050000") {
+			t.Fatalf("%s: selected entry not visible:\n%s", layout, view)
+		}
+		if first := strings.SplitN(view, "\n", 2)[0]; !strings.HasPrefix(first, "entry-049963") {
+			t.Fatalf("%s: first visible row = %q", layout, first)
+		}
+		m.setSelected(0)
+		m.refreshContent()
+		if first := strings.SplitN(m.windowView(), "\n", 2)[0]; !strings.HasPrefix(first, "entry-000000") {
+			t.Fatalf("%s: first row after scrolling back = %q", layout, first)
+		}
+	}
+}
+
+func BenchmarkRenderWindow100k(b *testing.B) {
+	for _, at := range []int{0, 50000, 99999} {
+		b.Run(fmt.Sprintf("at=%d", at), func(b *testing.B) {
+			m := newWindowModel(b, 100000)
+			m.setSelected(at)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start, end := m.renderWindow()
+				_ = renderPanelRows(m, m.tabs[0], 40, true, start, end)
+			}
+		})
+	}
+}
+
+func BenchmarkTryRefreshMulti100k(b *testing.B) {
+	for _, layout := range []string{"single", "preview", "columns"} {
+		for _, at := range []int{0, 50000, 99999} {
+			b.Run(fmt.Sprintf("%s/at=%d", layout, at), func(b *testing.B) {
+				m := newWindowModel(b, 100000)
+				m.showPrev = layout == "preview"
+				if layout == "columns" {
+					m.rightMode = "panel"
+					m.rightCols = []tab{{panel: m.tabs[0].panel, selected: at}}
+				}
+				m.setSelected(at)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					m.tryRefreshMulti()
+				}
+			})
+		}
+	}
+}
+
+func TestRenderColumnRows_UnfocusedUsesOwnOffset(t *testing.T) {
+	m := &model{}
+	m.vp.YOffset = 10
+	var entries []panels.Entry
+	for i := 0; i < 30; i++ {
+		entries = append(entries, panels.Entry{Name: fmt.Sprintf("e%02d", i)})
+	}
+	col := tab{panel: &panels.Panel{Entries: entries}, scroll: 2}
+	start, end := 10-renderMargin, 10+5+renderMargin
+	lines := renderColumnRows(m, col, 4, false, start, end)
+	if len(lines) != end-start {
+		t.Fatalf("lines = %d; want %d", len(lines), end-start)
+	}
+	// The row at the top of the viewport shows the column's own offset.
+	if got := lines[m.vp.YOffset-start]; got != "e02 " {
+		t.Fatalf("top row = %q; want e02", got)
+	}
+	if got := lines[0]; got != "    " {
+		t.Fatalf("rows above the first entry should be blank, got %q", got)
+	}
+}
@@ -19,34 +19,53 @@ func isImagePath(path string) bool {
 }
 
 // renderFilePreviewBody builds body lines for the right preview for a file path.
-// It tries inline image rendering if supported, otherwise falls back to text preview.
+// Images are drawn with the terminal's image protocol or as text thumbnails;
+// other files render the text, hex or info view of the file from its scroll
+// position (see previewState).
 func (m *model) renderFilePreviewBody(path string, width int, maxBodyLines int) []string {
 	if width < 1 || maxBodyLines < 1 {
 		return nil
 	}
-	// Inline image path: only if enabled in config and terminal supports it.
-	if m.deps.Config != nil && m.deps.Config.InlineImages && isImagePath(path) && preview.SupportsIterm2() {
-		if lines, ok := preview.BuildIterm2Inline(path, width, maxBodyLines); ok {
+	st := m.previewFor(path)
+	// Images: the picture with its metadata below, when there is room.
+	if st.view == "" && isImagePath(path) {
+		meta := st.imageMeta()
+		if maxBodyLines-len(meta) < 2 {
+			meta = nil
+		}
+		if lines, ok := m.inlineImage(path, width, maxBodyLines-len(meta)); ok {
+			for _, l := range meta {
+				lines = append(lines, m.renderSpans([]preview.Span{{Text: l, Class: preview.Comment}}, width))
+			}
 			return lines
 		}
 	}
-	// Text fallback using provider (with cache)
-	res, ok := m.fileCache.Get(path)
-	if !ok {
-		res, _ = m.prevProv.Preview(path, 8192)
-		m.fileCache.Put(path, res)
+	view := m.previewView(st)
+	if view == "hex" {
+		return m.renderHexBody(st, width, maxBodyLines)
 	}
-	out := []string{}
-	for _, l := range strings.Split(res.Content, "\n") {
-		ln := trimToWidth(l, width)
-		pad := width - lipgloss.Width(ln)
-		if pad > 0 {
-			ln += strings.Repeat(" ", pad)
-		}
-		out = append(out, m.styNormal.Render(ln))
-		if len(out) >= maxBodyLines {
-			break
+	return m.renderTextBody(st, m.previewLines(st, view), width, maxBodyLines)
+}
+
+// renderSpans renders a highlighted line padded or truncated to width.
+func (m *model) renderSpans(spans []preview.Span, width int) string {
+	var b strings.Builder
+	used := 0
+	for _, sp := range spans {
+		text := sp.Text
+		w := lipgloss.Width(text)
+		if used+w > width {
+			// Cut the line like trimToWidth: width-1 cells and an ellipsis.
+			if used < width {
+				b.WriteString(m.stySyntax[sp.Class].Render(trimToWidth(text+"…", width-used)))
+			}
+			return b.String()
 		}
+		b.WriteString(m.stySyntax[sp.Class].Render(text))
+		used += w
+	}
+	if pad := width - used; pad > 0 {
+		b.WriteString(m.styNormal.Render(strings.Repeat(" ", pad)))
 	}
-	return out
+	return b.String()
 }
@@ -1,13 +1,19 @@
 package tui
 
 import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
 	uicache "github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
@@ -29,12 +35,13 @@ func TestRenderFilePreviewBody_TextFallbackAndCache(t *testing.T) {
 	lipgloss.SetColorProfile(termenv.ANSI)
 	m := &model{}
 	m.styNormal = lipgloss.NewStyle() // no extra SGR
-	m.fileCache = uicache.NewFileCache(64)
+	m.fileCache = uicache.NewFileCache(64, 0)
 	sp := &stubProv{res: preview.Result{Kind: "text", Content: "AAA\nBBBBBB", Mime: "text/plain"}}
 	m.prevProv = sp
-	// Non-image path to force text fallback
+	// Non-image path to force text fallback. The provider decides the kind;
+	// the text itself is read from the file as it is scrolled.
 	path := filepath.Join(t.TempDir(), "readme.txt")
-	_ = os.WriteFile(path, []byte("dummy"), 0o644)
+	_ = os.WriteFile(path, []byte("AAA\nBBBBBB"), 0o644)
 	out := m.renderFilePreviewBody(path, 4, 2)
 	if len(out) != 2 {
 		t.Fatalf("len(out)=%d; want 2", len(out))
@@ -86,3 +93,218 @@ func TestRenderFilePreviewBody_InlineImageIterm2(t *testing.T) {
 
 // helper to init an empty FileCache since zero-value lacks maps
 // no extra helpers needed; using cache.NewFileCache in tests
+
+var sgr = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripANSI(s string) string { return sgr.ReplaceAllString(s, "") }
+
+func TestRenderFilePreviewBody_Highlighted(t *testing.T) {
+	m := &model{}
+	m.deps.Config = config.Default()
+	m.deps.Config.Theme.Syntax["keyword"] = config.ColorStyle{FG: "1"}
+	m.fileCache = uicache.NewFileCache(64, 0)
+	m.prevProv = preview.BasicProvider{}
+	path := filepath.Join(t.TempDir(), "main.go")
+	src := "package main\n\nfunc main() {\n\tprintln(\"a very long string literal\")\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lipgloss.SetColorProfile(termenv.ANSI)
+	m.computeStyles()
+	out := m.renderFilePreviewBody(path, 20, 10)
+	if !strings.HasPrefix(out[0], "\x1b[31mpackage") {
+		t.Fatalf("keyword not colored by [theme.syntax]: %q", out[0])
+	}
+	for i, ln := range out {
+		if w := lipgloss.Width(ln); w != 20 {
+			t.Fatalf("line %d width %d; want 20 (%q)", i, w, ln)
+		}
+	}
+	if got := stripANSI(out[3]); got != "    println(\"a very…" {
+		t.Fatalf("long line = %q", got)
+	}
+
+	// Without colors the text is unchanged.
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m.computeStyles()
+	out = m.renderFilePreviewBody(path, 20, 10)
+	if strings.Contains(out[0], "\x1b[") || out[0] != "package main        " {
+		t.Fatalf("ascii profile line = %q", out[0])
+	}
+}
+
+func TestRenderFilePreviewBody_HexViewScrollsPastMaxBytes(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m := &model{}
+	m.deps.Config = config.Default()
+	m.fileCache = uicache.NewFileCache(64, 0)
+	m.prevProv = preview.BasicProvider{}
+	m.computeStyles()
+	path := filepath.Join(t.TempDir(), "blob.bin")
+	data := make([]byte, 20000)
+	data[0], data[19999] = 0x7f, 'Z'
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Binary files open in the hex view.
+	out := m.renderFilePreviewBody(path, 80, 4)
+	if got := stripANSI(out[0]); !strings.HasPrefix(got, "00000000  7f 00") {
+		t.Fatalf("first row = %q", got)
+	}
+	if m.previewLabel(path) != " [hex]" {
+		t.Fatalf("label = %q", m.previewLabel(path))
+	}
+	// Scrolling past the end stops at the last rows, beyond the 8 KiB the
+	// provider reads.
+	m.scrollPreview(10000)
+	out = m.renderFilePreviewBody(path, 80, 4)
+	if got := stripANSI(out[3]); !strings.HasPrefix(got, "00004e10") || !strings.HasSuffix(strings.TrimRight(got, " "), ".Z|") {
+		t.Fatalf("last row = %q", got)
+	}
+	if m.pv.scroll != 1250-4 {
+		t.Fatalf("scroll = %d; want %d", m.pv.scroll, 1250-4)
+	}
+
+	// v cycles to the info view, then the text view.
+	m.cyclePreviewView()
+	out = m.renderFilePreviewBody(path, 80, 4)
+	if got := stripANSI(out[0]); !strings.HasPrefix(got, "blob.bin (20000 bytes)") {
+		t.Fatalf("info view = %q", got)
+	}
+	m.cyclePreviewView()
+	out = m.renderFilePreviewBody(path, 80, 4)
+	if got := stripANSI(out[0]); !strings.HasPrefix(got, "....") {
+		t.Fatalf("text view = %q", got)
+	}
+
+	// Another file starts in its automatic view again.
+	other := filepath.Join(t.TempDir(), "a.txt")
+	_ = os.WriteFile(other, []byte("hello"), 0o644)
+	out = m.renderFilePreviewBody(other, 10, 2)
+	if got := stripANSI(out[0]); got != "hello     " {
+		t.Fatalf("text file = %q", got)
+	}
+}
+
+func TestRenderPreviewRows_StaysAtViewportTop(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m := &model{}
+	m.deps.Config = config.Default()
+	m.fileCache = uicache.NewFileCache(64, 0)
+	m.prevProv = preview.BasicProvider{}
+	m.computeStyles()
+	m.height, m.header, m.status = 12, 1, 1
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "x.txt"), []byte("one\ntwo"), 0o644)
+	var entries []panels.Entry
+	for i := 0; i < 40; i++ {
+		entries = append(entries, panels.Entry{Name: "x.txt"})
+	}
+	tb := tab{panel: &panels.Panel{Cwd: dir, Entries: entries}, selected: 30}
+	m.vp.YOffset = 25
+	start, end := m.renderWindow()
+	rows := m.renderPreviewRows(&tb, 20, start, end)
+	top := m.vp.YOffset - start
+	if strings.TrimSpace(rows[0]) != "" {
+		t.Fatalf("rows above the viewport should be blank, got %q", rows[0])
+	}
+	if got := stripANSI(rows[top+1]); strings.TrimSpace(got) != "one" {
+		t.Fatalf("preview body at viewport top = %q (rows %q)", got, rows)
+	}
+}
+
+// newImageModel returns a model previewing a 64×64 PNG file, and its path.
+func newImageModel(t *testing.T) (*model, string) {
+	t.Helper()
+	m := &model{colorProfile: "truecolor"}
+	m.deps.Config = config.Default()
+	m.fileCache = uicache.NewFileCache(64, 0)
+	m.imgCache = uicache.NewFileCache(16, 0)
+	m.prevProv = preview.BasicProvider{}
+	m.prefetching = make(map[string]struct{})
+	path := filepath.Join(t.TempDir(), "pic.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 200, A: 255}), image.Point{}, draw.Src)
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return m, path
+}
+
+func TestInlineImageKittyCachedAndCleared(t *testing.T) {
+	t.Setenv("TFM_INLINE", "kitty")
+	t.Setenv("TMUX", "")
+	m, path := newImageModel(t)
+	// The image is drawn in the background.
+	if out := m.renderFilePreviewBody(path, 20, 10); len(out) == 0 || !strings.Contains(out[0], "loading…") || m.imgPlaced {
+		t.Fatalf("no placeholder while drawing: %.40q", out)
+	}
+	drain(m)
+	out := m.renderFilePreviewBody(path, 20, 10)
+	if len(out) == 0 || !strings.HasPrefix(out[0], "\x1b_Ga=T,f=100") {
+		t.Fatalf("no kitty transmission: %.40q", out)
+	}
+	if !m.imgPlaced || m.imgCache.Len() != 1 {
+		t.Fatalf("placed %v, cached %d", m.imgPlaced, m.imgCache.Len())
+	}
+	again := m.renderFilePreviewBody(path, 20, 10)
+	if strings.Join(again, "\n") != strings.Join(out, "\n") {
+		t.Fatalf("cached image differs")
+	}
+	m.updateImageClear()
+	if m.imgClear != "" {
+		t.Fatalf("image deleted while shown")
+	}
+	// A frame without the image deletes it.
+	m.imgPlaced = false
+	m.updateImageClear()
+	if want := preview.KittyDelete(m.kittyImageID(), false); m.imgClear != want {
+		t.Fatalf("imgClear = %q; want %q", m.imgClear, want)
+	}
+}
+
+func TestRedrawSixelWhenCoveredLinesChange(t *testing.T) {
+	m := &model{imgPlaced: true, sixel: &sixelFrame{}}
+	frame := func(top string) string { return top + "\nrow\n" + preview.SixelIntro + "…\nstatus" }
+	first := m.redrawSixel(frame("a"))
+	if again := m.redrawSixel(frame("a")); again != first {
+		t.Fatalf("unchanged frame rewrote the image line")
+	}
+	changed := m.redrawSixel(frame("b"))
+	if strings.Split(changed, "\n")[2] == strings.Split(first, "\n")[2] {
+		t.Fatalf("image line not rewritten after a covered line changed")
+	}
+	if got := m.redrawSixel("no image"); got != "no image" {
+		t.Fatalf("frame without sixel changed: %q", got)
+	}
+}
+
+func TestInlineImageThumbnailWithoutProtocol(t *testing.T) {
+	t.Setenv("TFM_INLINE", "off")
+	m, path := newImageModel(t)
+	m.renderFilePreviewBody(path, 20, 10)
+	drain(m)
+	out := m.renderFilePreviewBody(path, 20, 10)
+	if len(out) != 10 || !strings.Contains(out[0], "\x1b[38;2;200;0;0;48;2;200;0;0m▀") {
+		t.Fatalf("no half-block thumbnail: %q", out)
+	}
+	if m.imgPlaced {
+		t.Fatalf("thumbnail counted as a placed image")
+	}
+	if !strings.Contains(out[len(out)-1], "64×64 png") {
+		t.Fatalf("no image metadata under the thumbnail: %q", out[len(out)-1])
+	}
+	// The ascii style leaves the image to the provider's text preview.
+	m.deps.Config.Thumbnails = "ascii"
+	m.renderFilePreviewBody(path, 20, 10)
+	drain(m)
+	if out := m.renderFilePreviewBody(path, 20, 10); strings.Contains(strings.Join(out, ""), "▀") {
+		t.Fatalf("ascii style drew blocks: %q", out)
+	}
+}
@@ -44,15 +44,40 @@ func statusContent(m *model) string {
 			} else {
 				status = fmt.Sprintf("%s | %s", e.Name, status)
 			}
+		} else if e.Info && !e.IsDir {
+			// Archive members are only known from their listing.
+			status = fmt.Sprintf("%s | %dB | %s", e.Name, e.Size, status)
 		} else {
 			status = fmt.Sprintf("%s | %s", e.Name, status)
 		}
 	}
+	if fs := ft.flat; fs != nil {
+		switch {
+		case fs.id != 0:
+			status = "flattening… | " + status
+		case fs.truncated:
+			status = fmt.Sprintf("flat: first %d entries | %s", n, status)
+		default:
+			status = fmt.Sprintf("flat: %d entries | %s", n, status)
+		}
+	}
+	if m.job != nil {
+		status = m.job.status() + " | " + status
+	}
+	if ft.load != nil {
+		status = fmt.Sprintf("loading… %d entries | %s", ft.load.count, status)
+	}
+	if m.previewFocused() {
+		status = m.pagerStatus()
+	}
 	if m.err != nil {
 		status = fmt.Sprintf("ERR: %s | %s", m.err.Error(), status)
 	}
 	if m.cmdActive {
 		prompt := ":" + string(m.cmdBuf)
+		if m.promptKind != "" {
+			prompt = m.promptLabel + string(m.cmdBuf)
+		}
 		return trimToWidth(prompt, m.width)
 	}
 	return status
@@ -0,0 +1,244 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/session"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+// sessionTickMsg triggers the periodic session save.
+type sessionTickMsg struct{}
+
+// sessionTick schedules the next periodic save, or returns nil when the
+// session is only saved on exit.
+func (m *model) sessionTick() tea.Cmd {
+	if m.deps.SessionPath == "" || m.deps.Config == nil || m.deps.Config.SessionSaveEvery <= 0 {
+		return nil
+	}
+	every := time.Duration(m.deps.Config.SessionSaveEvery) * time.Second
+	return tea.Tick(every, func(time.Time) tea.Msg { return sessionTickMsg{} })
+}
+
+// saveSession writes the current tabs to path.
+func (m *model) saveSession(path string) error {
+	if path == "" || len(m.tabs) == 0 {
+		return nil
+	}
+	return m.snapshotSession().Save(path)
+}
+
+// snapshotSession captures the tabs, their columns and the layout.
+func (m *model) snapshotSession() *session.Session {
+	s := &session.Session{Saved: time.Now(), Layout: "miller", Active: m.active}
+	if m.dual {
+		s.Layout = "dual"
+	}
+	for i := range m.tabs {
+		t := &m.tabs[i]
+		v := t.view
+		if i == m.active {
+			v = &tabView{rightMode: m.rightMode, rightCols: m.rightCols, rightT: m.rightT, focus: m.focus, showPrev: m.showPrev}
+		}
+		s.Tabs = append(s.Tabs, snapshotTab(t, v))
+	}
+	return s
+}
+
+func snapshotPanel(t *tab) session.Panel {
+	sp := session.Panel{Dir: t.panel.Cwd, Selected: selectedName(t)}
+	if t.load != nil {
+		sp.Selected = t.load.selName
+	}
+	return sp
+}
+
+func snapshotTab(t *tab, v *tabView) session.Tab {
+	st := session.Tab{
+		Panel:      snapshotPanel(t),
+		Name:       t.name,
+		ShowHidden: t.panel.ShowHidden,
+		HistoryPos: t.hist.pos,
+	}
+	for _, it := range t.hist.items {
+		st.History = append(st.History, it.Dir)
+	}
+	if t.tree != nil {
+		st.Tree = []string{}
+		for rel := range t.tree.expanded {
+			st.Tree = append(st.Tree, rel)
+		}
+		sort.Strings(st.Tree)
+	}
+	if fs := t.flat; fs != nil {
+		st.Flatten = &session.Flatten{Depth: fs.depth, All: fs.all, Sort: fs.sortKey, SortDesc: fs.sortDesc}
+	}
+	if v != nil {
+		for i := range v.rightCols {
+			st.Columns = append(st.Columns, snapshotPanel(&v.rightCols[i]))
+		}
+		if v.rightT.panel != nil {
+			rp := snapshotPanel(&v.rightT)
+			st.Right = &rp
+		}
+		st.Focus = v.focus
+		st.Preview = v.showPrev
+	}
+	return st
+}
+
+// applySession replaces the tabs with those of s. Directories that no
+// longer exist are replaced by their nearest existing parent.
+func (m *model) applySession(s *session.Session) {
+	m.tabs = m.tabs[:0]
+	m.rightCols, m.rightT = nil, tab{}
+	m.dual = s.Layout == "dual"
+	for _, st := range s.Tabs {
+		t := m.restoreTab(st.Panel, st.ShowHidden)
+		t.name = st.Name
+		if len(st.History) > 0 {
+			t.hist = history{}
+			for _, dir := range st.History {
+				t.hist.items = append(t.hist.items, histItem{Dir: dir})
+			}
+			t.hist.pos = st.HistoryPos
+			if t.hist.pos < 0 || t.hist.pos >= len(t.hist.items) {
+				t.hist.pos = len(t.hist.items) - 1
+			}
+		}
+		v := &tabView{focus: "left", showPrev: st.Preview}
+		for _, c := range st.Columns {
+			v.rightCols = append(v.rightCols, m.restoreTab(c, st.ShowHidden))
+		}
+		if st.Right != nil {
+			v.rightT = m.restoreTab(*st.Right, st.ShowHidden)
+		}
+		switch {
+		case st.Focus == "right" && (len(v.rightCols) > 0 || v.rightT.panel != nil):
+			v.focus = "right"
+			v.rightMode = "panel"
+		case len(v.rightCols) > 0:
+			v.rightMode = "panel"
+		case v.showPrev:
+			v.rightMode = "preview"
+		}
+		t.view = v
+		m.tabs = append(m.tabs, t)
+	}
+	// Tree and flattened listings need stable tab pointers.
+	for i, st := range s.Tabs {
+		t := &m.tabs[i]
+		switch {
+		case st.Flatten != nil:
+			// Flattened tabs are walked instead of listed; the plain
+			// listing is read when leaving the flattened view.
+			m.cancelLoad(t)
+			f := st.Flatten
+			t.flat = &flatState{
+				depth: f.Depth, all: f.All, sortKey: f.Sort, sortDesc: f.SortDesc,
+				selName:    st.Selected,
+				prevHidden: t.panel.ShowHidden,
+			}
+			m.startFlatten(t)
+		case st.Tree != nil:
+			// List again with the open directories.
+			t.tree = newTreeState()
+			for _, rel := range st.Tree {
+				t.tree.expanded[rel] = true
+			}
+			m.startReload(t, st.Selected)
+		}
+	}
+	m.active = s.Active
+	if m.active < 0 || m.active >= len(m.tabs) {
+		m.active = 0
+	}
+	m.restoreView()
+	m.ensureVisible()
+}
+
+// restoreTab returns a tab on sp's directory (or its nearest existing
+// parent), listed in the background with the saved entry selected.
+func (m *model) restoreTab(sp session.Panel, showHidden bool) tab {
+	dir := session.ExistingDir(sp.Dir)
+	t := tab{panel: panels.NewPanel(dir, showHidden)}
+	t.hist.visit(dir)
+	m.startReload(&t, sp.Selected)
+	return t
+}
+
+// loadSession replaces the open tabs with the session at path.
+func (m *model) loadSession(path string) error {
+	s, err := session.Load(path)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return fmt.Errorf("no session at %s", path)
+	}
+	for i := range m.tabs {
+		for _, t := range m.tabTree(&m.tabs[i]) {
+			m.cancelLoad(t)
+			resetFlatten(t)
+		}
+	}
+	m.applySession(s)
+	return nil
+}
+
+// sessionCommand implements :session save [name], :session load [name] and
+// :session list. Without a name the session saved on exit is used.
+func (m *model) sessionCommand(args []string) {
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	path := m.deps.SessionPath
+	if path == "" {
+		path = session.DefaultPath()
+	}
+	if len(args) > 1 {
+		p, err := session.NamedPath(args[1])
+		if err != nil {
+			m.setError(err)
+			return
+		}
+		path = p
+	}
+	switch sub {
+	case "save":
+		if err := m.saveSession(path); err != nil {
+			m.setError(fmt.Errorf("save session: %w", err))
+			return
+		}
+	case "load", "restore":
+		if err := m.loadSession(path); err != nil {
+			m.setError(fmt.Errorf("load session: %w", err))
+			return
+		}
+	case "list", "ls":
+		names, err := session.Names()
+		if err != nil {
+			m.setError(err)
+			return
+		}
+		lines := []string{"last session: " + path}
+		if len(names) == 0 {
+			lines = append(lines, "no named sessions — :session save <name>")
+		} else {
+			lines = append(lines, "named: "+strings.Join(names, ", "))
+		}
+		m.modalTitle = "Sessions"
+		m.modalLines = lines
+		m.modalActive = true
+	default:
+		m.setError(fmt.Errorf("usage: :session save|load [name] | list"))
+		return
+	}
+	m.err = nil
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionSnapshotAndRestore(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m, root := newTreeModel(t)
+	m.treeExpand(m.current(), 0) // a
+	drain(m)
+	m.setSelected(indexOfEntry(m.current().panel.Entries, "a/f1"))
+	m.execCommand(":tabname code")
+
+	// Second tab: plain listing of a with a Miller column on inner.
+	m.newTab()
+	m.navigate(m.current(), filepath.Join(root, "a"), "")
+	drain(m)
+	m.openRightPanel(filepath.Join(root, "a", "inner"))
+	drain(m)
+	m.focus = "right"
+
+	// Third tab: flattened, sorted, later removed from disk.
+	gone := filepath.Join(root, "b", "gone")
+	if err := os.MkdirAll(gone, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.newTab()
+	m.navigate(m.current(), gone, "")
+	drain(m)
+	m.execCommand(":flatten 2")
+	drain(m)
+	m.execCommand(":sort mtime desc")
+	drain(m)
+	m.switchTab(1)
+	m.execCommand(":session save work")
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	n, _ := newHistoryModel(t)
+	n.execCommand(":session load work")
+	drain(n)
+	if n.err != nil {
+		t.Fatal(n.err)
+	}
+	if len(n.tabs) != 3 || n.active != 1 {
+		t.Fatalf("restored %d tabs, active %d", len(n.tabs), n.active)
+	}
+	first := &n.tabs[0]
+	if first.name != "code" || first.tree == nil || !first.tree.expanded["a"] || selectedName(first) != "a/f1" {
+		t.Fatalf("tree tab: name %q tree %v sel %q", first.name, first.tree != nil, selectedName(first))
+	}
+	if got := n.current().panel.Cwd; got != filepath.Join(root, "a") {
+		t.Fatalf("active tab cwd = %q", got)
+	}
+	if len(n.rightCols) != 1 || n.rightCols[0].panel.Cwd != filepath.Join(root, "a", "inner") || n.focus != "right" {
+		t.Fatalf("columns not restored: %d, focus %s", len(n.rightCols), n.focus)
+	}
+	third := &n.tabs[2]
+	if third.panel.Cwd != filepath.Join(root, "b") {
+		t.Fatalf("missing directory not replaced by its parent: %q", third.panel.Cwd)
+	}
+	if third.flat == nil || third.flat.depth != 2 || third.flat.sortKey != "mtime" || !third.flat.sortDesc {
+		t.Fatalf("flatten state not restored: %+v", third.flat)
+	}
+	if len(third.hist.items) < 2 {
+		t.Fatalf("history not restored: %v", third.hist.items)
+	}
+}
+
+func TestSessionRestoresDualLayout(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.setLayout(true)
+	drain(m)
+	m.navigate(&m.rightT, filepath.Join(root, "b"), "")
+	drain(m)
+	s := m.snapshotSession()
+	if s.Layout != "dual" || s.Tabs[0].Right == nil {
+		t.Fatalf("snapshot: layout %q right %v", s.Layout, s.Tabs[0].Right)
+	}
+
+	n, _ := newHistoryModel(t)
+	n.applySession(s)
+	drain(n)
+	if !n.dual || n.rightT.panel == nil || n.rightT.panel.Cwd != filepath.Join(root, "b") {
+		t.Fatalf("dual layout not restored: dual=%v", n.dual)
+	}
+}
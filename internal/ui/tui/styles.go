@@ -1,6 +1,24 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/preview"
+)
+
+// defaultSyntax colors highlighted previews with the 16 ANSI colors, so that
+// they follow the terminal palette and every color profile.
+var defaultSyntax = map[preview.Class]config.ColorStyle{
+	preview.Keyword: {FG: "5"},
+	preview.Type:    {FG: "6"},
+	preview.String:  {FG: "2"},
+	preview.Number:  {FG: "3"},
+	preview.Comment: {FG: "8"},
+	preview.Meta:    {FG: "13"},
+	preview.Key:     {FG: "4"},
+	preview.Heading: {FG: "4", Bold: true},
+}
 
 // computeStyles initializes styles based on theme and size.
 func (m *model) computeStyles() {
@@ -91,4 +109,36 @@ func (m *model) computeStyles() {
 	if th.Dir.BG != "" && !transparent {
 		m.styDir = m.styDir.Background(lipgloss.Color(th.Dir.BG))
 	}
+	// Syntax highlighting, on top of the normal style
+	m.stySyntax[preview.Plain] = m.styNormal
+	for c := preview.Plain + 1; c < preview.NumClasses; c++ {
+		cs, ok := th.Syntax[c.String()]
+		if !ok {
+			cs = defaultSyntax[c]
+		}
+		m.stySyntax[c] = colorStyle(cs, transparent).Inherit(m.styNormal)
+	}
+	m.stySyntax[classMatch] = m.stySelected
+}
+
+// colorStyle converts a configured style; backgrounds are dropped when the
+// terminal background should show through.
+func colorStyle(cs config.ColorStyle, transparent bool) lipgloss.Style {
+	st := lipgloss.NewStyle()
+	if cs.Bold {
+		st = st.Bold(true)
+	}
+	if cs.Faint {
+		st = st.Faint(true)
+	}
+	if cs.Reverse {
+		st = st.Reverse(true)
+	}
+	if cs.FG != "" {
+		st = st.Foreground(lipgloss.Color(cs.FG))
+	}
+	if cs.BG != "" && !transparent {
+		st = st.Background(lipgloss.Color(cs.BG))
+	}
+	return st
 }
@@ -0,0 +1,321 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+// tabLabelMax bounds the width of one label in the tab bar.
+const tabLabelMax = 16
+
+// tabView is the right-hand state of a tab: its Miller columns (or the
+// second panel of the dual layout), preview and focus. The active tab's
+// view lives in the model fields; switching tabs stashes it in tab.view.
+type tabView struct {
+	rightMode string
+	rightCols []tab
+	rightT    tab
+	focus     string
+	showPrev  bool
+}
+
+// stashView moves the model's right-hand state into the active tab.
+func (m *model) stashView() {
+	m.focused().scroll = m.vp.YOffset
+	m.current().view = &tabView{
+		rightMode: m.rightMode,
+		rightCols: m.rightCols,
+		rightT:    m.rightT,
+		focus:     m.focus,
+		showPrev:  m.showPrev,
+	}
+	m.rightCols = nil
+	m.rightT = tab{}
+}
+
+// restoreView applies the active tab's stashed right-hand state. A tab
+// without one (a new tab) starts with just the preview setting inherited.
+// The state is adapted to the current layout, which is global.
+func (m *model) restoreView() {
+	t := m.current()
+	v := t.view
+	t.view = nil
+	if v == nil {
+		v = &tabView{focus: "left", showPrev: m.showPrev}
+		if v.showPrev {
+			v.rightMode = "preview"
+		}
+	}
+	m.rightMode, m.rightCols, m.rightT = v.rightMode, v.rightCols, v.rightT
+	m.focus, m.showPrev = v.focus, v.showPrev
+	if m.dual {
+		m.dropColumns(0)
+		m.rightMode = "panel"
+		if m.rightT.panel == nil {
+			m.openDualPane(t.panel.Cwd)
+		}
+	} else if m.rightT.panel != nil {
+		// A second panel left over from the dual layout.
+		m.dropTab(&m.rightT)
+		m.rightT = tab{}
+		if len(m.rightCols) == 0 {
+			m.focus = "left"
+			m.rightMode = ""
+			if m.showPrev {
+				m.rightMode = "preview"
+			}
+		}
+	}
+	m.vp.YOffset = m.focused().scroll
+}
+
+// switchTab makes tab i active.
+func (m *model) switchTab(i int) {
+	if i < 0 || i >= len(m.tabs) || i == m.active {
+		return
+	}
+	m.stashView()
+	m.active = i
+	m.restoreView()
+	m.ensureVisible()
+	m.refreshContent()
+}
+
+func (m *model) nextTab() {
+	if len(m.tabs) == 0 {
+		return
+	}
+	m.switchTab((m.active + 1) % len(m.tabs))
+}
+
+func (m *model) prevTab() {
+	if len(m.tabs) == 0 {
+		return
+	}
+	m.switchTab((m.active - 1 + len(m.tabs)) % len(m.tabs))
+}
+
+func (m *model) newTab() {
+	// Clone current CWD and ShowHidden
+	curr := m.current().panel
+	p := panels.NewPanel(curr.Cwd, curr.ShowHidden)
+	t := tab{panel: p}
+	t.hist.visit(p.Cwd)
+	m.stashView()
+	m.insertTab(t)
+	if entries := m.dirCache.Get(p.Cwd); entries != nil {
+		p.Entries, p.MaxDirName = entries, computeMaxDirName(entries)
+	} else {
+		m.startLoad(m.current(), p.Cwd, "")
+	}
+	m.setSelected(0)
+}
+
+// duplicateTab opens a copy of the active tab next to it, with its cursor,
+// history, tree or flattened listing, name and right-hand columns.
+func (m *model) duplicateTab() {
+	m.stashView()
+	src := m.current()
+	dup := cloneTab(src)
+	if v := src.view; v != nil {
+		dv := *v
+		dv.rightCols = make([]tab, len(v.rightCols))
+		for i := range v.rightCols {
+			dv.rightCols[i] = cloneTab(&v.rightCols[i])
+		}
+		dv.rightT = tab{}
+		if v.rightT.panel != nil {
+			dv.rightT = cloneTab(&v.rightT)
+		}
+		dup.view = &dv
+	}
+	m.insertTab(dup)
+	// Listings still loading in the original are read again for the copy.
+	for _, t := range m.tabTree(m.current()) {
+		if t.panel != nil && t.panel.Entries == nil && t.panel.Cwd != "" {
+			m.startLoad(t, t.panel.Cwd, "")
+		}
+	}
+	m.ensureVisible()
+	m.refreshContent()
+}
+
+// cloneTab copies the navigation state of t. In-flight work (loads, walks)
+// is not copied; such listings are left empty for the caller to reload.
+func cloneTab(t *tab) tab {
+	p := *t.panel
+	c := tab{panel: &p, selected: t.selected, scroll: t.scroll, name: t.name}
+	c.hist.items = append([]histItem(nil), t.hist.items...)
+	c.hist.pos = t.hist.pos
+	if t.load != nil {
+		p.Entries = nil
+		c.selected = 0
+	}
+	if t.tree != nil {
+		c.tree = newTreeState()
+		for k, v := range t.tree.expanded {
+			c.tree.expanded[k] = v
+		}
+	}
+	if t.flat != nil {
+		fs := *t.flat
+		fs.id, fs.cancel = 0, nil
+		c.flat = &fs
+	}
+	return c
+}
+
+// insertTab adds t after the active tab and switches to it. The caller
+// stashes the active tab's view first.
+func (m *model) insertTab(t tab) {
+	m.tabs = append(m.tabs, tab{})
+	copy(m.tabs[m.active+2:], m.tabs[m.active+1:])
+	m.tabs[m.active+1] = t
+	m.active++
+	m.restoreView()
+}
+
+func (m *model) closeTab() {
+	if len(m.tabs) <= 1 {
+		return
+	}
+	idx := m.active
+	for _, t := range m.tabTree(&m.tabs[idx]) {
+		m.dropTab(t)
+	}
+	m.rightCols, m.rightT = nil, tab{}
+	m.tabs = append(m.tabs[:idx], m.tabs[idx+1:]...)
+	if m.active >= len(m.tabs) {
+		m.active = len(m.tabs) - 1
+	}
+	m.restoreView()
+	m.ensureVisible()
+}
+
+// dropTab stops the background work of t, a tab or column being closed.
+func (m *model) dropTab(t *tab) {
+	m.cancelLoad(t)
+	resetFlatten(t)
+	if t.tree != nil {
+		t.tree.stop()
+	}
+}
+
+// dropColumns closes the right-hand columns from index n on.
+func (m *model) dropColumns(n int) {
+	if n >= len(m.rightCols) {
+		return
+	}
+	for i := n; i < len(m.rightCols); i++ {
+		m.dropTab(&m.rightCols[i])
+	}
+	if n == 0 {
+		m.rightCols = nil
+		return
+	}
+	m.rightCols = m.rightCols[:n]
+}
+
+// moveTab moves the active tab delta positions, stopping at either end.
+func (m *model) moveTab(delta int) {
+	m.moveTabTo(m.active + delta)
+}
+
+// moveTabTo moves the active tab to index i.
+func (m *model) moveTabTo(i int) {
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(m.tabs) {
+		i = len(m.tabs) - 1
+	}
+	t := m.tabs[m.active]
+	if i < m.active {
+		copy(m.tabs[i+1:m.active+1], m.tabs[i:m.active])
+	} else {
+		copy(m.tabs[m.active:i], m.tabs[m.active+1:i+1])
+	}
+	m.tabs[i] = t
+	m.active = i
+}
+
+// tabTree returns t and every column that belongs to it: the model's right
+// state for the active tab, the stashed view for the others.
+func (m *model) tabTree(t *tab) []*tab {
+	out := []*tab{t}
+	cols, right := m.rightCols, &m.rightT
+	if t != m.current() {
+		if t.view == nil {
+			return out
+		}
+		cols, right = t.view.rightCols, &t.view.rightT
+	}
+	for i := range cols {
+		out = append(out, &cols[i])
+	}
+	if right.panel != nil {
+		out = append(out, right)
+	}
+	return out
+}
+
+// tabLabel is the name shown for t in the tab bar.
+func tabLabel(t *tab) string {
+	name := t.name
+	if name == "" && t.panel != nil {
+		name = filepath.Base(t.panel.Cwd)
+	}
+	return trimToWidth(name, tabLabelMax)
+}
+
+// tabBarItems returns the tab bar entries in order. The active one is
+// bracketed, the others padded to the same width.
+func tabBarItems(m *model) []string {
+	items := make([]string, len(m.tabs))
+	for i := range m.tabs {
+		label := fmt.Sprintf("%d:%s", i+1, tabLabel(&m.tabs[i]))
+		if i == m.active {
+			items[i] = "[" + label + "]"
+		} else {
+			items[i] = " " + label + " "
+		}
+	}
+	return items
+}
+
+// tabCommand implements :tabname, :tabmove, :tabdup and :tab <n>.
+func (m *model) tabCommand(name string, args []string) {
+	switch name {
+	case "tabname":
+		m.current().name = strings.Join(args, " ")
+	case "tabdup":
+		m.duplicateTab()
+	case "tab":
+		n, err := strconv.Atoi(strings.Join(args, ""))
+		if err != nil || n < 1 || n > len(m.tabs) {
+			m.setError(fmt.Errorf("usage: :tab <1..%d>", len(m.tabs)))
+			return
+		}
+		m.switchTab(n - 1)
+	case "tabmove":
+		if len(args) != 1 {
+			m.setError(fmt.Errorf("usage: :tabmove +N|-N|N"))
+			return
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			m.setError(fmt.Errorf("usage: :tabmove +N|-N|N"))
+			return
+		}
+		if strings.HasPrefix(args[0], "+") || strings.HasPrefix(args[0], "-") {
+			m.moveTab(n)
+		} else {
+			m.moveTabTo(n - 1)
+		}
+	}
+	m.err = nil
+}
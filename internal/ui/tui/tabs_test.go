@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tabNames(m *model) string {
+	return strings.Join(tabBarItems(m), "")
+}
+
+func TestTabsKeepTheirOwnColumns(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.showPrev = true
+	m.rightMode = "preview"
+	m.openRightPanel(filepath.Join(root, "a"))
+	drain(m)
+	m.focus = "right"
+
+	m.newTab()
+	if len(m.rightCols) != 0 || m.focus != "left" || m.rightMode != "preview" {
+		t.Fatalf("new tab inherited columns: %d cols, focus %s, mode %s", len(m.rightCols), m.focus, m.rightMode)
+	}
+	m.togglePreview()
+	m.prevTab()
+	if len(m.rightCols) != 1 || m.rightCols[0].panel.Cwd != filepath.Join(root, "a") || m.focus != "right" {
+		t.Fatalf("columns not restored: %d cols, focus %s", len(m.rightCols), m.focus)
+	}
+	if !m.showPrev {
+		t.Fatalf("preview state leaked from the other tab")
+	}
+	m.nextTab()
+	if m.showPrev || m.rightMode != "" {
+		t.Fatalf("second tab lost its preview state: %v %q", m.showPrev, m.rightMode)
+	}
+}
+
+func TestTabNameMoveJumpAndDuplicate(t *testing.T) {
+	m, root := newHistoryModel(t)
+	base := filepath.Base(root)
+	m.newTab()
+	m.navigate(m.current(), filepath.Join(root, "a"), "")
+	drain(m)
+	m.execCommand(":tabname docs")
+	if got, want := tabNames(m), " 1:"+base+" [2:docs]"; got != want {
+		t.Fatalf("tab bar = %q; want %q", got, want)
+	}
+	m.doAction("move-tab-left")
+	if got, want := tabNames(m), "[1:docs] 2:"+base+" "; got != want || m.active != 0 {
+		t.Fatalf("after move left: %q (active %d)", got, m.active)
+	}
+	m.doAction("go-tab-2")
+	if m.active != 1 || m.current().panel.Cwd != root {
+		t.Fatalf("go-tab-2: active %d cwd %s", m.active, m.current().panel.Cwd)
+	}
+	m.execCommand(":tabmove 1")
+	if m.active != 0 || m.tabs[1].name != "docs" {
+		t.Fatalf(":tabmove 1: active %d", m.active)
+	}
+
+	// The duplicate keeps cursor and history but is independent.
+	m.setSelected(1)
+	m.doAction("duplicate-tab")
+	if len(m.tabs) != 3 || m.active != 1 {
+		t.Fatalf("duplicate: %d tabs, active %d", len(m.tabs), m.active)
+	}
+	if selectedName(m.current()) != "b" || len(m.current().hist.items) != len(m.tabs[0].hist.items) {
+		t.Fatalf("duplicate lost state: sel %q", selectedName(m.current()))
+	}
+	m.enter()
+	drain(m)
+	if m.tabs[0].panel.Cwd != root || m.current().panel.Cwd != filepath.Join(root, "b") {
+		t.Fatalf("duplicate shares its panel: %s / %s", m.tabs[0].panel.Cwd, m.current().panel.Cwd)
+	}
+	m.closeTab()
+	if len(m.tabs) != 2 || m.active != 1 || m.current().name != "docs" {
+		t.Fatalf("close: %d tabs, active %d", len(m.tabs), m.active)
+	}
+}
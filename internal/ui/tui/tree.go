@@ -0,0 +1,449 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/panels"
+)
+
+const (
+	// treeExpandDepth is the depth zR and :tree expand open by default.
+	treeExpandDepth = 3
+	// treeMaxEntries bounds expand-all so that a huge tree cannot stall the UI.
+	treeMaxEntries = 20000
+)
+
+// errTreeTooBig is reported when expand-all stops at treeMaxEntries.
+var errTreeTooBig = fmt.Errorf("tree: stopped expanding at %d entries", treeMaxEntries)
+
+// treeState is the folding state of a tab shown as a tree. The panel's
+// entries are the visible rows; nested rows are named by their path relative
+// to the panel's directory, so file operations that join Cwd and Name work
+// on the tree cursor unchanged.
+type treeState struct {
+	expanded map[string]bool // relative paths of open directories
+	// expansions being read in the background, by load id
+	loads map[int]context.CancelFunc
+}
+
+func newTreeState() *treeState {
+	return &treeState{expanded: make(map[string]bool), loads: make(map[int]context.CancelFunc)}
+}
+
+// stop cancels the expansions being read.
+func (ts *treeState) stop() {
+	for id, cancel := range ts.loads {
+		cancel()
+		delete(ts.loads, id)
+	}
+}
+
+// treeLoadMsg delivers the children read for a tree expansion.
+type treeLoadMsg struct {
+	id   int
+	kids map[string][]panels.Entry
+	err  error
+}
+
+// treeDepth returns the nesting level of a row name.
+func treeDepth(name string) int { return strings.Count(name, string(filepath.Separator)) }
+
+// treeParent returns the index of the row that contains row i, or -1.
+func treeParent(entries []panels.Entry, i int) int {
+	dir := filepath.Dir(entries[i].Name)
+	if dir == "." {
+		return -1
+	}
+	for j := i - 1; j >= 0; j-- {
+		if entries[j].Name == dir {
+			return j
+		}
+	}
+	return -1
+}
+
+// treeLabel renders a row: indentation, a fold marker for directories and
+// the base name.
+func treeLabel(t tab, e panels.Entry) string {
+	indent := strings.Repeat("  ", treeDepth(e.Name))
+	base := filepath.Base(e.Name)
+	if !e.IsDir {
+		return indent + "  " + base
+	}
+	if t.tree.expanded[e.Name] {
+		return indent + "▾ " + base + "/"
+	}
+	return indent + "▸ " + base + "/"
+}
+
+// treeTab returns the focused tab if it is in tree mode.
+func (m *model) treeTab() *tab {
+	if t := m.focused(); t.tree != nil {
+		return t
+	}
+	return nil
+}
+
+// toggleTree switches the current tab between the tree and the plain listing.
+func (m *model) toggleTree() {
+	t := m.paneTab()
+	if t.tree != nil {
+		m.treeCollapseAll(t)
+		t.tree = nil
+		return
+	}
+	// The tree replaces the column chain on the right.
+	if !m.dual {
+		if m.rightMode == "panel" {
+			m.closeRight()
+		}
+		m.focus = "left"
+	}
+	m.leaveFlatten(t)
+	t.tree = newTreeState()
+}
+
+// listTree reads, below dir, the directories named by rels and then,
+// breadth first, the subdirectories that more accepts (more may be nil).
+// Children are keyed by their directory and named relative to dir. It stops
+// once ctx is done or, with errTreeTooBig, after limit entries (0: no
+// limit); otherwise it reports the first directory that could not be read.
+func listTree(ctx context.Context, dir string, showHidden bool, rels []string, more func(rel string) bool, limit int) (map[string][]panels.Entry, error) {
+	kids := make(map[string][]panels.Entry)
+	queue := append([]string(nil), rels...)
+	var first error
+	n := 0
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return kids, err
+		}
+		rel := queue[0]
+		queue = queue[1:]
+		if _, ok := kids[rel]; ok {
+			continue
+		}
+		dp := panels.NewPanel(filepath.Join(dir, rel), showHidden)
+		if err := dp.Refresh(); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		for i := range dp.Entries {
+			e := &dp.Entries[i]
+			e.Name = filepath.Join(rel, e.Name)
+			if e.IsDir && more != nil && more(e.Name) {
+				queue = append(queue, e.Name)
+			}
+		}
+		kids[rel] = dp.Entries
+		if n += len(dp.Entries); limit > 0 && n >= limit {
+			return kids, errTreeTooBig
+		}
+	}
+	return kids, first
+}
+
+// treeInsert opens the rows of t that have children in kids, and those of
+// the inserted rows in turn, in one pass over the listing. The cursor stays
+// on the same row.
+func treeInsert(t *tab, kids map[string][]panels.Entry) {
+	if len(kids) == 0 {
+		return
+	}
+	p := t.panel
+	sel := selectedName(t)
+	n := len(p.Entries)
+	for _, k := range kids {
+		n += len(k)
+	}
+	// Always build a new slice: the old one may be shared with the dir cache.
+	next := make([]panels.Entry, 0, n)
+	var add func(e panels.Entry)
+	add = func(e panels.Entry) {
+		next = append(next, e)
+		k, ok := kids[e.Name]
+		if !ok || !e.IsDir || t.tree.expanded[e.Name] {
+			return
+		}
+		t.tree.expanded[e.Name] = true
+		for _, c := range k {
+			add(c)
+		}
+	}
+	for _, e := range p.Entries {
+		add(e)
+	}
+	p.Entries = next
+	p.MaxDirName = panels.MaxDirNameOf(next)
+	if i := indexOfEntry(next, sel); i >= 0 {
+		t.selected = i
+	}
+}
+
+// startTreeLoad reads the children of the rows rels of t (see listTree) in
+// the background. The rows open once they arrive, unless the tab changed
+// directory meanwhile; a read that takes longer than the listing timeout is
+// abandoned.
+func (m *model) startTreeLoad(t *tab, rels []string, more func(rel string) bool, limit int) {
+	if len(rels) == 0 {
+		return
+	}
+	m.loadSeq++
+	id := m.loadSeq
+	ctx, cancel := context.WithCancel(context.Background())
+	t.tree.loads[id] = cancel
+	dir, showHidden, timeout := t.panel.Cwd, t.panel.ShowHidden, m.loadTimeout()
+	m.queue(func() tea.Msg {
+		done := make(chan treeLoadMsg, 1)
+		go func() {
+			kids, err := listTree(ctx, dir, showHidden, rels, more, limit)
+			done <- treeLoadMsg{id: id, kids: kids, err: err}
+		}()
+		var stall <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			stall = timer.C
+		}
+		select {
+		case msg := <-done:
+			return msg
+		case <-stall:
+			cancel()
+			return treeLoadMsg{id: id, err: errLoadStalled}
+		}
+	})
+}
+
+// onTreeLoad opens the rows whose children were read. Results of cancelled
+// expansions, or arriving while the directory is listed again, are ignored.
+func (m *model) onTreeLoad(msg treeLoadMsg) {
+	var t *tab
+	for _, c := range m.loadingTabs() {
+		if c.tree != nil && c.tree.loads[msg.id] != nil {
+			t = c
+			break
+		}
+	}
+	if t == nil {
+		return
+	}
+	t.tree.loads[msg.id]()
+	delete(t.tree.loads, msg.id)
+	if t.load != nil {
+		return
+	}
+	treeInsert(t, msg.kids)
+	if msg.err != nil {
+		m.setError(msg.err)
+	}
+	if t == m.focused() {
+		m.ensureVisible()
+	}
+}
+
+// treeExpand opens directory row i once its children are read.
+func (m *model) treeExpand(t *tab, i int) {
+	e := t.panel.Entries[i]
+	if !e.IsDir || t.tree.expanded[e.Name] {
+		return
+	}
+	m.startTreeLoad(t, []string{e.Name}, nil, 0)
+}
+
+// treeCollapse closes directory row i and everything below it.
+func (m *model) treeCollapse(t *tab, i int) {
+	p := t.panel
+	rel := p.Entries[i].Name
+	if !t.tree.expanded[rel] {
+		return
+	}
+	prefix := rel + string(filepath.Separator)
+	end := i + 1
+	for end < len(p.Entries) && strings.HasPrefix(p.Entries[end].Name, prefix) {
+		end++
+	}
+	next := make([]panels.Entry, 0, len(p.Entries)-(end-i-1))
+	next = append(next, p.Entries[:i+1]...)
+	next = append(next, p.Entries[end:]...)
+	p.Entries = next
+	p.MaxDirName = panels.MaxDirNameOf(next)
+	for k := range t.tree.expanded {
+		if k == rel || strings.HasPrefix(k, prefix) {
+			delete(t.tree.expanded, k)
+		}
+	}
+	switch {
+	case t.selected >= end:
+		t.selected -= end - i - 1
+	case t.selected > i:
+		t.selected = i
+	}
+}
+
+// treeExpandAll opens every directory up to depth levels below the root:
+// depth 1 opens the top-level directories, depth 2 their subdirectories too.
+func (m *model) treeExpandAll(t *tab, depth int) {
+	limit := treeMaxEntries - len(t.panel.Entries)
+	if limit <= 0 {
+		m.setError(errTreeTooBig)
+		return
+	}
+	var rels []string
+	for _, e := range t.panel.Entries {
+		if e.IsDir && !t.tree.expanded[e.Name] && treeDepth(e.Name) < depth {
+			rels = append(rels, e.Name)
+		}
+	}
+	more := func(rel string) bool { return treeDepth(rel) < depth }
+	m.startTreeLoad(t, rels, more, limit)
+}
+
+// treeCollapseAll closes every directory, keeping the cursor on the
+// top-level ancestor of the selected row.
+func (m *model) treeCollapseAll(t *tab) {
+	p := t.panel
+	sel := selectedName(t)
+	if i := strings.IndexRune(sel, filepath.Separator); i >= 0 {
+		sel = sel[:i]
+	}
+	next := make([]panels.Entry, 0, len(p.Entries))
+	for _, e := range p.Entries {
+		if treeDepth(e.Name) == 0 {
+			next = append(next, e)
+		}
+	}
+	p.Entries = next
+	p.MaxDirName = panels.MaxDirNameOf(next)
+	t.tree.stop()
+	t.tree.expanded = make(map[string]bool)
+	t.selected = 0
+	if i := indexOfEntry(next, sel); i >= 0 {
+		t.selected = i
+	}
+}
+
+// treeOpen, treeClose and treeToggle implement zo, zc and za on the cursor.
+func (m *model) treeOpen() {
+	t := m.treeTab()
+	if t == nil || len(t.panel.Entries) == 0 {
+		return
+	}
+	m.treeExpand(t, t.selected)
+}
+
+func (m *model) treeClose() {
+	t := m.treeTab()
+	if t == nil || len(t.panel.Entries) == 0 {
+		return
+	}
+	i := t.selected
+	if !t.tree.expanded[t.panel.Entries[i].Name] {
+		// Close the fold the cursor is in.
+		if i = treeParent(t.panel.Entries, i); i < 0 {
+			return
+		}
+	}
+	m.treeCollapse(t, i)
+	t.selected = i
+	m.ensureVisible()
+}
+
+func (m *model) treeToggle() {
+	t := m.treeTab()
+	if t == nil || len(t.panel.Entries) == 0 {
+		return
+	}
+	e := t.panel.Entries[t.selected]
+	if e.IsDir && !t.tree.expanded[e.Name] {
+		m.treeOpen()
+		return
+	}
+	m.treeClose()
+}
+
+// treeRight opens the directory under the cursor or, if it is already open,
+// moves to its first child. It reports whether the key was handled.
+func (m *model) treeRight(t *tab) bool {
+	if t.tree == nil || len(t.panel.Entries) == 0 {
+		return false
+	}
+	e := t.panel.Entries[t.selected]
+	if !e.IsDir {
+		return false
+	}
+	if !t.tree.expanded[e.Name] {
+		m.treeExpand(t, t.selected)
+		return true
+	}
+	if t.selected+1 < len(t.panel.Entries) && treeDepth(t.panel.Entries[t.selected+1].Name) > treeDepth(e.Name) {
+		m.setSelected(t.selected + 1)
+	}
+	return true
+}
+
+// treeLeft closes the open directory under the cursor or moves to the
+// parent row. At the top level it reports false so that the tab goes up.
+func (m *model) treeLeft(t *tab) bool {
+	if t.tree == nil || len(t.panel.Entries) == 0 {
+		return false
+	}
+	e := t.panel.Entries[t.selected]
+	if t.tree.expanded[e.Name] {
+		m.treeCollapse(t, t.selected)
+		return true
+	}
+	if i := treeParent(t.panel.Entries, t.selected); i >= 0 {
+		m.setSelected(i)
+		return true
+	}
+	return false
+}
+
+// treeCommand implements :tree [on|off|expand [N]|collapse].
+func (m *model) treeCommand(args []string) {
+	sub := "toggle"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	t := m.paneTab()
+	switch sub {
+	case "toggle":
+		m.toggleTree()
+	case "on":
+		if t.tree == nil {
+			m.toggleTree()
+		}
+	case "off":
+		if t.tree != nil {
+			m.toggleTree()
+		}
+	case "expand":
+		depth := treeExpandDepth
+		if len(args) > 1 {
+			if _, err := fmt.Sscan(args[1], &depth); err != nil || depth < 1 {
+				m.setError(fmt.Errorf("usage: :tree expand [depth]"))
+				return
+			}
+		}
+		if t.tree == nil {
+			m.toggleTree()
+		}
+		m.treeExpandAll(t, depth)
+	case "collapse":
+		if t.tree != nil {
+			m.treeCollapseAll(t)
+		}
+	default:
+		m.setError(fmt.Errorf("usage: :tree [on|off|expand [depth]|collapse]"))
+		return
+	}
+	m.ensureVisible()
+}
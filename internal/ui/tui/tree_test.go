@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTreeModel returns a model in tree mode over root containing
+// a/inner/deep, a/f1 and b.
+func newTreeModel(t *testing.T) (*model, string) {
+	t.Helper()
+	m, root := newHistoryModel(t)
+	if err := os.MkdirAll(filepath.Join(root, "a", "inner", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "f1"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.toggleTree()
+	return m, root
+}
+
+func treeNames(m *model) string {
+	var names []string
+	for _, e := range m.current().panel.Entries {
+		names = append(names, e.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestTreeExpandCollapse(t *testing.T) {
+	m, root := newTreeModel(t)
+	m.setSelected(1) // b
+	m.treeExpand(m.current(), 0)
+	if got := treeNames(m); got != "a b" {
+		t.Fatalf("rows changed before the children were read: %q", got)
+	}
+	drain(m)
+	if got, want := treeNames(m), "a a/inner a/f1 b"; got != want {
+		t.Fatalf("after expand: %q; want %q", got, want)
+	}
+	if got := selectedName(m.current()); got != "b" {
+		t.Fatalf("selection moved to %q", got)
+	}
+	// Right on an open directory descends, zc on a child closes its parent.
+	m.setSelected(0)
+	m.enter()
+	if got := selectedName(m.current()); got != "a/inner" {
+		t.Fatalf("right on open dir selected %q", got)
+	}
+	m.treeOpen()
+	drain(m)
+	m.copySelectedPath()
+	if got := m.clip.Items(); len(got) != 1 || got[0] != filepath.Join(root, "a", "inner") {
+		t.Fatalf("copy-path on tree row = %v", got)
+	}
+	m.setSelected(2) // a/inner/deep
+	m.treeClose()
+	if got, want := treeNames(m), "a a/inner a/f1 b"; got != want || selectedName(m.current()) != "a/inner" {
+		t.Fatalf("zc: %q sel=%q", got, selectedName(m.current()))
+	}
+	// Left on a child moves to the parent row, then closes it.
+	m.setSelected(2) // a/f1
+	m.up()
+	m.up()
+	if got := treeNames(m); got != "a b" || selectedName(m.current()) != "a" {
+		t.Fatalf("left: %q sel=%q", got, selectedName(m.current()))
+	}
+	if len(m.current().tree.expanded) != 0 {
+		t.Fatalf("expanded set not cleared: %v", m.current().tree.expanded)
+	}
+}
+
+func TestTreeExpandAllAndToggleOff(t *testing.T) {
+	m, _ := newTreeModel(t)
+	m.treeExpandAll(m.current(), 1)
+	drain(m)
+	if got, want := treeNames(m), "a a/inner a/f1 b"; got != want {
+		t.Fatalf("depth 1: %q; want %q", got, want)
+	}
+	m.treeCommand([]string{"expand", "5"})
+	drain(m)
+	if got, want := treeNames(m), "a a/inner a/inner/deep a/f1 b"; got != want {
+		t.Fatalf("depth 5: %q; want %q", got, want)
+	}
+	m.setSelected(2)
+	m.toggleTree()
+	if m.current().tree != nil || treeNames(m) != "a b" || selectedName(m.current()) != "a" {
+		t.Fatalf("tree off: %q sel=%q", treeNames(m), selectedName(m.current()))
+	}
+}
+
+func TestTreeSurvivesReloadAndResetsOnNavigate(t *testing.T) {
+	m, root := newTreeModel(t)
+	m.treeExpandAll(m.current(), 2)
+	drain(m)
+	m.setSelected(2) // a/inner/deep
+	if err := os.WriteFile(filepath.Join(root, "a", "inner", "new"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.onDirsChanged([]string{filepath.Join(root, "a", "inner")})
+	drain(m)
+	if got, want := treeNames(m), "a a/inner a/inner/deep a/inner/new a/f1 b"; got != want {
+		t.Fatalf("after reload: %q; want %q", got, want)
+	}
+	if got := selectedName(m.current()); got != "a/inner/deep" {
+		t.Fatalf("cursor after reload = %q", got)
+	}
+	lines := renderPanelRows(m, *m.current(), 30, false, 0, len(m.current().panel.Entries))
+	if !strings.HasPrefix(lines[1], "  ▾ inner/") || !strings.HasPrefix(lines[3], "      new") {
+		t.Fatalf("labels: %q", lines[:4])
+	}
+	m.setSelected(1)
+	m.treeClose() // a/inner
+	m.setSelected(len(m.current().panel.Entries) - 1)
+	m.navigate(m.current(), filepath.Join(root, "b"), "")
+	drain(m)
+	if m.current().tree == nil || len(m.current().tree.expanded) != 0 {
+		t.Fatalf("tree state should reset on a new root")
+	}
+}
+
+func TestTreeExpandInBackground(t *testing.T) {
+	m, root := newTreeModel(t)
+	m.treeExpandAll(m.current(), 5)
+	m.treeCollapseAll(m.current())
+	drain(m)
+	if got := treeNames(m); got != "a b" {
+		t.Fatalf("expansion applied after collapse: %q", got)
+	}
+	// An expansion that cannot be read leaves the row closed.
+	if err := os.RemoveAll(filepath.Join(root, "b")); err != nil {
+		t.Fatal(err)
+	}
+	m.treeExpand(m.current(), 1)
+	drain(m)
+	if m.err == nil || m.current().tree.expanded["b"] || treeNames(m) != "a b" {
+		t.Fatalf("unreadable directory: err=%v rows=%q", m.err, treeNames(m))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,11 +14,15 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 
+	"github.com/MrTeeett/TerminalFileMeneger/internal/bookmarks"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/config"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/frecency"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/ops"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/watch"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/input/layout"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/keymap"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/logging"
+	"github.com/MrTeeett/TerminalFileMeneger/internal/session"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/theme"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/cache"
 	"github.com/MrTeeett/TerminalFileMeneger/internal/ui/clipboard"
@@ -28,12 +33,19 @@ import (
 
 // Dependencies wired by app.Run. Keep this small and explicit.
 type Dependencies struct {
-	Logger   logging.Logger
-	Config   *config.Config
-	Keymap   keymap.Map
-	Theme    theme.Theme
-	Registry commands.Registry
-	FS       ops.Manager
+	Logger    logging.Logger
+	Config    *config.Config
+	Keymap    keymap.Map
+	Theme     theme.Theme
+	Registry  commands.Registry
+	FS        ops.Manager
+	Bookmarks *bookmarks.Store
+	Frecency  *frecency.DB // nil when visit tracking is disabled
+	// Session is restored on start (nil starts in the working directory).
+	Session *session.Session
+	// SessionPath is where the session is saved on exit and periodically
+	// ("" = never).
+	SessionPath string
 }
 
 // tab holds state for a single tab/panel.
@@ -41,6 +53,12 @@ type tab struct {
 	panel    *panels.Panel
 	selected int
 	scroll   int
+	hist     history    // back/forward navigation history
+	load     *dirLoad   // in-flight asynchronous listing, if any
+	tree     *treeState // folding state when shown as a tree (nil otherwise)
+	flat     *flatState // flattened recursive listing (nil otherwise)
+	name     string     // label set with :tabname ("" = directory basename)
+	view     *tabView   // right-hand state while the tab is in the background
 }
 
 // model is the Bubble Tea model for the app.
@@ -57,13 +75,22 @@ type model struct {
 	// command-line (Ex) mode
 	cmdActive bool
 	cmdBuf    []rune
+	// prompt reuses the command line for free-form input (see onPrompt)
+	promptKind  string
+	promptArg   string
+	promptLabel string
 	// simple modal overlay (help/command output)
 	modalActive bool
 	modalTitle  string
 	modalLines  []string
+	modalKind   string   // "" for read-only text; otherwise a picker kind (see onPick)
+	modalSel    int      // selected line in picker modals
+	modalItems  []string // per-line payload for pickers that need one
 	// key chords
 	keySeq []string
 	seqGen int
+	// pending mark operation after m / ' ("set" | "jump")
+	markPending string
 	// focus and right area
 	focus     string // "left" or "right" (right focuses the last column)
 	rightMode string // "" | "preview" | "panel"
@@ -72,6 +99,7 @@ type model struct {
 	showPrev  bool   // preview enabled flag
 	openRight bool   // open dirs on right flag
 	rightPct  int    // width percent (10..80)
+	dual      bool   // two commander panels: the active tab and rightT (see dual.go)
 	prevProv  preview.Provider
 	// profiling
 	prof profiler
@@ -80,12 +108,46 @@ type model struct {
 	fileCache cache.FileCache
 	// async prefetch tracker to avoid duplicate work
 	prefetching map[string]struct{}
+	// filesystem watcher for visible directories (nil if unavailable)
+	watcher *watch.Watcher
+	// remembered cursor per visited directory
+	positions positions
+	// last time the frecency database was written
+	frecencySaved time.Time
+	// asynchronous directory listings (and jobs, which share the sequence)
+	loadSeq int
+	// long file operation running in the background (see jobs.go)
+	job *job
+	// commands queued by helpers during Update (see queue)
+	pending []tea.Cmd
+	// commands queued while building the initial model, run by Init
+	startup []tea.Cmd
+	// first row held by the viewport; vp.YOffset stays absolute (see setWindowContent)
+	winStart int
+	// last left click, for double-click detection (see mouse.go)
+	lastClick clickState
+	// view and scroll position of the previewed file (see preview_view.go)
+	pv      previewState
+	pvFocus bool // keys page through the preview (see pager.go)
+	pvWrap  bool // wrap long lines of the preview
+	// inline images (see images.go)
+	imgCache  cache.FileCache  // encoded images per path and size
+	cell      preview.CellSize // pixel size of a terminal cell (zero = unknown)
+	kittyID   uint32           // id of the kitty image, 0 until first used
+	imgPlaced bool             // the last render showed a kitty or sixel image
+	sixel     *sixelFrame      // last frame with a sixel image
+	imgClear  string           // sequence deleting a stale kitty image, sent with the status line
+	// archive members extracted for previews, by virtual path (see archive.go)
+	members     map[string]*member
+	memberOrder []string // oldest first
+	memberDir   string
 	// styles
 	styHeader   lipgloss.Style
 	styStatus   lipgloss.Style
 	styNormal   lipgloss.Style
 	stySelected lipgloss.Style
 	styDir      lipgloss.Style
+	stySyntax   [preview.NumClasses + 1]lipgloss.Style // and classMatch
 	// diagnostics
 	colorProfile string
 	// clipboard
@@ -130,9 +192,11 @@ func initialModel(deps Dependencies) (model, error) {
 	if err := p.Refresh(); err != nil {
 		return model{}, err
 	}
+	t := tab{panel: p}
+	t.hist.visit(p.Cwd)
 	m := model{
 		deps:   deps,
-		tabs:   []tab{{panel: p}},
+		tabs:   []tab{t},
 		active: 0,
 		header: 1,
 		status: 1,
@@ -140,6 +204,7 @@ func initialModel(deps Dependencies) (model, error) {
 	m.vp = viewport.Model{}
 	// right area defaults
 	m.showPrev = deps.Config.ShowPreview
+	m.pvWrap = deps.Config.PreviewWrap
 	m.openRight = deps.Config.OpenDirsRight
 	m.rightPct = deps.Config.RightPaneWidth
 	if m.rightPct < 10 {
@@ -151,17 +216,40 @@ func initialModel(deps Dependencies) (model, error) {
 	if m.showPrev {
 		m.rightMode = "preview"
 	}
-	m.prevProv = preview.BasicProvider{}
+	m.prevProv = preview.ArchiveProvider{Next: preview.BasicProvider{}}
 	// profiling controlled by env var TFM_PROFILE (any non-empty value)
 	m.prof = profiler{enabled: os.Getenv("TFM_PROFILE") != "", logger: deps.Logger}
 	// init caches
 	m.dirCache = cache.NewDirCache(64)
-	m.fileCache = cache.NewFileCache(64)
+	// Previews and encoded images share the memory budget.
+	budget := deps.Config.PreviewCacheBytes
+	if budget <= 0 {
+		budget = cache.DefaultPreviewBudget
+	}
+	m.fileCache = cache.NewFileCache(64, budget/2)
+	m.imgCache = cache.NewFileCache(16, budget/2)
+	m.sixel = &sixelFrame{}
+	if deps.Config.PreviewDiskCache {
+		if d, err := cache.OpenDisk(cache.DefaultDiskDir(), deps.Config.PreviewDiskBytes); err != nil {
+			deps.Logger.Warnf("preview cache: %v", err)
+		} else {
+			m.fileCache.SetDisk(d)
+			m.imgCache.SetDisk(d)
+		}
+	}
 	m.prefetching = make(map[string]struct{})
+	m.positions = newPositions(positionsMax)
 	m.focus = "left"
+	if deps.Session != nil {
+		m.applySession(deps.Session)
+	} else if deps.Config.Layout == "dual" {
+		m.setLayout(true)
+	}
 	m.computeStyles()
 	m.colorProfile = usedProfile
 	m.refreshContent()
+	// Loads and prefetches queued so far are started by Init.
+	m.startup, m.pending = m.pending, nil
 	return m, nil
 }
 
@@ -221,9 +309,27 @@ func configureColorProfile(cfg *config.Config) string {
 	}
 }
 
-func (m model) Init() tea.Cmd { return nil }
+func (m model) Init() tea.Cmd {
+	cmds := append([]tea.Cmd(nil), m.startup...)
+	if m.watcher != nil {
+		cmds = append(cmds, waitForChanges(m.watcher))
+	}
+	cmds = append(cmds, m.sessionTick())
+	return tea.Batch(cmds...)
+}
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	nm := next.(model)
+	if len(nm.pending) == 0 {
+		return nm, cmd
+	}
+	cmds := append([]tea.Cmd{cmd}, nm.pending...)
+	nm.pending = nil
+	return nm, tea.Batch(cmds...)
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
@@ -234,12 +340,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.vp.Width = m.width
 		m.vp.Height = contentH
+		m.setCellSize(preview.TermCellSize())
 		m.computeStyles()
 		// Keep cursor visible after resize.
 		m.ensureVisible()
 		m.refreshContent()
 		return m, nil
 	case tea.KeyMsg:
+		// Picker modals handle their own navigation keys.
+		if m.modalActive && m.modalKind != "" {
+			cmd := m.onModalKey(msg)
+			m.refreshContent()
+			return m, cmd
+		}
 		// If a modal is active, close it on Esc/Enter/any key (except modifiers)
 		if m.modalActive {
 			s := msg.String()
@@ -278,9 +391,46 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.refreshContent()
 		return m, nil
+	case imageMsg:
+		m.onImage(msg)
+		m.refreshContent()
+		return m, nil
+	case memberMsg:
+		m.onMember(msg)
+		m.refreshContent()
+		return m, nil
+	case previewResultMsg:
+		m.onPreviewResult(msg)
+		m.refreshContent()
+		return m, nil
+	case dirLoadMsg:
+		cmd := m.onDirLoad(msg)
+		m.refreshContent()
+		return m, cmd
+	case flattenMsg:
+		m.onFlatten(msg)
+		m.refreshContent()
+		return m, nil
+	case treeLoadMsg:
+		m.onTreeLoad(msg)
+		m.refreshContent()
+		return m, nil
+	case sortMsg:
+		m.onSort(msg)
+		m.refreshContent()
+		return m, nil
+	case sessionTickMsg:
+		if err := m.saveSession(m.deps.SessionPath); err != nil {
+			m.deps.Logger.Warnf("session: %v", err)
+		}
+		return m, m.sessionTick()
+	case dirChangedMsg:
+		m.onDirsChanged(msg.dirs)
+		m.refreshContent()
+		return m, waitForChanges(m.watcher)
 	case tea.MouseMsg:
-		var cmd tea.Cmd
-		m.vp, cmd = m.vp.Update(msg)
+		cmd := m.onMouse(msg)
+		m.refreshContent()
 		return m, cmd
 	case extRunDoneMsg:
 		// After interactive command exits, refresh UI and report error if any.
@@ -292,16 +442,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Reconfigure color profile and recompute styles (in case capabilities changed)
 		m.colorProfile = configureColorProfile(m.deps.Config)
 		m.computeStyles()
-		// Refresh focused panel contents (FS may have changed)
-		if ft := m.focused(); ft != nil && ft.panel != nil {
-			_ = ft.panel.Refresh()
-		}
+		m.reloadPanes()
 		m.refreshContent()
 		return m, nil
+	case jobMsg:
+		cmd := m.onJob(msg)
+		m.refreshContent()
+		return m, cmd
 	}
 	return m, nil
 }
 
+// reloadPanes rereads the focused panel, and the other one in the dual
+// layout, after a file operation may have changed them.
+func (m *model) reloadPanes() {
+	if ft := m.focused(); ft != nil && ft.panel != nil {
+		m.dirCache.InvalidatePrefix(ft.panel.Cwd)
+		m.fileCache.InvalidatePrefix(ft.panel.Cwd)
+		m.reloadTab(ft)
+	}
+	if o := m.otherPane(); o != nil && o.panel != nil {
+		m.dirCache.InvalidatePrefix(o.panel.Cwd)
+		m.reloadTab(o)
+	}
+}
+
 // chordTimeoutMsg is emitted when we should resolve a pending key chord.
 type chordTimeoutMsg struct{ gen int }
 
@@ -332,6 +497,31 @@ func (m *model) onChordTimeout(msg chordTimeoutMsg) tea.Cmd {
 
 func (m *model) onKey(msg tea.KeyMsg) tea.Cmd {
 	key := normalizeKey(msg.String())
+	// The key after m / ' names the mark.
+	if m.markPending != "" {
+		op := m.markPending
+		m.markPending = ""
+		switch {
+		case key == "esc":
+			return nil
+		case op == "set":
+			m.setMark(key)
+			return nil
+		default:
+			return m.jumpMark(key)
+		}
+	}
+	// Esc returns from the preview to the list.
+	if key == "esc" && m.pvFocus {
+		m.pvFocus = false
+		m.keySeq = nil
+		return nil
+	}
+	// Esc stops a running job.
+	if key == "esc" && m.cancelJob() {
+		m.keySeq = nil
+		return nil
+	}
 	// Append to current sequence and try resolve.
 	m.keySeq = append(m.keySeq, key)
 
@@ -412,6 +602,11 @@ func (m *model) matchSequence(seq []string) (keymap.Action, bool, bool) {
 
 // doAction performs a semantic action according to the keymap.
 func (m *model) doAction(act keymap.Action) tea.Cmd {
+	if m.previewFocused() {
+		if cmd, ok := m.pagerAction(string(act)); ok {
+			return cmd
+		}
+	}
 	switch string(act) {
 	case "copy":
 		m.copySelectedFile()
@@ -452,28 +647,77 @@ func (m *model) doAction(act keymap.Action) tea.Cmd {
 	case "toggle-hidden":
 		m.toggleHidden()
 		return m.maybePrefetchSelected()
+	case "back":
+		m.historyStep(-1)
+		return m.maybePrefetchSelected()
+	case "forward":
+		m.historyStep(1)
+		return m.maybePrefetchSelected()
+	case "history":
+		m.showHistory()
+	case "toggle-tree":
+		m.toggleTree()
+		m.ensureVisible()
+	case "tree-open":
+		m.treeOpen()
+	case "tree-close":
+		m.treeClose()
+	case "tree-toggle":
+		m.treeToggle()
+	case "tree-expand-all":
+		if t := m.treeTab(); t != nil {
+			m.treeExpandAll(t, treeExpandDepth)
+			m.ensureVisible()
+		}
+	case "tree-collapse-all":
+		if t := m.treeTab(); t != nil {
+			m.treeCollapseAll(t)
+			m.ensureVisible()
+		}
+	case "set-mark":
+		m.markPending = "set"
+	case "jump-mark":
+		m.markPending = "jump"
+	case "bookmarks":
+		m.showBookmarks(0)
 	case "toggle-preview":
 		m.togglePreview()
+	case "cycle-preview-view":
+		m.cyclePreviewView()
+	case "preview-down":
+		m.scrollPreview(m.viewportHeight() / 2)
+	case "preview-up":
+		m.scrollPreview(-m.viewportHeight() / 2)
+	case "focus-preview":
+		m.togglePreviewFocus()
+	case "toggle-preview-wrap":
+		m.pvWrap = !m.pvWrap
 	case "toggle-right-open-mode":
 		m.toggleOpenRightMode()
 	case "close-right":
 		m.closeRight()
 	case "toggle-focus":
-		if m.rightMode == "panel" && m.rightT.panel != nil {
-			if m.focus == "left" {
-				m.focus = "right"
-			} else {
-				m.focus = "left"
-			}
+		if m.focus == "left" {
+			m.setFocus("right")
 		} else {
-			m.focus = "left"
+			m.setFocus("left")
 		}
 	case "focus-left":
-		m.focus = "left"
+		m.setFocus("left")
 	case "focus-right":
-		if m.rightMode == "panel" && m.rightT.panel != nil {
-			m.focus = "right"
-		}
+		m.setFocus("right")
+	case "toggle-layout":
+		m.setLayout(!m.dual)
+		m.ensureVisible()
+	case "swap-panes":
+		m.swapPanes()
+	case "sync-panes":
+		m.syncPanes()
+		return m.maybePrefetchSelected()
+	case "copy-to-other":
+		m.transferToOther("copy")
+	case "move-to-other":
+		m.transferToOther("move")
 	case "new-tab":
 		m.newTab()
 	case "next-tab":
@@ -482,6 +726,12 @@ func (m *model) doAction(act keymap.Action) tea.Cmd {
 		m.prevTab()
 	case "close-tab":
 		m.closeTab()
+	case "duplicate-tab":
+		m.duplicateTab()
+	case "move-tab-left":
+		m.moveTab(-1)
+	case "move-tab-right":
+		m.moveTab(1)
 	case "page-down":
 		m.page(1)
 		return m.maybePrefetchSelected()
@@ -495,6 +745,13 @@ func (m *model) doAction(act keymap.Action) tea.Cmd {
 		m.halfPage(-1)
 		return m.maybePrefetchSelected()
 	default:
+		// go-tab-N jumps to tab N (gt<N>).
+		if n, ok := strings.CutPrefix(string(act), "go-tab-"); ok {
+			if i, err := strconv.Atoi(n); err == nil {
+				m.switchTab(i - 1)
+			}
+			return m.maybePrefetchSelected()
+		}
 		// Unimplemented actions (rename, delete, copy, paste, filter, fuzzy) are ignored for now.
 	}
 	return nil
@@ -518,10 +775,10 @@ func (m model) View() string {
 	// Compose full view: header, viewport view, status
 	lines := []string{
 		m.styHeader.Render(trimToWidth(header, m.width)),
-		m.vp.View(),
-		m.styStatus.Render(trimToWidth(status, m.width)),
+		m.windowView(),
+		m.imgClear + m.styStatus.Render(trimToWidth(status, m.width)),
 	}
-	return join(lines, "\n")
+	return m.redrawSixel(join(lines, "\n"))
 }
 
 func (m *model) current() *tab { return &m.tabs[m.active] }
@@ -579,10 +836,17 @@ func (m *model) halfPage(delta int) {
 }
 
 func (m *model) toggleHidden() {
-	p := m.focused().panel
+	m.cancelLoad(m.focused())
+	t := m.focused()
+	p := t.panel
+	if t.flat != nil {
+		// Walk again with the new filter; keep the flattened rows meanwhile.
+		p.ShowHidden = !p.ShowHidden
+		m.startFlatten(t)
+		return
+	}
 	p.ShowHidden = !p.ShowHidden
-	_ = p.Refresh()
-	m.setSelected(0)
+	m.startReload(t, "")
 }
 
 func (m *model) enter() {
@@ -593,31 +857,25 @@ func (m *model) enter() {
 		m.prof.End("enter")
 		return
 	}
+	if m.treeRight(t) {
+		m.prof.End("enter")
+		return
+	}
 	e := p.Entries[t.selected]
-	if e.IsDir {
-		newPath := filepath.Join(p.Cwd, e.Name)
-		if m.openRight {
+	newPath := filepath.Join(p.Cwd, e.Name)
+	// Archives are entered like directories.
+	if e.IsDir || panels.IsArchive(newPath) {
+		if m.openRight && !m.dual {
 			// open as a new column and focus it
 			m.prof.Step("enter", "open-right")
+			m.focused().scroll = m.vp.YOffset
 			m.openRightPanel(newPath)
 			m.focus = "right"
+			m.vp.YOffset = m.focused().scroll
 		} else {
 			m.prof.Step("enter", "chdir")
-			if entries := m.dirCache.Get(newPath); entries != nil {
-				// Use cached listing to avoid extra I/O
-				p.Cwd = newPath
-				p.Entries = entries
-				p.MaxDirName = computeMaxDirName(entries)
-				m.err = nil
-				t.selected = 0
-				t.scroll = 0
-			} else if err := p.Chdir(newPath); err != nil {
-				m.err = err
-			} else {
-				m.err = nil
-				t.selected = 0
-				t.scroll = 0
-			}
+			m.err = nil
+			m.navigate(t, newPath, "")
 		}
 	}
 	m.prof.End("enter")
@@ -628,7 +886,8 @@ func (m *model) up() {
 	if m.focus == "right" && m.rightMode == "panel" {
 		if n := len(m.rightCols); n > 0 {
 			// pop last column
-			m.rightCols = m.rightCols[:n-1]
+			m.rememberCursor(&m.rightCols[n-1])
+			m.dropColumns(n - 1)
 			if len(m.rightCols) == 0 {
 				// No more columns — switch focus back to left and restore preview if enabled
 				m.focus = "left"
@@ -638,74 +897,129 @@ func (m *model) up() {
 					m.rightMode = ""
 				}
 			}
+			m.vp.YOffset = m.focused().scroll
 			return
 		}
 	}
 	// Otherwise go to parent in the focused panel
 	t := m.focused()
+	if m.treeLeft(t) || m.leaveFlatten(t) {
+		return
+	}
 	p := t.panel
 	parent := filepath.Dir(p.Cwd)
 	if parent == p.Cwd {
 		return
 	}
-	if err := p.Chdir(parent); err != nil {
-		m.err = err
+	// Select the directory we came from in the parent listing.
+	m.err = nil
+	m.navigate(t, parent, filepath.Base(p.Cwd))
+}
+
+// navigate changes t's directory to dir and records it in the tab history.
+// If selName is present in the new listing it becomes the selection. Errors
+// of asynchronous listings are reported when the load finishes.
+func (m *model) navigate(t *tab, dir, selName string) {
+	if len(t.hist.items) == 0 {
+		t.hist.visit(t.panel.Cwd)
+	}
+	prev := selectedName(t)
+	m.loadDir(t, dir, selName)
+	t.hist.setName(prev)
+	t.hist.visit(dir)
+	if t.load != nil {
+		t.load.record = true
 	} else {
-		m.err = nil
-		t.selected = 0
-		t.scroll = 0
+		m.recordVisit(dir)
 	}
 }
 
-func (m *model) newTab() {
-	// Clone current CWD and ShowHidden
-	curr := m.current().panel
-	p := panels.NewPanel(curr.Cwd, curr.ShowHidden)
-	_ = p.Refresh()
-	m.tabs = append(m.tabs, tab{panel: p})
-	m.active = len(m.tabs) - 1
-	m.setSelected(0)
+// loadDir switches t to dir without touching history. A cached listing is
+// applied at once; otherwise the directory is streamed in the background
+// (see startLoad). It selects selName if present, otherwise restores the
+// cursor remembered for dir (or the first entry).
+func (m *model) loadDir(t *tab, dir, selName string) {
+	p := t.panel
+	m.rememberCursor(t)
+	entries := m.dirCache.Get(dir)
+	if entries == nil {
+		m.startLoad(t, dir, selName)
+	} else {
+		// Use cached listing to avoid extra I/O
+		m.cancelLoad(t)
+		resetFlatten(t)
+		if t.tree != nil {
+			t.tree.stop()
+			t.tree = newTreeState()
+		}
+		p.Cwd = dir
+		p.Entries = entries
+		p.MaxDirName = computeMaxDirName(entries)
+	}
+	t.selected = 0
+	t.scroll = 0
+	if t == m.focused() {
+		m.vp.YOffset = 0
+	}
+	if entries != nil {
+		m.restoreCursor(t)
+		if i := indexOfEntry(p.Entries, selName); i >= 0 {
+			t.selected = i
+		}
+	}
+	m.ensureVisible()
 }
 
-func (m *model) closeTab() {
-	if len(m.tabs) <= 1 {
-		return
+// selectedName returns the name of the entry under t's cursor, or "".
+func selectedName(t *tab) string {
+	if t == nil || t.panel == nil {
+		return ""
 	}
-	idx := m.active
-	m.tabs = append(m.tabs[:idx], m.tabs[idx+1:]...)
-	if m.active >= len(m.tabs) {
-		m.active = len(m.tabs) - 1
+	if t.selected < 0 || t.selected >= len(t.panel.Entries) {
+		return ""
 	}
+	return t.panel.Entries[t.selected].Name
 }
 
-func (m *model) nextTab() {
-	if len(m.tabs) == 0 {
-		return
+// indexOfEntry returns the index of the entry called name, or -1.
+func indexOfEntry(entries []panels.Entry, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, e := range entries {
+		if e.Name == name {
+			return i
+		}
 	}
-	m.active = (m.active + 1) % len(m.tabs)
-	m.ensureVisible()
-	m.refreshContent()
+	return -1
 }
 
 func (m *model) openRightPanel(path string) {
 	p := panels.NewPanel(path, m.deps.Config.ShowHidden)
+	m.rightCols = append(m.rightCols, tab{panel: p})
+	m.rightMode = "panel"
+	t := &m.rightCols[len(m.rightCols)-1]
 	if entries := m.dirCache.Get(path); entries != nil {
-		p.Cwd = path
 		p.Entries = entries
 		p.MaxDirName = computeMaxDirName(entries)
-	} else {
-		_ = p.Refresh()
+		m.restoreCursor(t)
+		return
 	}
-	m.rightCols = append(m.rightCols, tab{panel: p})
-	m.rightMode = "panel"
+	m.startLoad(t, path, "")
 }
 
 func (m *model) closeRight() {
+	if m.dual {
+		// The right panel of the dual layout is not a column.
+		return
+	}
 	// Close all right columns and revert focus
-	m.rightCols = nil
+	m.dropColumns(0)
+	m.dropTab(&m.rightT)
 	m.rightT = tab{}
 	if m.focus == "right" {
 		m.focus = "left"
+		m.vp.YOffset = m.current().scroll
 	}
 	if m.showPrev {
 		m.rightMode = "preview"
@@ -716,6 +1030,10 @@ func (m *model) closeRight() {
 
 func (m *model) togglePreview() {
 	m.showPrev = !m.showPrev
+	if m.dual {
+		// Takes effect when returning to the Miller layout.
+		return
+	}
 	if m.showPrev {
 		m.rightMode = "preview"
 		// No interactive right column in preview-only mode
@@ -728,15 +1046,6 @@ func (m *model) togglePreview() {
 
 func (m *model) toggleOpenRightMode() { m.openRight = !m.openRight }
 
-func (m *model) prevTab() {
-	if len(m.tabs) == 0 {
-		return
-	}
-	m.active = (m.active - 1 + len(m.tabs)) % len(m.tabs)
-	m.ensureVisible()
-	m.refreshContent()
-}
-
 func join(ss []string, sep string) string {
 	switch len(ss) {
 	case 0:
@@ -793,6 +1102,7 @@ func (m *model) ensureVisible() {
 	if m.vp.YOffset < 0 {
 		m.vp.YOffset = 0
 	}
+	t.scroll = m.vp.YOffset
 }
 
 func (m *model) copySelectedFile() {
@@ -973,11 +1283,12 @@ func (m *model) maybePrefetchSelected() tea.Cmd {
 }
 
 // tryRefreshMulti renders multi-column layout when right columns or preview are active.
+// Only the rows around the viewport offset are rendered (see renderWindow).
 // Returns true if it handled rendering and set the viewport content.
 func (m *model) tryRefreshMulti() bool {
 	m.prof.Begin("refresh-multi")
 	if len(m.tabs) == 0 {
-		m.vp.SetContent("")
+		m.setWindowContent("", 0)
 		m.prof.End("refresh-multi")
 		return true
 	}
@@ -989,6 +1300,13 @@ func (m *model) tryRefreshMulti() bool {
 	if totalW <= 0 {
 		totalW = 80
 	}
+	if m.dual {
+		m.renderDual(totalW)
+		m.prof.End("refresh-multi")
+		return true
+	}
+	start, end := m.renderWindow()
+	m.focused().scroll = m.vp.YOffset
 
 	// Collect columns (panel mode)
 	cols := []tab{leftTab}
@@ -1006,8 +1324,8 @@ func (m *model) tryRefreshMulti() bool {
 			if leftW < 10 {
 				leftW = 10
 			}
-			leftLines := renderPanelColumn(m, cols[0], leftW, true)
-			m.vp.SetContent(join(leftLines, "\n"))
+			leftLines := renderPanelRows(m, cols[0], leftW, true, start, end)
+			m.setWindowContent(join(leftLines, "\n"), start)
 			m.prof.End("refresh-multi")
 			return true
 		}
@@ -1025,46 +1343,9 @@ func (m *model) tryRefreshMulti() bool {
 		}
 		// In preview-only mode there is no interactive right column,
 		// so the left column should always be considered focused for styling.
-		leftLines := renderPanelColumn(m, cols[0], leftW, true)
-		var rightLines []string
-		p := cols[0].panel
-		if len(p.Entries) > 0 {
-			e := p.Entries[cols[0].selected]
-			path := filepath.Join(p.Cwd, e.Name)
-			header := trimToWidth(path, rightW)
-			rightLines = append(rightLines, m.styStatus.Render(header))
-			if e.IsDir {
-				// Use cached directory entries for preview
-				entries := m.dirCache.Get(path)
-				if entries == nil {
-					dp := panels.NewPanel(path, m.deps.Config.ShowHidden)
-					_ = dp.Refresh()
-					entries = dp.Entries
-					m.dirCache.Put(path, entries)
-				}
-				for _, de := range entries {
-					name := de.Name
-					if de.IsDir {
-						name += "/"
-					}
-					ln := trimToWidth(name, rightW)
-					pad := rightW - lipgloss.Width(ln)
-					if pad > 0 {
-						ln += strings.Repeat(" ", pad)
-					}
-					if de.IsDir {
-						rightLines = append(rightLines, m.styDir.Render(ln))
-					} else {
-						rightLines = append(rightLines, m.styNormal.Render(ln))
-					}
-				}
-			} else {
-				// File preview: try inline image if supported; else text fallback
-				body := m.renderFilePreviewBody(path, rightW, m.viewportHeight()-1)
-				rightLines = append(rightLines, body...)
-			}
-		}
-		m.vp.SetContent(mergeColumns([][]string{leftLines, rightLines}, []int{leftW, rightW}, " "))
+		leftLines := renderPanelRows(m, cols[0], leftW, true, start, end)
+		rightLines := m.renderPreviewRows(&cols[0], rightW, start, end)
+		m.setWindowContent(mergeColumns([][]string{leftLines, rightLines}, []int{leftW, rightW}, " "), start)
 		m.prof.End("refresh-multi")
 		return true
 	}
@@ -1087,50 +1368,11 @@ func (m *model) tryRefreshMulti() bool {
 		linesPerCol := make([][]string, 0, len(cols))
 		for i, c := range cols {
 			focusCol := (i == 0 && m.focus == "left") || (i == len(cols)-1 && m.focus == "right")
-			linesPerCol = append(linesPerCol, renderPanelColumn(m, c, widths[i], focusCol))
+			linesPerCol = append(linesPerCol, renderColumnRows(m, c, widths[i], focusCol, start, end))
 		}
 		// Build preview lines for the currently focused selection
-		var prevLines []string
-		ft := m.focused()
-		if ft != nil && ft.panel != nil {
-			p := ft.panel
-			if len(p.Entries) > 0 && ft.selected >= 0 && ft.selected < len(p.Entries) {
-				e := p.Entries[ft.selected]
-				path := filepath.Join(p.Cwd, e.Name)
-				header := trimToWidth(path, prevW)
-				prevLines = append(prevLines, m.styStatus.Render(header))
-				if e.IsDir {
-					entries := m.dirCache.Get(path)
-					if entries == nil {
-						dp := panels.NewPanel(path, p.ShowHidden)
-						_ = dp.Refresh()
-						entries = dp.Entries
-						m.dirCache.Put(path, entries)
-					}
-					for _, de := range entries {
-						name := de.Name
-						if de.IsDir {
-							name += "/"
-						}
-						ln := trimToWidth(name, prevW)
-						pad := prevW - lipgloss.Width(ln)
-						if pad > 0 {
-							ln += strings.Repeat(" ", pad)
-						}
-						if de.IsDir {
-							prevLines = append(prevLines, m.styDir.Render(ln))
-						} else {
-							prevLines = append(prevLines, m.styNormal.Render(ln))
-						}
-					}
-				} else {
-					// File preview: try inline image if supported; else text fallback
-					body := m.renderFilePreviewBody(path, prevW, m.viewportHeight()-1)
-					prevLines = append(prevLines, body...)
-				}
-			}
-		}
-		m.vp.SetContent(mergeColumns(append(linesPerCol, prevLines), append(widths, prevW), " "))
+		prevLines := m.renderPreviewRows(m.focused(), prevW, start, end)
+		m.setWindowContent(mergeColumns(append(linesPerCol, prevLines), append(widths, prevW), " "), start)
 		m.prof.End("refresh-multi")
 		return true
 	}
@@ -1138,13 +1380,87 @@ func (m *model) tryRefreshMulti() bool {
 	linesPerCol := make([][]string, 0, len(cols))
 	for i, c := range cols {
 		focusCol := (i == 0 && m.focus == "left") || (i == len(cols)-1 && m.focus == "right")
-		linesPerCol = append(linesPerCol, renderPanelColumn(m, c, widths[i], focusCol))
+		linesPerCol = append(linesPerCol, renderColumnRows(m, c, widths[i], focusCol, start, end))
 	}
-	m.vp.SetContent(mergeColumns(linesPerCol, widths, " "))
+	m.setWindowContent(mergeColumns(linesPerCol, widths, " "), start)
 	m.prof.End("refresh-multi")
 	return true
 }
 
+// renderPreviewRows renders rows [start, end) of the preview column for t's
+// selected entry: a header with the path followed by the directory listing
+// or the file preview. The preview does not scroll with the list: it
+// always starts at the top of the viewport.
+func (m *model) renderPreviewRows(t *tab, width, start, end int) []string {
+	if t == nil || t.panel == nil {
+		return nil
+	}
+	p := t.panel
+	if len(p.Entries) == 0 || t.selected < 0 || t.selected >= len(p.Entries) {
+		return nil
+	}
+	e := p.Entries[t.selected]
+	path := filepath.Join(p.Cwd, e.Name)
+	top := m.vp.YOffset
+	var rows []string
+	blank := strings.Repeat(" ", width)
+	for i := start; i < top && i < end; i++ {
+		rows = append(rows, blank)
+	}
+	if top >= end {
+		return rows
+	}
+	bodyLines := m.viewportHeight() - 1
+	if n := end - top - 1; bodyLines > n {
+		bodyLines = n
+	}
+	if !e.IsDir {
+		// File preview: try inline image if supported; else text, hex or info.
+		sty := m.styStatus
+		if m.previewFocused() {
+			sty = m.stySelected
+		}
+		src, err := m.previewSource(path)
+		if err != nil {
+			rows = append(rows, sty.Render(trimToWidth(path, width)))
+			if bodyLines > 0 {
+				rows = append(rows, m.styStatus.Render(trimToWidth(err.Error(), width)))
+			}
+			return rows
+		}
+		rows = append(rows, sty.Render(trimToWidth(path+m.previewLabel(src), width)))
+		return append(rows, m.renderFilePreviewBody(src, width, bodyLines)...)
+	}
+	m.previewFor(path)
+	rows = append(rows, m.styStatus.Render(trimToWidth(path, width)))
+	// Use cached directory entries for preview
+	entries, ok := m.previewListing(path, p.ShowHidden)
+	if !ok {
+		if bodyLines > 0 {
+			rows = append(rows, m.styStatus.Render(trimToWidth("loading…", width)))
+		}
+		return rows
+	}
+	_, to := clampRange(0, bodyLines, len(entries))
+	for _, de := range entries[:to] {
+		name := de.Name
+		if de.IsDir {
+			name += "/"
+		}
+		ln := trimToWidth(name, width)
+		pad := width - lipgloss.Width(ln)
+		if pad > 0 {
+			ln += strings.Repeat(" ", pad)
+		}
+		if de.IsDir {
+			rows = append(rows, m.styDir.Render(ln))
+		} else {
+			rows = append(rows, m.styNormal.Render(ln))
+		}
+	}
+	return rows
+}
+
 // autoColumnWidths computes column widths based on the longest directory name in each column.
 // moved to layout_utils.go and render_columns.go
 
@@ -1155,20 +1471,41 @@ func Start(ctx context.Context, deps Dependencies) error {
 	if err != nil {
 		return err
 	}
-	p := tea.NewProgram(
-		m,
+	if deps.Config.Watch {
+		w, err := watch.New(watch.DefaultDebounce)
+		if err != nil {
+			deps.Logger.Warnf("watch: %v", err)
+		} else {
+			defer w.Close()
+			m.watcher = w
+			m.syncWatches()
+		}
+	}
+	opts := []tea.ProgramOption{
 		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
 		tea.WithInput(os.Stdin),
 		tea.WithOutput(os.Stdout),
-	)
-	_, err = p.Run()
+	}
+	if deps.Config.Mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
+	if fm, ok := final.(model); ok {
+		fm.saveSpans(&fm.pv)
+		fm.removeMembers()
+		if serr := fm.saveSession(deps.SessionPath); serr != nil {
+			deps.Logger.Warnf("session: %v", serr)
+		}
+	}
 	return err
 }
 
 // refreshContent rebuilds the viewport content from the current panel state.
 func (m *model) refreshContent() {
 	m.prof.Begin("refresh")
+	m.imgPlaced = false
+	defer m.updateImageClear()
 	// Modal overlay content (help/command output)
 	if m.modalActive {
 		totalW := m.vp.Width
@@ -1183,23 +1520,29 @@ func (m *model) refreshContent() {
 		if title != "" {
 			lines = append(lines, m.styStatus.Render(trimToWidth(title, totalW)))
 		}
-		for _, l := range m.modalLines {
+		for i, l := range m.modalLines {
 			ln := trimToWidth(l, totalW)
 			pad := totalW - lipgloss.Width(ln)
 			if pad > 0 {
 				ln += strings.Repeat(" ", pad)
 			}
-			lines = append(lines, m.styNormal.Render(ln))
+			if m.modalKind != "" && i == m.modalSel {
+				lines = append(lines, m.stySelected.Render(ln))
+			} else {
+				lines = append(lines, m.styNormal.Render(ln))
+			}
 		}
 		if len(lines) == 0 {
 			lines = []string{""}
 		}
-		m.vp.SetContent(join(lines, "\n"))
+		m.setWindowContent(join(lines, "\n"), 0)
 		m.prof.End("refresh")
 		return
 	}
 	// Single path: unified renderer handles all layouts
 	_ = m.tryRefreshMulti()
+	// Whatever is on screen now is what the watcher should follow.
+	m.syncWatches()
 	m.prof.End("refresh")
 }
 
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MrTeeett/TerminalFileMeneger/internal/fs/watch"
+)
+
+// dirChangedMsg reports directories whose contents changed on disk.
+type dirChangedMsg struct{ dirs []string }
+
+// waitForChanges blocks until the watcher reports the next batch of changes.
+func waitForChanges(w *watch.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		dirs, ok := <-w.Events()
+		if !ok {
+			return nil
+		}
+		return dirChangedMsg{dirs: dirs}
+	}
+}
+
+// visibleTabs returns the tabs whose listings are currently on screen.
+func (m *model) visibleTabs() []*tab {
+	if len(m.tabs) == 0 {
+		return nil
+	}
+	out := []*tab{m.current()}
+	for i := range m.rightCols {
+		out = append(out, &m.rightCols[i])
+	}
+	if m.rightT.panel != nil {
+		out = append(out, &m.rightT)
+	}
+	return out
+}
+
+// visibleDirs returns the directories shown on screen: every panel column
+// plus the directory under the cursor when the preview lists it.
+func (m *model) visibleDirs() []string {
+	var dirs []string
+	for _, t := range m.visibleTabs() {
+		if t.panel != nil && t.panel.Cwd != "" {
+			dirs = append(dirs, t.panel.Cwd)
+		}
+		if t.tree != nil {
+			for rel := range t.tree.expanded {
+				dirs = append(dirs, t.panel.Join(rel))
+			}
+		}
+	}
+	if m.showPrev {
+		if ft := m.focused(); ft != nil && ft.panel != nil {
+			if ft.selected >= 0 && ft.selected < len(ft.panel.Entries) && ft.panel.Entries[ft.selected].IsDir {
+				dirs = append(dirs, ft.panel.Join(ft.panel.Entries[ft.selected].Name))
+			}
+		}
+	}
+	return dirs
+}
+
+// syncWatches points the watcher at the currently visible directories.
+func (m *model) syncWatches() {
+	if m.watcher == nil {
+		return
+	}
+	m.watcher.Set(m.visibleDirs())
+}
+
+// onDirsChanged drops cached data for changed directories and reloads the
+// visible panels showing them, keeping the cursor on the same name.
+func (m *model) onDirsChanged(dirs []string) {
+	for _, dir := range dirs {
+		m.dirCache.Invalidate(dir)
+		for _, t := range m.visibleTabs() {
+			// A tab that is still loading will see the change anyway; a
+			// reload in flight may have read the directory already.
+			if t.panel == nil || !showsDir(t, dir) || t.load != nil && !t.load.reload {
+				continue
+			}
+			if name := selectedName(t); name != "" {
+				m.fileCache.Invalidate(filepath.Join(dir, name))
+			}
+			m.reloadTab(t)
+		}
+	}
+	m.ensureVisible()
+}
+
+// reloadTab re-reads t's directory in the background keeping the cursor on
+// the same entry name (see startReload). A tab switching to another
+// directory is left alone.
+func (m *model) reloadTab(t *tab) {
+	if t.flat != nil {
+		m.startFlatten(t)
+		return
+	}
+	if t.load != nil && !t.load.reload {
+		return
+	}
+	m.startReload(t, "")
+}
+
+// showsDir reports whether t lists dir, either as its directory or as an
+// open directory of its tree. Flattened listings are snapshots and never
+// follow changes.
+func showsDir(t *tab, dir string) bool {
+	if t.flat != nil {
+		return false
+	}
+	if t.panel.Cwd == dir {
+		return true
+	}
+	if t.tree == nil {
+		return false
+	}
+	rel, err := filepath.Rel(t.panel.Cwd, dir)
+	return err == nil && t.tree.expanded[rel]
+}
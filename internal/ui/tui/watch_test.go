@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOnDirsChangedKeepsCursorOnName(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.setSelected(1) // "b"
+	m.dirCache.Put(root, m.current().panel.Entries)
+	if err := os.Mkdir(filepath.Join(root, "0first"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.onDirsChanged([]string{root})
+	if m.dirCache.Has(root) {
+		t.Fatalf("cached listing should be invalidated")
+	}
+	p := m.current().panel
+	if len(p.Entries) != 2 || m.current().load == nil {
+		t.Fatalf("rows should stay on screen while reloading: %v", p.Entries)
+	}
+	drain(m)
+	if len(p.Entries) != 3 {
+		t.Fatalf("entries = %v", p.Entries)
+	}
+	if got := selectedName(m.current()); got != "b" {
+		t.Fatalf("selected = %q; want b", got)
+	}
+	// Removing the selected entry keeps the index (clamped).
+	if err := os.Remove(filepath.Join(root, "b")); err != nil {
+		t.Fatal(err)
+	}
+	m.onDirsChanged([]string{root})
+	drain(m)
+	if got := m.current().selected; got != 1 {
+		t.Fatalf("selected index = %d; want 1", got)
+	}
+}
+
+func TestVisibleDirsIncludesColumnsAndPreviewDir(t *testing.T) {
+	m, root := newHistoryModel(t)
+	m.showPrev = true
+	got := m.visibleDirs()
+	want := []string{root, filepath.Join(root, "a")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("visibleDirs = %v; want %v", got, want)
+	}
+	m.showPrev = false
+	m.openRightPanel(filepath.Join(root, "b"))
+	got = m.visibleDirs()
+	want = []string{root, filepath.Join(root, "b")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("visibleDirs = %v; want %v", got, want)
+	}
+}